@@ -2,27 +2,104 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"database/sql"
+	"encoding/base64"
+	"flag"
 	"fmt"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/kelseyhightower/envconfig"
 	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"net"
 	"net/http"
+	"orderservice/pkg/orderservice/application/event"
+	"orderservice/pkg/orderservice/application/service"
+	"orderservice/pkg/orderservice/infrastructure/payment"
+	"orderservice/pkg/orderservice/infrastructure/repository"
+	"orderservice/pkg/orderservice/infrastructure/webhook"
+	"orderservice/pkg/orderservice/model"
 	"orderservice/pkg/orderservice/transport"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 )
 
 const appID = "orderservice"
 
+const (
+	webhookBatchSize = 100
+	webhookWorkers   = 8
+)
+
+const (
+	rebuildProjectionsCommand    = "rebuild-projections"
+	encryptWebhookSecretsCommand = "encrypt-webhook-secrets"
+	importActor                  = "import"
+)
+
 type config struct {
-	ServerPort        string `envconfig:"server_port"`
+	ServerPort string `envconfig:"server_port"`
+	GRPCPort   string `envconfig:"grpc_port" default:"9000"`
+	// MetricsPort serves /metrics apart from the public API, it must not be exposed outside
+	MetricsPort       string `envconfig:"metrics_port" default:"9100"`
 	DatabaseName      string `envconfig:"database_name"`
 	DatabaseAddress   string `envconfig:"database_address"`
 	DatabaseUser      string `envconfig:"database_user"`
 	DatabasePassword  string `envconfig:"database_password"`
 	DatabaseArguments string `envconfig:"database_arguments"`
+	// TaxRate and TaxRates are in basis points, TaxRates override TaxRate per delivery type
+	TaxRate  int            `envconfig:"tax_rate"`
+	TaxRates map[string]int `envconfig:"tax_rates"`
+	Currency string         `envconfig:"currency" default:"EUR"`
+
+	EventHistorySize   int  `envconfig:"event_history_size" default:"1000"`
+	EventSourcedOrders bool `envconfig:"event_sourced_orders"`
+
+	PaymentProvider         string `envconfig:"payment_provider" default:"local"`
+	PaymentWebhookSecret    string `envconfig:"payment_webhook_secret"`
+	LocalPaymentAutoCapture bool   `envconfig:"local_payment_auto_capture" default:"true"`
+
+	WebhookMaxAttempts  int           `envconfig:"webhook_max_attempts" default:"8"`
+	WebhookBaseBackoff  time.Duration `envconfig:"webhook_base_backoff" default:"5s"`
+	WebhookMaxBackoff   time.Duration `envconfig:"webhook_max_backoff" default:"1h"`
+	WebhookPollInterval time.Duration `envconfig:"webhook_poll_interval" default:"1s"`
+	WebhookTimeout      time.Duration `envconfig:"webhook_timeout" default:"10s"`
+	// WebhookSecretKey is a base64 encoded 32 byte key, webhooks can't be saved until it is set
+	WebhookSecretKey string `envconfig:"webhook_secret_key"`
+	webhookSecrets   *repository.SecretCipher
+
+	// MaxBodySize is in bytes, rates are requests per second refilling bursts
+	MaxBodySize             int64   `envconfig:"max_body_size" default:"1048576"`
+	RateLimitIPRate         float64 `envconfig:"rate_limit_ip_rate" default:"20"`
+	RateLimitIPBurst        int     `envconfig:"rate_limit_ip_burst" default:"40"`
+	RateLimitPrincipalRate  float64 `envconfig:"rate_limit_principal_rate" default:"10"`
+	RateLimitPrincipalBurst int     `envconfig:"rate_limit_principal_burst" default:"20"`
+	RateLimitTrustedProxies int     `envconfig:"rate_limit_trusted_proxies"`
+
+	CORSAllowedOrigins   []string      `envconfig:"cors_allowed_origins"`
+	CORSAllowedMethods   []string      `envconfig:"cors_allowed_methods" default:"GET,POST,PUT,PATCH,DELETE"`
+	CORSAllowedHeaders   []string      `envconfig:"cors_allowed_headers" default:"Content-Type,Accept,Authorization,X-Money-Format,Last-Event-ID"`
+	CORSExposedHeaders   []string      `envconfig:"cors_exposed_headers" default:"RateLimit-Limit,RateLimit-Remaining,RateLimit-Reset,Retry-After"`
+	CORSAllowCredentials bool          `envconfig:"cors_allow_credentials"`
+	CORSMaxAge           time.Duration `envconfig:"cors_max_age" default:"10m"`
+	HSTSMaxAge           time.Duration `envconfig:"hsts_max_age" default:"8760h"`
+
+	// TLS is enabled with both cert and key files, client CA file enables mutual TLS
+	TLSCertFile       string        `envconfig:"tls_cert_file"`
+	TLSKeyFile        string        `envconfig:"tls_key_file"`
+	TLSClientCAFile   string        `envconfig:"tls_client_ca_file"`
+	TLSReloadInterval time.Duration `envconfig:"tls_reload_interval" default:"30s"`
+
+	// API_V1_DEPRECATED_AT and API_V1_SUNSET are RFC 3339 times, unset omits the header
+	APIV1DeprecatedAt time.Time `envconfig:"api_v1_deprecated_at"`
+	APIV1Sunset       time.Time `envconfig:"api_v1_sunset"`
+
+	// AdminToken is a bearer token of admin routes, they answer 403 until it is set
+	AdminToken string `envconfig:"admin_token"`
+	// APITokens are token:principal pairs, the principal is the actor of changes made with the token
+	APITokens map[string]string `envconfig:"api_tokens"`
 }
 
 func main() {
@@ -33,13 +110,86 @@ func main() {
 
 	setupLogger()
 
+	if len(os.Args) > 1 {
+		runCommand(c, os.Args[1:])
+		return
+	}
+
 	killSignalChan := getKillSignalChan()
-	srv := startServer(c)
+	db := createDbConn(c)
+	if c.EventSourcedOrders {
+		importOrderEvents(db)
+	}
+	tc := transportConfig(c)
+	tlsConfig, stopCertReloader := startCertReloader(c)
+	tc.TLS = tlsConfig
+	services := transport.NewServices(db, tc)
+	srv := startServer(c, services, tc)
+	grpcSrv := startGRPCServer(c, services)
+	metricsSrv := startMetricsServer(c, services)
+	stopWebhooks := startWebhookDispatcher(c, db, tc)
 
 	waitForKillSignal(killSignalChan)
+	stopWebhooks()
+	stopCertReloader()
+	tc.Events.Close()
+	grpcSrv.GracefulStop()
+	if err = metricsSrv.Shutdown(context.Background()); err != nil {
+		log.Error(err)
+	}
 	log.Fatal(srv.Shutdown(context.Background()))
 }
 
+// runCommand runs maintenance commands instead of the server
+func runCommand(c *config, args []string) {
+	switch args[0] {
+	case rebuildProjectionsCommand:
+		flags := flag.NewFlagSet(rebuildProjectionsCommand, flag.ExitOnError)
+		importState := flags.Bool("import", false, "create events for orders written without event sourcing")
+		if err := flags.Parse(args[1:]); err != nil {
+			log.Fatal(err)
+		}
+
+		db := createDbConn(c)
+		defer db.Close()
+
+		if *importState {
+			importOrderEvents(db)
+		}
+
+		rebuilt, err := repository.RebuildOrderProjection(db)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.WithFields(log.Fields{"orders": rebuilt}).Info("order projection rebuilt")
+	case encryptWebhookSecretsCommand:
+		if c.webhookSecrets == nil {
+			log.Fatal("webhook secret key is not configured")
+		}
+
+		db := createDbConn(c)
+		defer db.Close()
+
+		encrypted, err := repository.EncryptWebhookSecrets(db, c.webhookSecrets)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.WithFields(log.Fields{"webhooks": encrypted}).Info("webhook secrets encrypted")
+	default:
+		log.Fatalf("unknown command: %s", args[0])
+	}
+}
+
+// importOrderEvents brings the event store up to orders written without event sourcing,
+// otherwise the event sourced repository doesn't find them
+func importOrderEvents(db *sql.DB) {
+	imported, err := repository.ImportOrderEvents(db, importActor)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.WithFields(log.Fields{"orders": imported}).Info("orders imported to the event store")
+}
+
 func getKillSignalChan() chan os.Signal {
 	osKillSignalChan := make(chan os.Signal, 1)
 	signal.Notify(osKillSignalChan, os.Interrupt, syscall.SIGTERM)
@@ -57,6 +207,38 @@ func parseConfig() (*config, error) {
 		return nil, err
 	}
 
+	if err := model.ValidateCurrency(c.Currency); err != nil {
+		return nil, err
+	}
+
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") || (c.TLSClientCAFile != "" && c.TLSCertFile == "") {
+		return nil, fmt.Errorf("tls requires both cert and key files")
+	}
+
+	if err := transportCORSConfig(&c).Validate(); err != nil {
+		return nil, err
+	}
+
+	if c.TLSReloadInterval <= 0 {
+		return nil, fmt.Errorf("invalid tls reload interval: %s", c.TLSReloadInterval)
+	}
+
+	if c.WebhookMaxAttempts <= 0 || c.WebhookPollInterval <= 0 || c.WebhookBaseBackoff <= 0 || c.WebhookMaxBackoff < c.WebhookBaseBackoff {
+		return nil, fmt.Errorf("invalid webhook delivery config")
+	}
+
+	if c.WebhookSecretKey != "" {
+		key, err := base64.StdEncoding.DecodeString(c.WebhookSecretKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid webhook secret key: %s", err)
+		}
+
+		c.webhookSecrets, err = repository.NewSecretCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid webhook secret key: %s", err)
+		}
+	}
+
 	return &c, nil
 }
 
@@ -70,19 +252,141 @@ func waitForKillSignal(ch <-chan os.Signal) {
 	}
 }
 
-func startServer(c *config) *http.Server {
+func transportConfig(c *config) transport.Config {
+	return transport.Config{
+		Pricing: service.PricingConfig{
+			Currency: c.Currency,
+			TaxRates: service.TaxRates{Default: c.TaxRate, ByDeliveryType: c.TaxRates},
+		},
+		Payment: service.PaymentConfig{
+			Providers:       []service.PaymentProvider{payment.NewLocalProvider(c.PaymentWebhookSecret, c.LocalPaymentAutoCapture)},
+			DefaultProvider: c.PaymentProvider,
+		},
+		Events:             event.NewBroker(c.EventHistorySize),
+		EventSourcedOrders: c.EventSourcedOrders,
+		CORS:               transportCORSConfig(c),
+		SecurityHeaders:    transport.SecurityHeadersConfig{HSTSMaxAge: c.HSTSMaxAge},
+		Auth:               transport.AuthConfig{AdminToken: c.AdminToken, APITokens: c.APITokens},
+		WebhookSecrets:     c.webhookSecrets,
+		Versioning: transport.VersioningConfig{
+			V1DeprecatedAt: c.APIV1DeprecatedAt,
+			V1Sunset:       c.APIV1Sunset,
+		},
+		Limits: transport.LimitsConfig{
+			MaxBodySize:    c.MaxBodySize,
+			IPRate:         transport.RateLimit{Rate: c.RateLimitIPRate, Burst: c.RateLimitIPBurst},
+			PrincipalRate:  transport.RateLimit{Rate: c.RateLimitPrincipalRate, Burst: c.RateLimitPrincipalBurst},
+			TrustedProxies: c.RateLimitTrustedProxies,
+		},
+	}
+}
+
+func transportCORSConfig(c *config) transport.CORSConfig {
+	return transport.CORSConfig{
+		AllowedOrigins:   c.CORSAllowedOrigins,
+		AllowedMethods:   c.CORSAllowedMethods,
+		AllowedHeaders:   c.CORSAllowedHeaders,
+		ExposedHeaders:   c.CORSExposedHeaders,
+		AllowCredentials: c.CORSAllowCredentials,
+		MaxAge:           c.CORSMaxAge,
+	}
+}
+
+// startCertReloader returns nil config when TLS isn't configured
+func startCertReloader(c *config) (*tls.Config, context.CancelFunc) {
+	if c.TLSCertFile == "" {
+		return nil, func() {}
+	}
+
+	reloader, err := transport.NewCertReloader(transport.TLSConfig{
+		CertFile:     c.TLSCertFile,
+		KeyFile:      c.TLSKeyFile,
+		ClientCAFile: c.TLSClientCAFile,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go reloader.Watch(ctx, c.TLSReloadInterval)
+
+	return reloader.ServerConfig(), cancel
+}
+
+func startServer(c *config, services *transport.Services, tc transport.Config) *http.Server {
 	log.WithFields(log.Fields{"port": c.ServerPort}).Info("starting the server")
-	db := createDbConn(c)
-	router := transport.Router(db)
-	srv := &http.Server{Addr: fmt.Sprintf(":%s", c.ServerPort), Handler: router}
+	router := transport.Router(services)
+	srv := &http.Server{Addr: fmt.Sprintf(":%s", c.ServerPort), Handler: router, TLSConfig: tc.TLS}
 	go func() {
-		log.Fatal(srv.ListenAndServe())
-		log.Fatal(db.Close())
+		var err error
+		if tc.TLS != nil {
+			// certificates come from TLSConfig so they can be reloaded
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
 	}()
 
 	return srv
 }
 
+func startMetricsServer(c *config, services *transport.Services) *http.Server {
+	log.WithFields(log.Fields{"port": c.MetricsPort}).Info("starting the metrics server")
+	srv := &http.Server{Addr: fmt.Sprintf(":%s", c.MetricsPort), Handler: transport.MetricsHandler(services)}
+	go func() {
+		if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	return srv
+}
+
+func startGRPCServer(c *config, services *transport.Services) *grpc.Server {
+	log.WithFields(log.Fields{"port": c.GRPCPort}).Info("starting the grpc server")
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%s", c.GRPCPort))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	srv := transport.GRPCServer(services)
+	go func() {
+		// Serve returns nil after GracefulStop, it isn't a failure
+		if err := srv.Serve(listener); err != nil && err != grpc.ErrServerStopped {
+			log.Fatal(err)
+		}
+	}()
+
+	return srv
+}
+
+// startWebhookDispatcher runs the delivery worker pool, the returned function stops it
+func startWebhookDispatcher(c *config, db *sql.DB, tc transport.Config) context.CancelFunc {
+	dispatcher := service.NewWebhookDispatcher(
+		repository.NewWebhookRepository(db, c.webhookSecrets),
+		repository.NewWebhookDeliveryRepository(db),
+		webhook.NewHTTPSender(c.WebhookTimeout),
+		tc.Events,
+		service.WebhookDispatcherConfig{
+			MaxAttempts:    c.WebhookMaxAttempts,
+			BaseBackoff:    c.WebhookBaseBackoff,
+			MaxBackoff:     c.WebhookMaxBackoff,
+			PollInterval:   c.WebhookPollInterval,
+			BatchSize:      webhookBatchSize,
+			Workers:        webhookWorkers,
+			AttemptTimeout: c.WebhookTimeout,
+		},
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go dispatcher.Run(ctx)
+
+	return cancel
+}
+
 func createDbConn(c *config) *sql.DB {
 	arguments := c.DatabaseArguments
 	if len(arguments) > 0 {
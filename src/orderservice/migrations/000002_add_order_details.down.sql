@@ -0,0 +1,9 @@
+ALTER TABLE `order_item`
+    DROP COLUMN `modifiers`,
+    DROP COLUMN `comment`;
+
+ALTER TABLE `order`
+    DROP COLUMN `notes`,
+    DROP COLUMN `delivery_type`,
+    DROP COLUMN `delivery_address`,
+    DROP COLUMN `phone`;
@@ -0,0 +1,9 @@
+ALTER TABLE `order`
+    ADD COLUMN `notes` VARCHAR(500) NOT NULL DEFAULT '',
+    ADD COLUMN `delivery_type` VARCHAR(16) NOT NULL DEFAULT 'pickup',
+    ADD COLUMN `delivery_address` VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN `phone` VARCHAR(32) NOT NULL DEFAULT '';
+
+ALTER TABLE `order_item`
+    ADD COLUMN `modifiers` JSON,
+    ADD COLUMN `comment` VARCHAR(255) NOT NULL DEFAULT '';
@@ -0,0 +1,7 @@
+ALTER TABLE `order`
+    DROP COLUMN `subtotal`,
+    DROP COLUMN `discount`,
+    DROP COLUMN `tax`,
+    DROP COLUMN `promo_code`;
+
+DROP TABLE promo_code;
@@ -0,0 +1,23 @@
+CREATE TABLE `promo_code` (
+    `code` VARCHAR(64) NOT NULL,
+    `type` VARCHAR(16) NOT NULL,
+    `value` INTEGER NOT NULL DEFAULT 0,
+    `buy_quantity` INTEGER NOT NULL DEFAULT 0,
+    `free_quantity` INTEGER NOT NULL DEFAULT 0,
+    `menu_item_id` BINARY(16),
+    `valid_from` DATETIME,
+    `valid_to` DATETIME,
+    `usage_limit` INTEGER NOT NULL DEFAULT 0,
+    `used_count` INTEGER NOT NULL DEFAULT 0,
+    `created_at` DATETIME NOT NULL,
+    `updated_at` DATETIME NOT NULL,
+    PRIMARY KEY (code)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+ALTER TABLE `order`
+    ADD COLUMN `subtotal` INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN `discount` INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN `tax` INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN `promo_code` VARCHAR(64);
+
+UPDATE `order` SET `subtotal` = `cost`;
@@ -0,0 +1,14 @@
+UPDATE `promo_code` SET `value` = ROUND(`value` / 100) WHERE `type` = 'fixed';
+
+UPDATE `order` SET
+    `cost` = ROUND(`cost` / 100),
+    `subtotal` = ROUND(`subtotal` / 100),
+    `discount` = ROUND(`discount` / 100),
+    `tax` = ROUND(`tax` / 100);
+
+ALTER TABLE `order`
+    DROP COLUMN `currency`,
+    MODIFY COLUMN `cost` INTEGER NOT NULL,
+    MODIFY COLUMN `subtotal` INTEGER NOT NULL DEFAULT 0,
+    MODIFY COLUMN `discount` INTEGER NOT NULL DEFAULT 0,
+    MODIFY COLUMN `tax` INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,15 @@
+ALTER TABLE `order`
+    MODIFY COLUMN `cost` BIGINT NOT NULL,
+    MODIFY COLUMN `subtotal` BIGINT NOT NULL DEFAULT 0,
+    MODIFY COLUMN `discount` BIGINT NOT NULL DEFAULT 0,
+    MODIFY COLUMN `tax` BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN `currency` CHAR(3) NOT NULL DEFAULT 'EUR' AFTER `cost`;
+
+-- amounts were in major units, they are stored in minor units of EUR (cents) from now on
+UPDATE `order` SET
+    `cost` = `cost` * 100,
+    `subtotal` = `subtotal` * 100,
+    `discount` = `discount` * 100,
+    `tax` = `tax` * 100;
+
+UPDATE `promo_code` SET `value` = `value` * 100 WHERE `type` = 'fixed';
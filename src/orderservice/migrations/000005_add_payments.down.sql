@@ -0,0 +1,4 @@
+DROP TABLE payment;
+
+ALTER TABLE `order`
+    DROP COLUMN `status`;
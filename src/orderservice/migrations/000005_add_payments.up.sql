@@ -0,0 +1,17 @@
+ALTER TABLE `order`
+    ADD COLUMN `status` VARCHAR(16) NOT NULL DEFAULT 'created';
+
+CREATE TABLE `payment` (
+    `payment_id` BINARY(16),
+    `order_id` BINARY(16) NOT NULL,
+    `provider` VARCHAR(32) NOT NULL,
+    `provider_ref` VARCHAR(128) NOT NULL DEFAULT '',
+    `amount` BIGINT NOT NULL,
+    `currency` CHAR(3) NOT NULL,
+    `status` VARCHAR(16) NOT NULL,
+    `created_at` DATETIME NOT NULL,
+    `updated_at` DATETIME NOT NULL,
+    PRIMARY KEY (payment_id),
+    KEY (provider, provider_ref),
+    FOREIGN KEY (`order_id`) REFERENCES `order`(`order_id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,27 @@
+CREATE TABLE `webhook` (
+    `webhook_id` BINARY(16),
+    `url` VARCHAR(2048) NOT NULL,
+    `event_types` JSON NOT NULL,
+    `secret` VARCHAR(128) NOT NULL,
+    `created_at` DATETIME NOT NULL,
+    `updated_at` DATETIME NOT NULL,
+    PRIMARY KEY (webhook_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE `webhook_delivery` (
+    `delivery_id` BINARY(16),
+    `webhook_id` BINARY(16) NOT NULL,
+    `event_id` BIGINT UNSIGNED NOT NULL,
+    `event_type` VARCHAR(32) NOT NULL,
+    `payload` JSON NOT NULL,
+    `status` VARCHAR(16) NOT NULL,
+    `attempts` INTEGER NOT NULL DEFAULT 0,
+    `next_attempt_at` DATETIME NOT NULL,
+    `last_status_code` INTEGER NOT NULL DEFAULT 0,
+    `last_error` VARCHAR(1024) NOT NULL DEFAULT '',
+    `created_at` DATETIME NOT NULL,
+    `updated_at` DATETIME NOT NULL,
+    PRIMARY KEY (delivery_id),
+    KEY (status, next_attempt_at),
+    FOREIGN KEY (`webhook_id`) REFERENCES `webhook`(`webhook_id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,44 @@
+CREATE TABLE `order_audit` (
+    `audit_id` BINARY(16),
+    `order_id` BINARY(16) NOT NULL,
+    `revision` INTEGER NOT NULL,
+    `action` VARCHAR(32) NOT NULL,
+    `actor` VARCHAR(255) NOT NULL,
+    `before` JSON NULL,
+    `after` JSON NULL,
+    `created_at` DATETIME(6) NOT NULL,
+    PRIMARY KEY (audit_id),
+    UNIQUE KEY (order_id, revision),
+    FOREIGN KEY (`order_id`) REFERENCES `order`(`order_id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- existing orders get their current state as the first revision
+INSERT INTO `order_audit` (`audit_id`, `order_id`, `revision`, `action`, `actor`, `before`, `after`, `created_at`)
+SELECT
+    UUID_TO_BIN(UUID()),
+    o.order_id,
+    1,
+    'imported',
+    'migration',
+    NULL,
+    JSON_OBJECT(
+        'menuItems', IFNULL((
+            SELECT JSON_ARRAYAGG(JSON_OBJECT(
+                'id', BIN_TO_UUID(oi.menu_item_id),
+                'quantity', oi.quantity,
+                'modifiers', oi.modifiers,
+                'comment', oi.comment))
+            FROM order_item oi
+            WHERE oi.order_id = o.order_id
+        ), JSON_ARRAY()),
+        'currency', o.currency,
+        'cost', o.cost,
+        'subtotal', o.subtotal,
+        'discount', o.discount,
+        'tax', o.tax,
+        'promoCode', IFNULL(o.promo_code, ''),
+        'notes', o.notes,
+        'delivery', JSON_OBJECT('type', o.delivery_type, 'address', o.delivery_address, 'phone', o.phone),
+        'status', o.status),
+    o.created_at
+FROM `order` o;
@@ -0,0 +1,11 @@
+CREATE TABLE `order_event` (
+    `event_id` BIGINT UNSIGNED AUTO_INCREMENT,
+    `aggregate_id` BINARY(16) NOT NULL,
+    `version` INTEGER NOT NULL,
+    `event_type` VARCHAR(64) NOT NULL,
+    `payload` JSON NOT NULL,
+    `actor` VARCHAR(255) NOT NULL,
+    `occurred_at` DATETIME(6) NOT NULL,
+    PRIMARY KEY (event_id),
+    UNIQUE KEY (aggregate_id, version)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1 @@
+ALTER TABLE webhook MODIFY `secret` VARCHAR(128) NOT NULL;
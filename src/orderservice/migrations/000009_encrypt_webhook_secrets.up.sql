@@ -0,0 +1 @@
+ALTER TABLE webhook MODIFY `secret` VARCHAR(255) NOT NULL;
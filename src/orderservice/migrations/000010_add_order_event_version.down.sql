@@ -0,0 +1 @@
+ALTER TABLE `order` DROP COLUMN `event_version`;
@@ -0,0 +1 @@
+ALTER TABLE `order` ADD COLUMN `event_version` INTEGER NULL;
@@ -0,0 +1 @@
+ALTER TABLE `order` DROP COLUMN `promo_terms`;
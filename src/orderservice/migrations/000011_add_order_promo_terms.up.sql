@@ -0,0 +1,11 @@
+ALTER TABLE `order` ADD COLUMN `promo_terms` JSON NULL AFTER `promo_code`;
+
+-- orders keep the terms of their promo code, codes deleted before can't be restored
+UPDATE `order` o
+    JOIN `promo_code` p ON p.`code` = o.`promo_code`
+SET o.`promo_terms` = JSON_OBJECT(
+    'type', p.`type`,
+    'value', p.`value`,
+    'buyQuantity', p.`buy_quantity`,
+    'freeQuantity', p.`free_quantity`,
+    'menuItemId', BIN_TO_UUID(p.`menu_item_id`));
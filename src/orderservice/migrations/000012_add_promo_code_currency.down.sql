@@ -0,0 +1,2 @@
+UPDATE `order` SET `promo_terms` = JSON_REMOVE(`promo_terms`, '$.currency') WHERE `promo_terms` IS NOT NULL;
+ALTER TABLE `promo_code` DROP COLUMN `currency`;
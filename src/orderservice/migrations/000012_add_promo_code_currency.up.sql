@@ -0,0 +1,6 @@
+ALTER TABLE `promo_code` ADD COLUMN `currency` CHAR(3) NULL AFTER `value`;
+
+-- fixed values were converted to minor units of EUR together with order amounts
+UPDATE `promo_code` SET `currency` = 'EUR' WHERE `type` = 'fixed';
+UPDATE `order` SET `promo_terms` = JSON_SET(`promo_terms`, '$.currency', 'EUR')
+WHERE JSON_UNQUOTE(JSON_EXTRACT(`promo_terms`, '$.type')) = 'fixed';
@@ -0,0 +1,17 @@
+package data
+
+import "time"
+
+const (
+	OrderCreatedEvent = "order.created"
+	OrderUpdatedEvent = "order.updated"
+	OrderDeletedEvent = "order.deleted"
+)
+
+type OrderEvent struct {
+	ID         uint64     `json:"id"`
+	Type       string     `json:"type"`
+	OrderID    string     `json:"orderId"`
+	OccurredAt time.Time  `json:"occurredAt"`
+	Order      *OrderInfo `json:"order,omitempty"`
+}
@@ -0,0 +1,13 @@
+package data
+
+import "orderservice/pkg/orderservice/model"
+
+// Money is rendered as decimal string in major units to avoid float rounding on clients
+type Money struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+func NewMoney(m model.Money) Money {
+	return Money{Amount: m.Format(), Currency: m.Currency}
+}
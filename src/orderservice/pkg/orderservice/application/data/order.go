@@ -6,15 +6,35 @@ import (
 )
 
 type MenuItem struct {
-	ID       string `json:"id"`
-	Quantity int    `json:"quantity"`
+	ID        string   `json:"id"`
+	Quantity  int      `json:"quantity"`
+	Modifiers []string `json:"modifiers,omitempty"`
+	Comment   string   `json:"comment,omitempty"`
+}
+
+type Delivery struct {
+	Type    string `json:"type"`
+	Address string `json:"address,omitempty"`
+	Phone   string `json:"phone,omitempty"`
 }
 
 type OrderInfo struct {
 	ID        string     `json:"id"`
 	MenuItems []MenuItem `json:"menuItems"`
 	OrderedAt time.Time  `json:"orderedAtTimestamp"`
-	Cost      int        `json:"cost"`
+	Cost      Money      `json:"cost"`
+	Notes     string     `json:"notes,omitempty"`
+	Delivery  Delivery   `json:"delivery"`
+	Pricing   Pricing    `json:"pricing"`
+	Status    string     `json:"status"`
+}
+
+type Pricing struct {
+	Subtotal  Money  `json:"subtotal"`
+	Discount  Money  `json:"discount"`
+	Tax       Money  `json:"tax"`
+	Total     Money  `json:"total"`
+	PromoCode string `json:"promoCode,omitempty"`
 }
 
 type OrdersList struct {
@@ -22,3 +42,11 @@ type OrdersList struct {
 }
 
 var InternalError error = errors.New("internal error")
+var OrderNotFoundError error = errors.New("order not found")
+var OrderDeletedError error = errors.New("order deleted")
+var OrderConflictError error = errors.New("order is modified concurrently, reload it and retry")
+var PromoCodeNotFoundError error = errors.New("promo code not found")
+var PaymentProviderNotFoundError error = errors.New("payment provider not found")
+var InvalidSignatureError error = errors.New("invalid signature")
+var WebhookNotFoundError error = errors.New("webhook not found")
+var WebhookDeliveryNotFoundError error = errors.New("webhook delivery not found")
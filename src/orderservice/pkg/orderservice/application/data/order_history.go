@@ -0,0 +1,36 @@
+package data
+
+import "time"
+
+// OrderSnapshot is the state of an order at some revision
+type OrderSnapshot struct {
+	MenuItems []MenuItem `json:"menuItems"`
+	Cost      Money      `json:"cost"`
+	Pricing   Pricing    `json:"pricing"`
+	Notes     string     `json:"notes"`
+	Delivery  Delivery   `json:"delivery"`
+	Status    string     `json:"status"`
+}
+
+// FieldChange is a changed value addressed by JSON pointer, menu items are addressed by id
+// e.g. "/menuItems/{id}/quantity", absent values are omitted
+type FieldChange struct {
+	Path   string      `json:"path"`
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+type OrderRevision struct {
+	Revision  int            `json:"revision"`
+	Action    string         `json:"action"`
+	Actor     string         `json:"actor"`
+	ChangedAt time.Time      `json:"changedAt"`
+	Before    *OrderSnapshot `json:"before"`
+	After     *OrderSnapshot `json:"after"`
+	Changes   []FieldChange  `json:"changes"`
+}
+
+type OrderHistory struct {
+	OrderID   string          `json:"orderId"`
+	Revisions []OrderRevision `json:"revisions"`
+}
@@ -0,0 +1,13 @@
+package data
+
+import "time"
+
+type PaymentInfo struct {
+	ID          string    `json:"id"`
+	OrderID     string    `json:"orderId"`
+	Provider    string    `json:"provider"`
+	ProviderRef string    `json:"providerRef"`
+	Amount      Money     `json:"amount"`
+	Status      string    `json:"status"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
@@ -0,0 +1,21 @@
+package data
+
+import "time"
+
+type PromoCode struct {
+	Code         string     `json:"code"`
+	Type         string     `json:"type"`
+	Value        int        `json:"value,omitempty"`
+	Currency     string     `json:"currency,omitempty"`
+	BuyQuantity  int        `json:"buyQuantity,omitempty"`
+	FreeQuantity int        `json:"freeQuantity,omitempty"`
+	MenuItemID   string     `json:"menuItemId,omitempty"`
+	ValidFrom    *time.Time `json:"validFrom,omitempty"`
+	ValidTo      *time.Time `json:"validTo,omitempty"`
+	UsageLimit   int        `json:"usageLimit"`
+	UsedCount    int        `json:"usedCount"`
+}
+
+type PromoCodesList struct {
+	PromoCodes []PromoCode `json:"promoCodes"`
+}
@@ -0,0 +1,119 @@
+package event
+
+import (
+	"orderservice/pkg/orderservice/application/data"
+	"sync"
+	"time"
+)
+
+const subscriptionBufferSize = 64
+
+// Broker fans out order events to in-process subscribers and keeps recent
+// events so reconnecting clients can continue from their last event id
+type Broker struct {
+	mu          sync.Mutex
+	lastID      uint64
+	history     []data.OrderEvent
+	historySize int
+	subscribers map[*Subscription]struct{}
+	closed      bool
+}
+
+type Subscription struct {
+	C      <-chan data.OrderEvent
+	ch     chan data.OrderEvent
+	filter func(data.OrderEvent) bool
+	broker *Broker
+}
+
+func NewBroker(historySize int) *Broker {
+	return &Broker{
+		historySize: historySize,
+		subscribers: map[*Subscription]struct{}{},
+	}
+}
+
+func (b *Broker) Publish(e data.OrderEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	b.lastID++
+	e.ID = b.lastID
+	if e.OccurredAt.IsZero() {
+		e.OccurredAt = time.Now()
+	}
+
+	b.history = append(b.history, e)
+	if len(b.history) > b.historySize {
+		b.history = b.history[len(b.history)-b.historySize:]
+	}
+
+	for s := range b.subscribers {
+		if s.filter != nil && !s.filter(e) {
+			continue
+		}
+
+		select {
+		case s.ch <- e:
+		default:
+			// slow subscriber is disconnected, it can reconnect with its last event id
+			b.unsubscribe(s)
+		}
+	}
+}
+
+// Subscribe returns missed events after lastEventID and subscription for the new ones,
+// both are taken under the same lock so no event is lost or duplicated between them
+func (b *Broker) Subscribe(lastEventID uint64, filter func(data.OrderEvent) bool) (*Subscription, []data.OrderEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan data.OrderEvent, subscriptionBufferSize)
+	s := &Subscription{C: ch, ch: ch, filter: filter, broker: b}
+	if b.closed {
+		close(ch)
+		return s, nil
+	}
+
+	replay := make([]data.OrderEvent, 0)
+	if lastEventID > 0 {
+		for _, e := range b.history {
+			if e.ID > lastEventID && (filter == nil || filter(e)) {
+				replay = append(replay, e)
+			}
+		}
+	}
+
+	b.subscribers[s] = struct{}{}
+
+	return s, replay
+}
+
+func (b *Broker) unsubscribe(s *Subscription) {
+	if _, found := b.subscribers[s]; found {
+		delete(b.subscribers, s)
+		close(s.ch)
+	}
+}
+
+// Close disconnects all subscribers, it is called on server shutdown
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for s := range b.subscribers {
+		b.unsubscribe(s)
+	}
+}
+
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+
+	s.broker.unsubscribe(s)
+}
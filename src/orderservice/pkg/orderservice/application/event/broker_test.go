@@ -0,0 +1,45 @@
+package event
+
+import (
+	"orderservice/pkg/orderservice/application/data"
+	"testing"
+)
+
+func TestBrokerReplay(t *testing.T) {
+	b := NewBroker(2)
+	b.Publish(data.OrderEvent{Type: data.OrderCreatedEvent, OrderID: "a"})
+	b.Publish(data.OrderEvent{Type: data.OrderCreatedEvent, OrderID: "b"})
+	b.Publish(data.OrderEvent{Type: data.OrderUpdatedEvent, OrderID: "a"})
+
+	s, replay := b.Subscribe(1, func(e data.OrderEvent) bool { return e.OrderID == "a" })
+	defer s.Close()
+
+	if len(replay) != 1 || replay[0].ID != 3 {
+		t.Errorf("Replay is wrong: %v", replay)
+	}
+
+	b.Publish(data.OrderEvent{Type: data.OrderDeletedEvent, OrderID: "b"})
+	b.Publish(data.OrderEvent{Type: data.OrderDeletedEvent, OrderID: "a"})
+
+	e := <-s.C
+	if e.ID != 5 || e.Type != data.OrderDeletedEvent {
+		t.Errorf("Event is wrong: %v", e)
+	}
+}
+
+func TestBrokerDisconnectsSlowSubscriber(t *testing.T) {
+	b := NewBroker(1)
+	s, _ := b.Subscribe(0, nil)
+	for i := 0; i <= subscriptionBufferSize; i++ {
+		b.Publish(data.OrderEvent{Type: data.OrderCreatedEvent})
+	}
+
+	received := 0
+	for range s.C {
+		received++
+	}
+
+	if received != subscriptionBufferSize {
+		t.Errorf("Received events count is wrong. Have: %d, want: %d", received, subscriptionBufferSize)
+	}
+}
@@ -5,4 +5,6 @@ import "orderservice/pkg/orderservice/application/data"
 type OrderQueryService interface {
 	GetOrders() (*data.OrdersList, error)
 	GetOrderInfo(id string) (*data.OrderInfo, error)
+	// GetOrderHistory returns revisions of an order including deleted one, nil if it has none
+	GetOrderHistory(id string) (*data.OrderHistory, error)
 }
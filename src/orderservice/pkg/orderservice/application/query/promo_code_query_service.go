@@ -0,0 +1,8 @@
+package query
+
+import "orderservice/pkg/orderservice/application/data"
+
+type PromoCodeQueryService interface {
+	GetPromoCodes() (*data.PromoCodesList, error)
+	GetPromoCode(code string) (*data.PromoCode, error)
+}
@@ -0,0 +1,10 @@
+package query
+
+import "orderservice/pkg/orderservice/application/data"
+
+type WebhookQueryService interface {
+	GetWebhooks() (*data.WebhooksList, error)
+	GetWebhook(id string) (*data.Webhook, error)
+	// GetDeliveries lists deliveries with status, all deliveries if status is empty
+	GetDeliveries(status string) (*data.WebhookDeliveriesList, error)
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"orderservice/pkg/orderservice/application/data"
+	"orderservice/pkg/orderservice/model"
+)
+
+type OrderEventPublisher interface {
+	Publish(e data.OrderEvent)
+}
+
+func newOrderInfo(o model.Order) *data.OrderInfo {
+	total, _ := o.Pricing.Total()
+	return &data.OrderInfo{
+		ID:        o.ID.String(),
+		MenuItems: toDataMenuItems(o.MenuItems),
+		OrderedAt: o.OrderedAt,
+		Cost:      data.NewMoney(o.Cost),
+		Notes:     o.Notes,
+		Delivery: data.Delivery{
+			Type:    string(o.Delivery.Type),
+			Address: o.Delivery.Address,
+			Phone:   o.Delivery.Phone,
+		},
+		Pricing: data.Pricing{
+			Subtotal:  data.NewMoney(o.Pricing.Subtotal),
+			Discount:  data.NewMoney(o.Pricing.Discount),
+			Tax:       data.NewMoney(o.Pricing.Tax),
+			Total:     data.NewMoney(total),
+			PromoCode: o.Pricing.PromoCode,
+		},
+		Status: string(o.Status),
+	}
+}
+
+func publishOrderEvent(publisher OrderEventPublisher, eventType string, o model.Order) {
+	publisher.Publish(data.OrderEvent{Type: eventType, OrderID: o.ID.String(), Order: newOrderInfo(o)})
+}
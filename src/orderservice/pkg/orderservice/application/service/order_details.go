@@ -0,0 +1,98 @@
+package service
+
+import (
+	"fmt"
+	"orderservice/pkg/orderservice/application/data"
+	"orderservice/pkg/orderservice/model"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxNotesLength    = 500
+	maxCommentLength  = 255
+	maxModifierLength = 64
+	maxModifiersCount = 20
+	maxAddressLength  = 255
+)
+
+var phoneRegexp = regexp.MustCompile(`^\+?[0-9]{7,15}$`)
+var phoneSeparators = strings.NewReplacer(" ", "", "-", "", "(", "", ")", "")
+
+func validateItemDetails(item data.MenuItem) error {
+	if utf8.RuneCountInString(item.Comment) > maxCommentLength {
+		return fmt.Errorf("item: %s comment is longer than %d characters", item.ID, maxCommentLength)
+	}
+	if len(item.Modifiers) > maxModifiersCount {
+		return fmt.Errorf("item: %s has more than %d modifiers", item.ID, maxModifiersCount)
+	}
+	for _, modifier := range item.Modifiers {
+		if strings.TrimSpace(modifier) == "" {
+			return fmt.Errorf("item: %s has empty modifier", item.ID)
+		}
+		if utf8.RuneCountInString(modifier) > maxModifierLength {
+			return fmt.Errorf("item: %s modifier is longer than %d characters", item.ID, maxModifierLength)
+		}
+	}
+
+	return nil
+}
+
+func validateNotes(notes string) (string, error) {
+	notes = strings.TrimSpace(notes)
+	if utf8.RuneCountInString(notes) > maxNotesLength {
+		return "", fmt.Errorf("notes are longer than %d characters", maxNotesLength)
+	}
+
+	return notes, nil
+}
+
+func validatePhone(phone string) (string, error) {
+	normalized := phoneSeparators.Replace(strings.TrimSpace(phone))
+	if !phoneRegexp.MatchString(normalized) {
+		return "", fmt.Errorf("invalid phone: %s", phone)
+	}
+
+	return normalized, nil
+}
+
+func validateDelivery(d data.Delivery) (model.Delivery, error) {
+	delivery := model.Delivery{
+		Type:    model.DeliveryType(d.Type),
+		Address: strings.TrimSpace(d.Address),
+	}
+	if delivery.Type == "" {
+		delivery.Type = model.DeliveryTypePickup
+	}
+
+	switch delivery.Type {
+	case model.DeliveryTypePickup:
+		if delivery.Address != "" {
+			return model.Delivery{}, fmt.Errorf("address is allowed only for %s", model.DeliveryTypeDelivery)
+		}
+	case model.DeliveryTypeDelivery:
+		if delivery.Address == "" {
+			return model.Delivery{}, fmt.Errorf("address is required for %s", model.DeliveryTypeDelivery)
+		}
+		if d.Phone == "" {
+			return model.Delivery{}, fmt.Errorf("phone is required for %s", model.DeliveryTypeDelivery)
+		}
+	default:
+		return model.Delivery{}, fmt.Errorf("invalid delivery type: %s", d.Type)
+	}
+
+	if utf8.RuneCountInString(delivery.Address) > maxAddressLength {
+		return model.Delivery{}, fmt.Errorf("address is longer than %d characters", maxAddressLength)
+	}
+
+	if d.Phone != "" {
+		phone, err := validatePhone(d.Phone)
+		if err != nil {
+			return model.Delivery{}, err
+		}
+		delivery.Phone = phone
+	}
+
+	return delivery, nil
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"orderservice/pkg/orderservice/application/data"
+	"orderservice/pkg/orderservice/model"
+	"testing"
+)
+
+func TestValidateDelivery(t *testing.T) {
+	delivery, err := validateDelivery(data.Delivery{})
+	if err != nil || delivery.Type != model.DeliveryTypePickup {
+		t.Errorf("Empty delivery must default to pickup, have: %v, %v", delivery, err)
+	}
+
+	delivery, err = validateDelivery(data.Delivery{Type: "delivery", Address: "Lenina 1", Phone: "+7 (927) 123-45-67"})
+	if err != nil || delivery.Phone != "+79271234567" {
+		t.Errorf("Phone must be normalized, have: %v, %v", delivery, err)
+	}
+
+	invalid := []data.Delivery{
+		{Type: "delivery", Phone: "+79271234567"},
+		{Type: "delivery", Address: "Lenina 1"},
+		{Type: "delivery", Address: "Lenina 1", Phone: "call me"},
+		{Type: "pickup", Address: "Lenina 1"},
+		{Type: "teleport"},
+	}
+	for _, d := range invalid {
+		if _, err = validateDelivery(d); err == nil {
+			t.Errorf("Delivery %v must be invalid", d)
+		}
+	}
+}
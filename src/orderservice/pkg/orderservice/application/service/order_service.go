@@ -11,24 +11,34 @@ import (
 
 type AddOrderRequest struct {
 	MenuItems []data.MenuItem `json:"menuItems"`
+	Notes     string          `json:"notes"`
+	Delivery  data.Delivery   `json:"delivery"`
+	PromoCode string          `json:"promoCode"`
 }
 
 type UpdateOrderRequest struct {
 	MenuItems []data.MenuItem `json:"menuItems"`
+	Notes     string          `json:"notes"`
+	Delivery  data.Delivery   `json:"delivery"`
 }
 
 type orderService struct {
-	repo model.OrderRepository
+	repo     model.OrderRepository
+	pricing  *pricingEngine
+	payments PaymentService
+	events   OrderEventPublisher
 }
 
+// OrderService methods take actor who makes the change, it is kept in the order history
 type OrderService interface {
-	Add(r AddOrderRequest) error
-	Update(id string, r UpdateOrderRequest) error
-	Delete(id string) error
+	Add(r AddOrderRequest, actor string) (string, error)
+	Update(id string, r UpdateOrderRequest, actor string) error
+	Patch(id string, p OrderPatch, actor string) error
+	Delete(id string, actor string) error
 }
 
-func NewOrderService(repo model.OrderRepository) OrderService {
-	return &orderService{repo: repo}
+func NewOrderService(repo model.OrderRepository, promoRepo model.PromoCodeRepository, pricing PricingConfig, payments PaymentService, events OrderEventPublisher) OrderService {
+	return &orderService{repo: repo, pricing: newPricingEngine(promoRepo, pricing), payments: payments, events: events}
 }
 
 func validateOrderItems(reqItems []data.MenuItem) ([]model.MenuItem, error) {
@@ -47,56 +57,164 @@ func validateOrderItems(reqItems []data.MenuItem) ([]model.MenuItem, error) {
 		} else {
 			itemIds[item.ID] = true
 		}
+		if err = validateItemDetails(item); err != nil {
+			return nil, err
+		}
 
 		items[i].ID = itemId
 		items[i].Quantity = item.Quantity
+		items[i].Modifiers = item.Modifiers
+		items[i].Comment = item.Comment
 	}
 
 	return items, nil
 }
 
-func calculateCost(items []model.MenuItem) int {
-	return 42 * len(items)
-}
-
-func (os *orderService) Delete(id string) error {
+func (os *orderService) Delete(id string, actor string) error {
 	uid, err := uuid.Parse(id)
 	if err != nil {
 		log.Debug(err)
 		return fmt.Errorf("invalid uuid: %s", id)
 	}
 
-	err = os.repo.Delete(uid)
+	var promoCode string
+	affected, err := os.repo.Delete(uid, actor, func(o model.Order) error {
+		// the pending payment may still succeed, the order is kept until its result is known
+		if o.Status == model.OrderStatusPaymentPending {
+			return data.OrderConflictError
+		}
+
+		promoCode = o.Pricing.PromoCode
+		// deleting an order cancels it, paid money is returned before the order is gone
+		return os.payments.RefundOrder(uid)
+	})
+	if err == model.ErrConcurrentModification {
+		return data.OrderConflictError
+	}
+	if err == data.OrderConflictError || err == data.InternalError {
+		return err
+	}
 	if err != nil {
 		log.Error(err)
 		return data.InternalError
 	}
 
+	if affected == 0 {
+		return os.missingOrderError(uid)
+	}
+
+	// the cancelled order doesn't use its promo code any more
+	if promoCode != "" {
+		os.pricing.release(promoCode)
+	}
+
+	os.events.Publish(data.OrderEvent{Type: data.OrderDeletedEvent, OrderID: uid.String()})
+
 	return nil
 }
 
-func (os *orderService) Add(r AddOrderRequest) error {
+func (os *orderService) missingOrderError(id uuid.UUID) error {
+	deleted, err := os.repo.IsDeleted(id)
+	if err != nil {
+		log.Error(err)
+		return data.InternalError
+	}
+
+	if deleted {
+		return data.OrderDeletedError
+	}
+
+	return data.OrderNotFoundError
+}
+
+func (os *orderService) Add(r AddOrderRequest, actor string) (string, error) {
 	items, err := validateOrderItems(r.MenuItems)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	err = os.repo.Add(model.Order{
+	notes, err := validateNotes(r.Notes)
+	if err != nil {
+		return "", err
+	}
+
+	delivery, err := validateDelivery(r.Delivery)
+	if err != nil {
+		return "", err
+	}
+
+	o := model.Order{
 		ID:        uuid.New(),
 		MenuItems: items,
-		Cost:      calculateCost(items),
 		OrderedAt: time.Now(),
-	})
+		Notes:     notes,
+		Delivery:  delivery,
+		Status:    model.OrderStatusCreated,
+	}
+
+	release := func() {}
+	if r.PromoCode != "" {
+		release, err = os.pricing.redeem(r.PromoCode)
+		if err != nil {
+			return "", err
+		}
+		o.Pricing.PromoCode = r.PromoCode
+	}
+
+	if err = os.pricing.price(&o); err != nil {
+		release()
+		return "", err
+	}
 
+	err = os.repo.Add(o, actor)
 	if err != nil {
+		release()
 		log.Error(err)
-		return data.InternalError
+		return "", data.InternalError
 	}
 
-	return nil
+	publishOrderEvent(os.events, data.OrderCreatedEvent, o)
+
+	return o.ID.String(), nil
 }
 
-func (os *orderService) Update(id string, r UpdateOrderRequest) error {
+func (os *orderService) Update(id string, r UpdateOrderRequest, actor string) error {
+	return os.updateOrder(id, actor, func(o *model.Order) error {
+		items, err := validateOrderItems(r.MenuItems)
+		if err != nil {
+			return err
+		}
+
+		notes, err := validateNotes(r.Notes)
+		if err != nil {
+			return err
+		}
+
+		delivery, err := validateDelivery(r.Delivery)
+		if err != nil {
+			return err
+		}
+
+		o.MenuItems = items
+		o.Notes = notes
+		o.Delivery = delivery
+		return nil
+	})
+}
+
+func (os *orderService) Patch(id string, p OrderPatch, actor string) error {
+	return os.updateOrder(id, actor, func(o *model.Order) error {
+		reqItems, err := p.apply(toDataMenuItems(o.MenuItems))
+		if err != nil {
+			return err
+		}
+
+		o.MenuItems, err = validateOrderItems(reqItems)
+		return err
+	})
+}
+
+func (os *orderService) updateOrder(id string, actor string, fn func(*model.Order) error) error {
 	uid, err := uuid.Parse(id)
 	if err != nil {
 		log.Debug(err)
@@ -110,23 +228,45 @@ func (os *orderService) Update(id string, r UpdateOrderRequest) error {
 	}
 
 	if o == nil {
-		return fmt.Errorf("order %s not found", id)
+		return os.missingOrderError(uid)
 	}
 
-	items, err := validateOrderItems(r.MenuItems)
-	if err != nil {
-		return err
+	// the repository reports the same conflict when status changes before the update
+	if !o.IsEditable() {
+		return data.OrderConflictError
 	}
 
-	o.MenuItems = items
-	o.Cost = calculateCost(items)
+	if err = fn(o); err != nil {
+		return err
+	}
 
-	err = os.repo.Update(*o)
+	if err = os.pricing.price(o); err != nil {
+		return err
+	}
 
+	affected, err := os.repo.Update(*o, actor)
+	if err == model.ErrConcurrentModification || err == model.ErrOrderNotEditable {
+		return data.OrderConflictError
+	}
 	if err != nil {
 		log.Error(err)
 		return data.InternalError
 	}
 
+	if affected == 0 {
+		return os.missingOrderError(uid)
+	}
+
+	publishOrderEvent(os.events, data.OrderUpdatedEvent, *o)
+
 	return nil
 }
+
+func toDataMenuItems(items []model.MenuItem) []data.MenuItem {
+	result := make([]data.MenuItem, len(items))
+	for i, item := range items {
+		result[i] = data.MenuItem{ID: item.ID.String(), Quantity: item.Quantity, Modifiers: item.Modifiers, Comment: item.Comment}
+	}
+
+	return result
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"github.com/google/uuid"
+	"orderservice/pkg/orderservice/application/data"
+	"orderservice/pkg/orderservice/application/event"
+	"orderservice/pkg/orderservice/model"
+	"testing"
+)
+
+func TestUpdateOfLockedOrderConflicts(t *testing.T) {
+	orders := &mocOrderRepository{order: model.Order{ID: uuid.New(), Status: model.OrderStatusPaymentPending}}
+	os := NewOrderService(orders, mocPromoCodeRepository{}, PricingConfig{}, nil, event.NewBroker(10))
+
+	err := os.Patch(orders.order.ID.String(), MergePatchRequest{}, "user")
+	if err != data.OrderConflictError {
+		t.Errorf("Have: %v, want: %v", err, data.OrderConflictError)
+	}
+}
+
+func TestDeleteWaitsForPendingPayment(t *testing.T) {
+	orders := &mocOrderRepository{order: model.Order{ID: uuid.New(), Status: model.OrderStatusCreated}}
+	payments := &mocPaymentRepository{payments: map[uuid.UUID]model.Payment{}}
+	ps := NewPaymentService(orders, payments, PaymentConfig{Providers: []PaymentProvider{mocPaymentProvider{}}, DefaultProvider: "moc"}, event.NewBroker(10))
+	os := NewOrderService(orders, mocPromoCodeRepository{}, PricingConfig{}, ps, event.NewBroker(10))
+	id := orders.order.ID.String()
+
+	info, err := ps.Pay(id, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = os.Delete(id, "user"); err != data.OrderConflictError || orders.deleted {
+		t.Errorf("Order with pending payment is deleted. Have: %v, want: %v", err, data.OrderConflictError)
+	}
+
+	if err = ps.HandleWebhook("moc", []byte(info.ProviderRef+":succeeded"), ""); err != nil {
+		t.Fatal(err)
+	}
+	if orders.order.Status != model.OrderStatusPaid {
+		t.Errorf("Order status is wrong. Have: %s, want: %s", orders.order.Status, model.OrderStatusPaid)
+	}
+
+	if err = os.Delete(id, "user"); err != nil || !orders.deleted {
+		t.Fatalf("Paid order isn't deleted: %v", err)
+	}
+	if p := payments.payments[uuid.MustParse(info.ID)]; p.Status != model.PaymentStatusRefunded {
+		t.Errorf("Payment status is wrong. Have: %s, want: %s", p.Status, model.PaymentStatusRefunded)
+	}
+}
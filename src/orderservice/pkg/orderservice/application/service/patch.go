@@ -0,0 +1,326 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"orderservice/pkg/orderservice/application/data"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const menuItemsPath = "/menuItems"
+
+// OrderPatch changes menu items of an existing order
+type OrderPatch interface {
+	apply(items []data.MenuItem) ([]data.MenuItem, error)
+}
+
+// JSONPatchOperation is a single RFC 6902 operation
+type JSONPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// JSONPatchRequest is an RFC 6902 document, paths are restricted to /menuItems
+type JSONPatchRequest []JSONPatchOperation
+
+// MergePatchRequest is an RFC 7396 document where menu items are keyed by id:
+// a quantity sets or adds the item, null removes it
+type MergePatchRequest struct {
+	MenuItems map[string]*int `json:"menuItems"`
+}
+
+func (p MergePatchRequest) apply(items []data.MenuItem) ([]data.MenuItem, error) {
+	result := make([]data.MenuItem, 0, len(items)+len(p.MenuItems))
+	for _, item := range items {
+		quantity, found := p.MenuItems[item.ID]
+		if !found {
+			result = append(result, item)
+		} else if quantity != nil {
+			// only quantity is patched, modifiers and comment of the item are kept
+			item.Quantity = *quantity
+			result = append(result, item)
+		}
+	}
+
+	existing := map[string]bool{}
+	for _, item := range items {
+		existing[item.ID] = true
+	}
+
+	added := make([]string, 0)
+	for id, quantity := range p.MenuItems {
+		if !existing[id] && quantity != nil {
+			added = append(added, id)
+		}
+	}
+
+	sort.Strings(added)
+	for _, id := range added {
+		result = append(result, data.MenuItem{ID: id, Quantity: *p.MenuItems[id]})
+	}
+
+	return result, nil
+}
+
+func (p JSONPatchRequest) apply(items []data.MenuItem) ([]data.MenuItem, error) {
+	var doc interface{}
+	err := convertJSON(struct {
+		MenuItems []data.MenuItem `json:"menuItems"`
+	}{items}, &doc)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range p {
+		doc, err = applyOperation(doc, op)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var result struct {
+		MenuItems []data.MenuItem `json:"menuItems"`
+	}
+	if err = convertJSON(doc, &result); err != nil {
+		return nil, fmt.Errorf("patched order is invalid: %s", err)
+	}
+
+	return result.MenuItems, nil
+}
+
+func convertJSON(from interface{}, to interface{}) error {
+	b, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, to)
+}
+
+func applyOperation(doc interface{}, op JSONPatchOperation) (interface{}, error) {
+	if !isMenuItemsPath(op.Path) {
+		return nil, fmt.Errorf("path %s is not supported, only %s can be patched", op.Path, menuItemsPath)
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		value, err := operationValue(op)
+		if err != nil {
+			return nil, err
+		}
+
+		if op.Op == "add" {
+			return addValue(doc, op.Path, value)
+		}
+		if op.Op == "replace" {
+			if _, err = removeValue(doc, op.Path); err != nil {
+				return nil, err
+			}
+			return addValue(doc, op.Path, value)
+		}
+		return doc, testValue(doc, op.Path, value)
+	case "remove":
+		return removeValue(doc, op.Path)
+	case "move", "copy":
+		if !isMenuItemsPath(op.From) {
+			return nil, fmt.Errorf("from %s is not supported, only %s can be patched", op.From, menuItemsPath)
+		}
+
+		value, err := getValue(doc, op.From)
+		if err != nil {
+			return nil, err
+		}
+
+		if op.Op == "move" {
+			if doc, err = removeValue(doc, op.From); err != nil {
+				return nil, err
+			}
+		} else {
+			var duplicate interface{}
+			if err = convertJSON(value, &duplicate); err != nil {
+				return nil, err
+			}
+			value = duplicate
+		}
+
+		return addValue(doc, op.Path, value)
+	default:
+		return nil, fmt.Errorf("unsupported patch operation: %s", op.Op)
+	}
+}
+
+func isMenuItemsPath(path string) bool {
+	return path == menuItemsPath || strings.HasPrefix(path, menuItemsPath+"/")
+}
+
+func operationValue(op JSONPatchOperation) (interface{}, error) {
+	if len(op.Value) == 0 {
+		return nil, fmt.Errorf("operation %s %s has no value", op.Op, op.Path)
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(op.Value, &value); err != nil {
+		return nil, fmt.Errorf("operation %s %s has invalid value", op.Op, op.Path)
+	}
+
+	return value, nil
+}
+
+func splitPointer(path string) []string {
+	tokens := strings.Split(path, "/")[1:]
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+
+	return tokens
+}
+
+// resolveParent returns container holding the last token of path
+func resolveParent(doc interface{}, path string) (interface{}, string, error) {
+	tokens := splitPointer(path)
+	current := doc
+	for _, token := range tokens[:len(tokens)-1] {
+		child, err := childValue(current, token, path)
+		if err != nil {
+			return nil, "", err
+		}
+		current = child
+	}
+
+	return current, tokens[len(tokens)-1], nil
+}
+
+func childValue(container interface{}, token string, path string) (interface{}, error) {
+	switch c := container.(type) {
+	case map[string]interface{}:
+		child, found := c[token]
+		if !found {
+			return nil, fmt.Errorf("path %s not found", path)
+		}
+		return child, nil
+	case []interface{}:
+		i, err := arrayIndex(token, len(c)-1)
+		if err != nil {
+			return nil, fmt.Errorf("path %s not found", path)
+		}
+		return c[i], nil
+	default:
+		return nil, fmt.Errorf("path %s not found", path)
+	}
+}
+
+func arrayIndex(token string, max int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i > max || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid index: %s", token)
+	}
+
+	return i, nil
+}
+
+func getValue(doc interface{}, path string) (interface{}, error) {
+	parent, token, err := resolveParent(doc, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return childValue(parent, token, path)
+}
+
+// setInParent replaces container at parentPath, slices must be reassigned after growing or shrinking
+func setInParent(doc interface{}, path string, container interface{}) (interface{}, error) {
+	tokens := splitPointer(path)
+	if len(tokens) == 1 {
+		return container, nil
+	}
+
+	parentPath := "/" + strings.Join(tokens[:len(tokens)-1], "/")
+	parent, token, err := resolveParent(doc, parentPath)
+	if err != nil {
+		return nil, err
+	}
+
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		p[token] = container
+	case []interface{}:
+		i, err := arrayIndex(token, len(p)-1)
+		if err != nil {
+			return nil, err
+		}
+		p[i] = container
+	}
+
+	return doc, nil
+}
+
+func addValue(doc interface{}, path string, value interface{}) (interface{}, error) {
+	parent, token, err := resolveParent(doc, path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		p[token] = value
+		return doc, nil
+	case []interface{}:
+		i := len(p)
+		if token != "-" {
+			if i, err = arrayIndex(token, len(p)); err != nil {
+				return nil, fmt.Errorf("path %s not found", path)
+			}
+		}
+
+		p = append(p, nil)
+		copy(p[i+1:], p[i:])
+		p[i] = value
+		return setInParent(doc, path, p)
+	default:
+		return nil, fmt.Errorf("path %s not found", path)
+	}
+}
+
+func removeValue(doc interface{}, path string) (interface{}, error) {
+	parent, token, err := resolveParent(doc, path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		if _, found := p[token]; !found {
+			return nil, fmt.Errorf("path %s not found", path)
+		}
+		delete(p, token)
+		return doc, nil
+	case []interface{}:
+		i, err := arrayIndex(token, len(p)-1)
+		if err != nil {
+			return nil, fmt.Errorf("path %s not found", path)
+		}
+
+		p = append(p[:i], p[i+1:]...)
+		return setInParent(doc, path, p)
+	default:
+		return nil, fmt.Errorf("path %s not found", path)
+	}
+}
+
+func testValue(doc interface{}, path string, expected interface{}) error {
+	actual, err := getValue(doc, path)
+	if err != nil {
+		return err
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		return fmt.Errorf("test failed for path %s", path)
+	}
+
+	return nil
+}
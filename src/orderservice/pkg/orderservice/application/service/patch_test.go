@@ -0,0 +1,72 @@
+package service
+
+import (
+	"encoding/json"
+	"orderservice/pkg/orderservice/application/data"
+	"reflect"
+	"testing"
+)
+
+const (
+	firstItemID  = "3fa85f64-5717-4562-b3fc-2c963f66afa6"
+	secondItemID = "9b2e4c1a-0d7f-4e55-8a3b-6f1c2d3e4f50"
+)
+
+func TestJSONPatchApply(t *testing.T) {
+	var patch JSONPatchRequest
+	err := json.Unmarshal([]byte(`[
+		{"op": "test", "path": "/menuItems/0/quantity", "value": 1},
+		{"op": "replace", "path": "/menuItems/0/quantity", "value": 3},
+		{"op": "add", "path": "/menuItems/-", "value": {"id": "`+secondItemID+`", "quantity": 2}}
+	]`), &patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := patch.apply([]data.MenuItem{{ID: firstItemID, Quantity: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []data.MenuItem{{ID: firstItemID, Quantity: 3}, {ID: secondItemID, Quantity: 2}}
+	if !reflect.DeepEqual(items, expected) {
+		t.Errorf("Patched items are wrong. Have: %v, want: %v", items, expected)
+	}
+}
+
+func TestJSONPatchRejectsForeignPath(t *testing.T) {
+	patch := JSONPatchRequest{{Op: "replace", Path: "/cost", Value: json.RawMessage("1")}}
+	if _, err := patch.apply(nil); err == nil {
+		t.Error("Patch outside of menuItems must fail")
+	}
+}
+
+func TestMergePatchApply(t *testing.T) {
+	quantity := 5
+	patch := MergePatchRequest{MenuItems: map[string]*int{firstItemID: nil, secondItemID: &quantity}}
+
+	items, err := patch.apply([]data.MenuItem{{ID: firstItemID, Quantity: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []data.MenuItem{{ID: secondItemID, Quantity: 5}}
+	if !reflect.DeepEqual(items, expected) {
+		t.Errorf("Patched items are wrong. Have: %v, want: %v", items, expected)
+	}
+}
+
+func TestMergePatchKeepsItemDetails(t *testing.T) {
+	quantity := 2
+	patch := MergePatchRequest{MenuItems: map[string]*int{firstItemID: &quantity}}
+
+	items, err := patch.apply([]data.MenuItem{{ID: firstItemID, Quantity: 1, Modifiers: []string{"no-onion"}, Comment: "well done"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []data.MenuItem{{ID: firstItemID, Quantity: 2, Modifiers: []string{"no-onion"}, Comment: "well done"}}
+	if !reflect.DeepEqual(items, expected) {
+		t.Errorf("Patched items are wrong. Have: %v, want: %v", items, expected)
+	}
+}
@@ -0,0 +1,308 @@
+package service
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"orderservice/pkg/orderservice/application/data"
+	"orderservice/pkg/orderservice/model"
+	"time"
+)
+
+// PaymentIntent is a provider side state of a payment
+type PaymentIntent struct {
+	ProviderRef string
+	Status      model.PaymentStatus
+}
+
+// PaymentEvent is a payment result reported by provider webhook
+type PaymentEvent struct {
+	ProviderRef string
+	Status      model.PaymentStatus
+}
+
+type PaymentProvider interface {
+	Name() string
+	CreateIntent(paymentID uuid.UUID, amount model.Money) (*PaymentIntent, error)
+	Refund(providerRef string, amount model.Money) error
+	// ParseWebhook verifies payload signature and returns reported payment result
+	ParseWebhook(payload []byte, signature string) (*PaymentEvent, error)
+}
+
+type PaymentConfig struct {
+	Providers       []PaymentProvider
+	DefaultProvider string
+}
+
+type paymentService struct {
+	orderRepo       model.OrderRepository
+	paymentRepo     model.PaymentRepository
+	providers       map[string]PaymentProvider
+	defaultProvider string
+	events          OrderEventPublisher
+}
+
+type PaymentService interface {
+	Pay(orderID string, actor string) (*data.PaymentInfo, error)
+	HandleWebhook(provider string, payload []byte, signature string) error
+	// RefundOrder returns paid money of the order being deleted, the order itself isn't changed
+	RefundOrder(orderID uuid.UUID) error
+}
+
+func NewPaymentService(orderRepo model.OrderRepository, paymentRepo model.PaymentRepository, config PaymentConfig, events OrderEventPublisher) PaymentService {
+	providers := map[string]PaymentProvider{}
+	for _, provider := range config.Providers {
+		providers[provider.Name()] = provider
+	}
+
+	return &paymentService{
+		orderRepo:       orderRepo,
+		paymentRepo:     paymentRepo,
+		providers:       providers,
+		defaultProvider: config.DefaultProvider,
+		events:          events,
+	}
+}
+
+const paymentActorPrefix = "payment:"
+
+var orderStatusByPaymentStatus = map[model.PaymentStatus]model.OrderStatus{
+	model.PaymentStatusSucceeded: model.OrderStatusPaid,
+	model.PaymentStatusFailed:    model.OrderStatusPaymentFailed,
+	model.PaymentStatusRefunded:  model.OrderStatusRefunded,
+}
+
+func newPaymentInfo(p model.Payment) *data.PaymentInfo {
+	return &data.PaymentInfo{
+		ID:          p.ID.String(),
+		OrderID:     p.OrderID.String(),
+		Provider:    p.Provider,
+		ProviderRef: p.ProviderRef,
+		Amount:      data.NewMoney(p.Amount),
+		Status:      string(p.Status),
+		CreatedAt:   p.CreatedAt,
+	}
+}
+
+func (ps *paymentService) Pay(orderID string, actor string) (*data.PaymentInfo, error) {
+	uid, err := uuid.Parse(orderID)
+	if err != nil {
+		log.Debug(err)
+		return nil, fmt.Errorf("invalid uuid: %s", orderID)
+	}
+
+	provider, found := ps.providers[ps.defaultProvider]
+	if !found {
+		log.Errorf("payment provider %s is not configured", ps.defaultProvider)
+		return nil, data.InternalError
+	}
+
+	o, err := ps.orderRepo.Get(uid)
+	if err != nil {
+		log.Error(err)
+		return nil, data.InternalError
+	}
+
+	if o == nil {
+		return nil, data.OrderNotFoundError
+	}
+
+	if !o.IsEditable() {
+		return nil, fmt.Errorf("order %s is %s and can't be paid", orderID, o.Status)
+	}
+
+	payments, err := ps.paymentRepo.FindByOrder(uid)
+	if err != nil {
+		log.Error(err)
+		return nil, data.InternalError
+	}
+
+	for _, p := range payments {
+		if p.IsActive() {
+			return nil, fmt.Errorf("order %s already has %s payment", orderID, p.Status)
+		}
+	}
+
+	// the pending status locks the order, a concurrent payment fails to take it
+	affected, err := ps.orderRepo.UpdateStatus(uid, model.OrderStatusPaymentPending, actor)
+	if err == model.ErrInvalidStatusTransition {
+		return nil, data.OrderConflictError
+	}
+	if err != nil {
+		log.Error(err)
+		return nil, data.InternalError
+	}
+
+	if affected == 0 {
+		return nil, data.OrderNotFoundError
+	}
+
+	payment := model.Payment{
+		ID:        uuid.New(),
+		OrderID:   uid,
+		Provider:  provider.Name(),
+		Amount:    o.Cost,
+		Status:    model.PaymentStatusPending,
+		CreatedAt: time.Now(),
+	}
+
+	err = ps.paymentRepo.Add(payment)
+	if err != nil {
+		log.Error(err)
+		// unlock the order, the payment was never started
+		if _, err = ps.orderRepo.UpdateStatus(uid, model.OrderStatusPaymentFailed, actor); err != nil {
+			log.Error(err)
+		}
+		return nil, data.InternalError
+	}
+
+	intent, err := provider.CreateIntent(payment.ID, payment.Amount)
+	if err != nil {
+		log.Error(err)
+		intent = &PaymentIntent{Status: model.PaymentStatusFailed}
+	}
+
+	payment.ProviderRef = intent.ProviderRef
+	if err = ps.applyStatus(&payment, intent.Status, actor); err != nil {
+		return nil, err
+	}
+
+	return newPaymentInfo(payment), nil
+}
+
+func (ps *paymentService) HandleWebhook(providerName string, payload []byte, signature string) error {
+	provider, found := ps.providers[providerName]
+	if !found {
+		return data.PaymentProviderNotFoundError
+	}
+
+	event, err := provider.ParseWebhook(payload, signature)
+	if err != nil {
+		log.Debug(err)
+		return data.InvalidSignatureError
+	}
+
+	payment, err := ps.paymentRepo.GetByProviderRef(providerName, event.ProviderRef)
+	if err != nil {
+		log.Error(err)
+		return data.InternalError
+	}
+
+	if payment == nil {
+		return fmt.Errorf("payment %s not found", event.ProviderRef)
+	}
+
+	if payment.Status == event.Status {
+		return nil // duplicate delivery
+	}
+
+	// late and replayed results must not move the payment back, they are acknowledged to stop redelivery
+	if !payment.CanTransitTo(event.Status) {
+		log.WithFields(log.Fields{"payment": payment.ID, "status": payment.Status, "reported": event.Status}).
+			Info("payment webhook ignored")
+		return nil
+	}
+
+	// status changes reported by provider are recorded on behalf of the provider
+	return ps.applyStatus(payment, event.Status, paymentActorPrefix+providerName)
+}
+
+func (ps *paymentService) RefundOrder(orderID uuid.UUID) error {
+	payments, err := ps.paymentRepo.FindByOrder(orderID)
+	if err != nil {
+		log.Error(err)
+		return data.InternalError
+	}
+
+	for _, payment := range payments {
+		if payment.Status != model.PaymentStatusSucceeded {
+			continue
+		}
+
+		if err = ps.refund(&payment); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// refund returns money of the succeeded payment and stores it as refunded
+func (ps *paymentService) refund(payment *model.Payment) error {
+	provider, found := ps.providers[payment.Provider]
+	if !found {
+		log.Errorf("payment provider %s is not configured", payment.Provider)
+		return data.InternalError
+	}
+
+	err := provider.Refund(payment.ProviderRef, payment.Amount)
+	if err != nil {
+		log.Error(err)
+		return data.InternalError
+	}
+
+	_, err = ps.updatePayment(payment, model.PaymentStatusRefunded)
+	return err
+}
+
+// updatePayment stores payment status, it reports false if the stored status has changed concurrently
+func (ps *paymentService) updatePayment(payment *model.Payment, status model.PaymentStatus) (bool, error) {
+	previous := payment.Status
+	payment.Status = status
+	affected, err := ps.paymentRepo.Update(*payment, previous)
+	if err != nil {
+		log.Error(err)
+		return false, data.InternalError
+	}
+
+	if affected == 0 {
+		log.WithFields(log.Fields{"payment": payment.ID, "status": status}).Info("payment is changed concurrently, status ignored")
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// applyStatus stores payment status and moves the order to the matching status,
+// statuses which don't follow the stored ones are ignored
+func (ps *paymentService) applyStatus(payment *model.Payment, status model.PaymentStatus, actor string) error {
+	updated, err := ps.updatePayment(payment, status)
+	if err != nil || !updated {
+		return err
+	}
+
+	orderStatus, found := orderStatusByPaymentStatus[status]
+	if !found {
+		return nil
+	}
+
+	affected, err := ps.orderRepo.UpdateStatus(payment.OrderID, orderStatus, actor)
+	if err == model.ErrInvalidStatusTransition {
+		log.WithFields(log.Fields{"order": payment.OrderID, "status": orderStatus}).Info("order status transition ignored")
+		return nil
+	}
+	if err != nil {
+		log.Error(err)
+		return data.InternalError
+	}
+
+	if affected == 0 {
+		// the order is already deleted, money paid for it is returned
+		if status == model.PaymentStatusSucceeded {
+			return ps.refund(payment)
+		}
+		return nil
+	}
+
+	o, err := ps.orderRepo.Get(payment.OrderID)
+	if err != nil {
+		log.Error(err)
+		return nil // status is already saved, only the notification is lost
+	}
+
+	if o != nil {
+		publishOrderEvent(ps.events, data.OrderUpdatedEvent, *o)
+	}
+
+	return nil
+}
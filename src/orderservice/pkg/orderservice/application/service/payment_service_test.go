@@ -0,0 +1,164 @@
+package service
+
+import (
+	"github.com/google/uuid"
+	"orderservice/pkg/orderservice/application/event"
+	"orderservice/pkg/orderservice/model"
+	"testing"
+)
+
+type mocOrderRepository struct {
+	model.OrderRepository
+	order   model.Order
+	deleted bool
+}
+
+func (r *mocOrderRepository) Get(uuid.UUID) (*model.Order, error) {
+	if r.deleted {
+		return nil, nil
+	}
+
+	o := r.order
+	return &o, nil
+}
+
+func (r *mocOrderRepository) Delete(_ uuid.UUID, _ string, beforeDelete func(model.Order) error) (int64, error) {
+	if r.deleted {
+		return 0, nil
+	}
+	if err := beforeDelete(r.order); err != nil {
+		return 0, err
+	}
+
+	r.deleted = true
+	return 1, nil
+}
+
+func (r *mocOrderRepository) IsDeleted(uuid.UUID) (bool, error) {
+	return r.deleted, nil
+}
+
+func (r *mocOrderRepository) UpdateStatus(_ uuid.UUID, status model.OrderStatus, _ string) (int64, error) {
+	if r.deleted {
+		return 0, nil
+	}
+	if !r.order.Status.CanTransitTo(status) {
+		return 0, model.ErrInvalidStatusTransition
+	}
+
+	r.order.Status = status
+	return 1, nil
+}
+
+type mocPaymentRepository struct {
+	model.PaymentRepository
+	payments map[uuid.UUID]model.Payment
+}
+
+func (r *mocPaymentRepository) Add(p model.Payment) error {
+	r.payments[p.ID] = p
+	return nil
+}
+
+func (r *mocPaymentRepository) Update(p model.Payment, previous model.PaymentStatus) (int64, error) {
+	if r.payments[p.ID].Status != previous {
+		return 0, nil
+	}
+
+	r.payments[p.ID] = p
+	return 1, nil
+}
+
+func (r *mocPaymentRepository) GetByProviderRef(_ string, ref string) (*model.Payment, error) {
+	for _, p := range r.payments {
+		if p.ProviderRef == ref {
+			return &p, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (r *mocPaymentRepository) FindByOrder(uuid.UUID) ([]model.Payment, error) {
+	result := make([]model.Payment, 0, len(r.payments))
+	for _, p := range r.payments {
+		result = append(result, p)
+	}
+
+	return result, nil
+}
+
+// mocPaymentProvider leaves payments pending, webhook payload is "<ref>:<status>"
+type mocPaymentProvider struct{}
+
+func (mocPaymentProvider) Name() string {
+	return "moc"
+}
+
+func (mocPaymentProvider) CreateIntent(paymentID uuid.UUID, _ model.Money) (*PaymentIntent, error) {
+	return &PaymentIntent{ProviderRef: paymentID.String(), Status: model.PaymentStatusPending}, nil
+}
+
+func (mocPaymentProvider) Refund(string, model.Money) error {
+	return nil
+}
+
+func (mocPaymentProvider) ParseWebhook(payload []byte, _ string) (*PaymentEvent, error) {
+	ref, status := string(payload[:36]), string(payload[37:])
+	return &PaymentEvent{ProviderRef: ref, Status: model.PaymentStatus(status)}, nil
+}
+
+func TestPaymentStatusIsMonotonic(t *testing.T) {
+	orders := &mocOrderRepository{order: model.Order{ID: uuid.New(), Status: model.OrderStatusCreated}}
+	payments := &mocPaymentRepository{payments: map[uuid.UUID]model.Payment{}}
+	ps := NewPaymentService(orders, payments, PaymentConfig{Providers: []PaymentProvider{mocPaymentProvider{}}, DefaultProvider: "moc"}, event.NewBroker(10))
+
+	info, err := ps.Pay(orders.order.ID.String(), "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if orders.order.Status != model.OrderStatusPaymentPending || orders.order.IsEditable() {
+		t.Errorf("Order with pending payment must be locked, status: %s", orders.order.Status)
+	}
+
+	if _, err = ps.Pay(orders.order.ID.String(), "user"); err == nil {
+		t.Error("Second payment is started while the first one is pending")
+	}
+
+	if err = ps.HandleWebhook("moc", []byte(info.ProviderRef+":succeeded"), ""); err != nil {
+		t.Fatal(err)
+	}
+	if err = ps.HandleWebhook("moc", []byte(info.ProviderRef+":failed"), ""); err != nil {
+		t.Errorf("Late webhook must be acknowledged: %s", err)
+	}
+	if orders.order.Status != model.OrderStatusPaid {
+		t.Errorf("Order status is wrong. Have: %s, want: %s", orders.order.Status, model.OrderStatusPaid)
+	}
+
+	if _, err = ps.Pay(orders.order.ID.String(), "user"); err == nil {
+		t.Error("Paid order is paid again")
+	}
+}
+
+func TestSuccessOfDeletedOrderIsRefunded(t *testing.T) {
+	orders := &mocOrderRepository{order: model.Order{ID: uuid.New(), Status: model.OrderStatusCreated}}
+	payments := &mocPaymentRepository{payments: map[uuid.UUID]model.Payment{}}
+	ps := NewPaymentService(orders, payments, PaymentConfig{Providers: []PaymentProvider{mocPaymentProvider{}}, DefaultProvider: "moc"}, event.NewBroker(10))
+
+	info, err := ps.Pay(orders.order.ID.String(), "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// deleted before the pending check existed
+	orders.deleted = true
+	if err = ps.HandleWebhook("moc", []byte(info.ProviderRef+":succeeded"), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range payments.payments {
+		if p.Status != model.PaymentStatusRefunded {
+			t.Errorf("Payment status is wrong. Have: %s, want: %s", p.Status, model.PaymentStatusRefunded)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"orderservice/pkg/orderservice/application/data"
+	"orderservice/pkg/orderservice/model"
+	"time"
+)
+
+// menuItemPrice is in major units of the order currency
+const menuItemPrice = 42
+
+// TaxRates are in basis points, 1000 means 10%
+type TaxRates struct {
+	Default        int
+	ByDeliveryType map[string]int
+}
+
+func (t TaxRates) rate(deliveryType model.DeliveryType) int {
+	if rate, found := t.ByDeliveryType[string(deliveryType)]; found {
+		return rate
+	}
+
+	return t.Default
+}
+
+type PricingConfig struct {
+	Currency string
+	TaxRates TaxRates
+}
+
+type pricingEngine struct {
+	promoRepo model.PromoCodeRepository
+	config    PricingConfig
+	now       func() time.Time
+}
+
+func newPricingEngine(promoRepo model.PromoCodeRepository, config PricingConfig) *pricingEngine {
+	return &pricingEngine{promoRepo: promoRepo, config: config, now: time.Now}
+}
+
+func itemPrice(currency string) model.Money {
+	amount := int64(menuItemPrice)
+	for i := 0; i < model.CurrencyExponent(currency); i++ {
+		amount *= 10
+	}
+
+	return model.NewMoney(amount, currency)
+}
+
+func (e *pricingEngine) calculateCost(items []model.MenuItem, currency string) (model.Money, error) {
+	cost := model.NewMoney(0, currency)
+	for _, item := range items {
+		itemCost, err := itemPrice(currency).Mul(int64(item.Quantity))
+		if err != nil {
+			return model.Money{}, err
+		}
+
+		cost, err = cost.Add(itemCost)
+		if err != nil {
+			return model.Money{}, err
+		}
+	}
+
+	return cost, nil
+}
+
+func (e *pricingEngine) calculateDiscount(promo *model.PromoTerms, items []model.MenuItem, subtotal model.Money) (model.Money, error) {
+	discount := model.NewMoney(0, subtotal.Currency)
+	if promo == nil {
+		return discount, nil
+	}
+
+	var err error
+	switch promo.Type {
+	case model.PromoCodeTypePercentage:
+		discount, err = subtotal.MulRate(int64(promo.Value), 100)
+	case model.PromoCodeTypeFixed:
+		// amounts aren't converted between currencies, value of other currency means something else
+		if promo.Currency != subtotal.Currency {
+			return model.Money{}, fmt.Errorf("promo code is in %s, order is in %s", promo.Currency, subtotal.Currency)
+		}
+		discount = model.NewMoney(int64(promo.Value), promo.Currency)
+	case model.PromoCodeTypeBuyXGetY:
+		freeItems := 0
+		for _, item := range items {
+			if promo.MenuItemID != nil && *promo.MenuItemID != item.ID {
+				continue
+			}
+
+			freeItems += item.Quantity / (promo.BuyQuantity + promo.FreeQuantity) * promo.FreeQuantity
+		}
+		discount, err = itemPrice(subtotal.Currency).Mul(int64(freeItems))
+	}
+	if err != nil {
+		return model.Money{}, err
+	}
+
+	return discount.Min(subtotal), nil
+}
+
+// price recalculates order pricing with the promo terms kept on the order, terms of already
+// redeemed code are read once for new orders and orders priced before the terms were kept
+func (e *pricingEngine) price(o *model.Order) error {
+	promo := o.Pricing.Promo
+	if o.Pricing.PromoCode != "" && promo == nil {
+		code, err := e.promoRepo.Get(o.Pricing.PromoCode)
+		if err != nil {
+			log.Error(err)
+			return data.InternalError
+		}
+		if code == nil {
+			return fmt.Errorf("promo code %s of the order is deleted, the order can't be repriced", o.Pricing.PromoCode)
+		}
+		terms := code.Terms()
+		promo = &terms
+	}
+
+	// orders keep the currency they are created in, config currency is used for new ones
+	currency := o.Cost.Currency
+	if currency == "" {
+		currency = e.config.Currency
+	}
+
+	pricing, err := e.calculatePricing(promo, o.MenuItems, o.Delivery.Type, currency)
+	if err != nil {
+		return fmt.Errorf("can't calculate order cost: %s", err)
+	}
+
+	total, err := pricing.Total()
+	if err != nil {
+		return fmt.Errorf("can't calculate order cost: %s", err)
+	}
+
+	pricing.PromoCode = o.Pricing.PromoCode
+	pricing.Promo = promo
+	o.Pricing = pricing
+	o.Cost = total
+
+	return nil
+}
+
+func (e *pricingEngine) calculatePricing(promo *model.PromoTerms, items []model.MenuItem, deliveryType model.DeliveryType, currency string) (model.Pricing, error) {
+	subtotal, err := e.calculateCost(items, currency)
+	if err != nil {
+		return model.Pricing{}, err
+	}
+
+	discount, err := e.calculateDiscount(promo, items, subtotal)
+	if err != nil {
+		return model.Pricing{}, err
+	}
+
+	taxable, err := subtotal.Sub(discount)
+	if err != nil {
+		return model.Pricing{}, err
+	}
+
+	tax, err := taxable.MulRate(int64(e.config.TaxRates.rate(deliveryType)), 10000)
+	if err != nil {
+		return model.Pricing{}, err
+	}
+
+	return model.Pricing{Subtotal: subtotal, Discount: discount, Tax: tax}, nil
+}
+
+// redeem checks the code and reserves one usage, release must be called if order is not saved
+func (e *pricingEngine) redeem(code string) (release func(), err error) {
+	promo, err := e.promoRepo.Get(code)
+	if err != nil {
+		log.Error(err)
+		return nil, data.InternalError
+	}
+
+	if promo == nil || !promo.IsActive(e.now()) {
+		return nil, fmt.Errorf("promo code %s is not valid", code)
+	}
+
+	affected, err := e.promoRepo.Redeem(code)
+	if err != nil {
+		log.Error(err)
+		return nil, data.InternalError
+	}
+
+	if affected == 0 {
+		return nil, fmt.Errorf("promo code %s usage limit is reached", code)
+	}
+
+	return func() {
+		e.release(code)
+	}, nil
+}
+
+// release returns a usage reserved by redeem
+func (e *pricingEngine) release(code string) {
+	if err := e.promoRepo.Release(code); err != nil {
+		log.Error(err)
+	}
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"github.com/google/uuid"
+	"orderservice/pkg/orderservice/model"
+	"testing"
+)
+
+func TestCalculateDiscount(t *testing.T) {
+	engine := newPricingEngine(nil, PricingConfig{Currency: "EUR"})
+	itemId := uuid.New()
+	items := []model.MenuItem{{ID: itemId, Quantity: 5}, {ID: uuid.New(), Quantity: 1}}
+	subtotal, err := engine.calculateCost(items, "EUR")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		promo    *model.PromoTerms
+		discount int64
+	}{
+		{nil, 0},
+		{&model.PromoTerms{Type: model.PromoCodeTypePercentage, Value: 10}, 2520},
+		{&model.PromoTerms{Type: model.PromoCodeTypeFixed, Value: 100, Currency: "EUR"}, 100},
+		{&model.PromoTerms{Type: model.PromoCodeTypeFixed, Value: 100000, Currency: "EUR"}, subtotal.Amount},
+		{&model.PromoTerms{Type: model.PromoCodeTypeBuyXGetY, BuyQuantity: 2, FreeQuantity: 1, MenuItemID: &itemId}, 4200},
+	}
+
+	for _, c := range cases {
+		discount, err := engine.calculateDiscount(c.promo, items, subtotal)
+		if err != nil || discount != model.NewMoney(c.discount, "EUR") {
+			t.Errorf("Discount is wrong for %v. Have: %v, want: %d", c.promo, discount, c.discount)
+		}
+	}
+
+	_, err = engine.calculateDiscount(&model.PromoTerms{Type: model.PromoCodeTypeFixed, Value: 100, Currency: "JPY"}, items, subtotal)
+	if err == nil {
+		t.Errorf("Fixed discount in other currency is applied")
+	}
+}
+
+func TestPriceAppliesTax(t *testing.T) {
+	engine := newPricingEngine(nil, PricingConfig{
+		Currency: "EUR",
+		TaxRates: TaxRates{Default: 1000, ByDeliveryType: map[string]int{"delivery": 2000}},
+	})
+	o := model.Order{
+		MenuItems: []model.MenuItem{{ID: uuid.New(), Quantity: 2}},
+		Delivery:  model.Delivery{Type: model.DeliveryTypeDelivery},
+	}
+
+	if err := engine.price(&o); err != nil {
+		t.Fatal(err)
+	}
+
+	if o.Pricing.Subtotal.Amount != 8400 || o.Pricing.Tax.Amount != 1680 || o.Cost != model.NewMoney(10080, "EUR") {
+		t.Errorf("Pricing is wrong: %v, cost: %v", o.Pricing, o.Cost)
+	}
+}
+
+func TestPriceKeepsOrderCurrency(t *testing.T) {
+	engine := newPricingEngine(nil, PricingConfig{Currency: "USD"})
+	o := model.Order{
+		MenuItems: []model.MenuItem{{ID: uuid.New(), Quantity: 1}},
+		Cost:      model.NewMoney(100, "JPY"),
+	}
+
+	if err := engine.price(&o); err != nil {
+		t.Fatal(err)
+	}
+
+	if o.Cost != model.NewMoney(42, "JPY") {
+		t.Errorf("Cost is wrong. Have: %v, want: 42 JPY", o.Cost)
+	}
+}
+
+type mocPromoCodeRepository struct {
+	model.PromoCodeRepository
+	promo *model.PromoCode
+}
+
+func (m mocPromoCodeRepository) Get(string) (*model.PromoCode, error) {
+	return m.promo, nil
+}
+
+func TestPriceKeepsTermsOfDeletedPromoCode(t *testing.T) {
+	engine := newPricingEngine(mocPromoCodeRepository{}, PricingConfig{Currency: "EUR"})
+	o := model.Order{
+		MenuItems: []model.MenuItem{{ID: uuid.New(), Quantity: 2}},
+		Pricing:   model.Pricing{PromoCode: "SPRING", Promo: &model.PromoTerms{Type: model.PromoCodeTypePercentage, Value: 10}},
+	}
+
+	if err := engine.price(&o); err != nil {
+		t.Fatal(err)
+	}
+
+	if o.Pricing.Discount.Amount != 840 || o.Pricing.Promo == nil {
+		t.Errorf("Pricing is wrong: %v", o.Pricing)
+	}
+}
+
+func TestPriceRejectsDeletedPromoCodeWithoutTerms(t *testing.T) {
+	engine := newPricingEngine(mocPromoCodeRepository{}, PricingConfig{Currency: "EUR"})
+	o := model.Order{
+		MenuItems: []model.MenuItem{{ID: uuid.New(), Quantity: 2}},
+		Pricing:   model.Pricing{PromoCode: "SPRING"},
+	}
+
+	if err := engine.price(&o); err == nil {
+		t.Errorf("Order with deleted promo code is repriced without discount: %v", o.Pricing)
+	}
+}
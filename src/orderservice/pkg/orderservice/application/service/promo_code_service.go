@@ -0,0 +1,166 @@
+package service
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"orderservice/pkg/orderservice/application/data"
+	"orderservice/pkg/orderservice/model"
+	"regexp"
+	"time"
+)
+
+var promoCodeRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+type PromoCodeRequest struct {
+	Type         string     `json:"type"`
+	Value        int        `json:"value"`
+	Currency     string     `json:"currency"`
+	BuyQuantity  int        `json:"buyQuantity"`
+	FreeQuantity int        `json:"freeQuantity"`
+	MenuItemID   string     `json:"menuItemId"`
+	ValidFrom    *time.Time `json:"validFrom"`
+	ValidTo      *time.Time `json:"validTo"`
+	UsageLimit   int        `json:"usageLimit"`
+}
+
+type AddPromoCodeRequest struct {
+	Code string `json:"code"`
+	PromoCodeRequest
+}
+
+type promoCodeService struct {
+	repo model.PromoCodeRepository
+}
+
+type PromoCodeService interface {
+	Add(r AddPromoCodeRequest) error
+	Update(code string, r PromoCodeRequest) error
+	Delete(code string) error
+}
+
+func NewPromoCodeService(repo model.PromoCodeRepository) PromoCodeService {
+	return &promoCodeService{repo: repo}
+}
+
+func validatePromoCode(code string, r PromoCodeRequest) (*model.PromoCode, error) {
+	if !promoCodeRegexp.MatchString(code) {
+		return nil, fmt.Errorf("invalid promo code: %s", code)
+	}
+
+	p := model.PromoCode{
+		Code:         code,
+		Type:         model.PromoCodeType(r.Type),
+		Value:        r.Value,
+		Currency:     r.Currency,
+		BuyQuantity:  r.BuyQuantity,
+		FreeQuantity: r.FreeQuantity,
+		ValidFrom:    r.ValidFrom,
+		ValidTo:      r.ValidTo,
+		UsageLimit:   r.UsageLimit,
+	}
+
+	switch p.Type {
+	case model.PromoCodeTypePercentage:
+		if p.Value <= 0 || p.Value > 100 {
+			return nil, fmt.Errorf("percentage promo code value must be in range 1..100")
+		}
+	case model.PromoCodeTypeFixed:
+		if p.Value <= 0 {
+			return nil, fmt.Errorf("fixed promo code value must be positive")
+		}
+		if err := model.ValidateCurrency(p.Currency); err != nil {
+			return nil, fmt.Errorf("fixed promo code must have currency: %s", err)
+		}
+	case model.PromoCodeTypeBuyXGetY:
+		if p.BuyQuantity <= 0 || p.FreeQuantity <= 0 {
+			return nil, fmt.Errorf("buy and free quantities must be positive")
+		}
+	default:
+		return nil, fmt.Errorf("invalid promo code type: %s", r.Type)
+	}
+
+	if p.Currency != "" && p.Type != model.PromoCodeTypeFixed {
+		return nil, fmt.Errorf("currency is allowed only for %s promo codes", model.PromoCodeTypeFixed)
+	}
+
+	if r.MenuItemID != "" {
+		if p.Type != model.PromoCodeTypeBuyXGetY {
+			return nil, fmt.Errorf("menu item is allowed only for %s promo codes", model.PromoCodeTypeBuyXGetY)
+		}
+
+		itemId, err := uuid.Parse(r.MenuItemID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid menu item id: %s", r.MenuItemID)
+		}
+		p.MenuItemID = &itemId
+	}
+
+	if p.ValidFrom != nil && p.ValidTo != nil && !p.ValidFrom.Before(*p.ValidTo) {
+		return nil, fmt.Errorf("validFrom must be before validTo")
+	}
+
+	if p.UsageLimit < 0 {
+		return nil, fmt.Errorf("usage limit must not be negative")
+	}
+
+	return &p, nil
+}
+
+func (ps *promoCodeService) Add(r AddPromoCodeRequest) error {
+	p, err := validatePromoCode(r.Code, r.PromoCodeRequest)
+	if err != nil {
+		return err
+	}
+
+	existing, err := ps.repo.Get(p.Code)
+	if err != nil {
+		log.Error(err)
+		return data.InternalError
+	}
+
+	if existing != nil {
+		return fmt.Errorf("promo code %s already exists", p.Code)
+	}
+
+	err = ps.repo.Add(*p)
+	if err != nil {
+		log.Error(err)
+		return data.InternalError
+	}
+
+	return nil
+}
+
+func (ps *promoCodeService) Update(code string, r PromoCodeRequest) error {
+	p, err := validatePromoCode(code, r)
+	if err != nil {
+		return err
+	}
+
+	affected, err := ps.repo.Update(*p)
+	if err != nil {
+		log.Error(err)
+		return data.InternalError
+	}
+
+	if affected == 0 {
+		return data.PromoCodeNotFoundError
+	}
+
+	return nil
+}
+
+func (ps *promoCodeService) Delete(code string) error {
+	affected, err := ps.repo.Delete(code)
+	if err != nil {
+		log.Error(err)
+		return data.InternalError
+	}
+
+	if affected == 0 {
+		return data.PromoCodeNotFoundError
+	}
+
+	return nil
+}
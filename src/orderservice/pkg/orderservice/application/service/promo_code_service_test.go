@@ -0,0 +1,24 @@
+package service
+
+import (
+	"testing"
+)
+
+func TestValidatePromoCodeCurrency(t *testing.T) {
+	cases := []struct {
+		request PromoCodeRequest
+		valid   bool
+	}{
+		{PromoCodeRequest{Type: "fixed", Value: 500, Currency: "USD"}, true},
+		{PromoCodeRequest{Type: "fixed", Value: 500}, false},
+		{PromoCodeRequest{Type: "fixed", Value: 500, Currency: "usd"}, false},
+		{PromoCodeRequest{Type: "percentage", Value: 10, Currency: "USD"}, false},
+	}
+
+	for _, c := range cases {
+		_, err := validatePromoCode("CODE", c.request)
+		if have := err == nil; have != c.valid {
+			t.Errorf("Validation is wrong for %+v. Have: %v, want: %v", c.request, err, c.valid)
+		}
+	}
+}
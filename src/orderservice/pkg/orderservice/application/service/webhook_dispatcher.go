@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"orderservice/pkg/orderservice/application/data"
+	"orderservice/pkg/orderservice/application/event"
+	"orderservice/pkg/orderservice/model"
+	"sync"
+	"time"
+)
+
+const maxWebhookErrorLength = 1024
+
+// WebhookSender posts delivery payload to the webhook url and returns response status code
+type WebhookSender interface {
+	Send(ctx context.Context, url, secret string, d model.WebhookDelivery) (int, error)
+}
+
+type WebhookDispatcherConfig struct {
+	MaxAttempts int
+	// BaseBackoff is doubled after every failed attempt up to MaxBackoff
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	PollInterval time.Duration
+	BatchSize    int
+	// Workers bounds concurrent attempts, every attempt is cancelled after AttemptTimeout
+	Workers        int
+	AttemptTimeout time.Duration
+}
+
+// WebhookDispatcher stores a delivery per subscribed webhook for every order event
+// and sends pending deliveries, failed ones are retried with exponential backoff
+// and marked dead when attempts are exhausted, due deliveries are sent by a bounded worker pool
+type WebhookDispatcher struct {
+	repo         model.WebhookRepository
+	deliveryRepo model.WebhookDeliveryRepository
+	sender       WebhookSender
+	events       *event.Broker
+	config       WebhookDispatcherConfig
+	now          func() time.Time
+}
+
+func NewWebhookDispatcher(repo model.WebhookRepository, deliveryRepo model.WebhookDeliveryRepository, sender WebhookSender, events *event.Broker, config WebhookDispatcherConfig) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		repo:         repo,
+		deliveryRepo: deliveryRepo,
+		sender:       sender,
+		events:       events,
+		config:       config,
+		now:          time.Now,
+	}
+}
+
+// Run dispatches events until ctx is done
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+
+	var lastEventID uint64
+	sub, _ := d.events.Subscribe(lastEventID, nil)
+	defer func() {
+		if sub != nil {
+			sub.Close()
+		}
+	}()
+
+	for {
+		var events <-chan data.OrderEvent
+		if sub != nil {
+			events = sub.C
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				// dropped as a slow subscriber, resubscribe on the next tick to catch up from history
+				sub = nil
+				continue
+			}
+			lastEventID = e.ID
+			d.enqueue(e)
+			d.processDue(ctx)
+		case <-ticker.C:
+			if sub == nil {
+				var replay []data.OrderEvent
+				sub, replay = d.events.Subscribe(lastEventID, nil)
+				for _, e := range replay {
+					lastEventID = e.ID
+					d.enqueue(e)
+				}
+			}
+			d.processDue(ctx)
+		}
+	}
+}
+
+func (d *WebhookDispatcher) enqueue(e data.OrderEvent) {
+	webhooks, err := d.repo.List()
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	now := d.now()
+	for _, w := range webhooks {
+		if !w.Accepts(e.Type) {
+			continue
+		}
+
+		err = d.deliveryRepo.Add(model.WebhookDelivery{
+			ID:            uuid.New(),
+			WebhookID:     w.ID,
+			EventID:       e.ID,
+			EventType:     e.Type,
+			Payload:       payload,
+			Status:        model.WebhookDeliveryStatusPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+		if err != nil {
+			log.Error(err)
+		}
+	}
+}
+
+// processDue returns when the batch is attempted, so a delivery is never sent twice at once
+func (d *WebhookDispatcher) processDue(ctx context.Context) {
+	deliveries, err := d.deliveryRepo.FindDue(d.now(), d.config.BatchSize)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	workers := d.config.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	jobs := make(chan model.WebhookDelivery)
+	var wg sync.WaitGroup
+	for i := 0; i < workers && i < len(deliveries); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for delivery := range jobs {
+				d.attempt(ctx, delivery)
+			}
+		}()
+	}
+
+	for _, delivery := range deliveries {
+		jobs <- delivery
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+func (d *WebhookDispatcher) attempt(ctx context.Context, delivery model.WebhookDelivery) {
+	w, err := d.repo.Get(delivery.WebhookID)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	delivery.Attempts++
+	if w == nil {
+		delivery.Status = model.WebhookDeliveryStatusDead
+		delivery.LastError = "webhook not found"
+	} else {
+		delivery.LastStatusCode, err = d.send(ctx, *w, delivery)
+		d.applyResult(&delivery, err)
+	}
+
+	_, err = d.deliveryRepo.Update(delivery)
+	if err != nil {
+		log.Error(err)
+	}
+}
+
+func (d *WebhookDispatcher) send(ctx context.Context, w model.Webhook, delivery model.WebhookDelivery) (int, error) {
+	if d.config.AttemptTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.config.AttemptTimeout)
+		defer cancel()
+	}
+
+	return d.sender.Send(ctx, w.URL, w.Secret, delivery)
+}
+
+func (d *WebhookDispatcher) applyResult(delivery *model.WebhookDelivery, err error) {
+	if err == nil && delivery.LastStatusCode >= 200 && delivery.LastStatusCode < 300 {
+		delivery.Status = model.WebhookDeliveryStatusDelivered
+		delivery.LastError = ""
+		return
+	}
+
+	delivery.LastError = ""
+	if err != nil {
+		delivery.LastError = err.Error()
+		if len(delivery.LastError) > maxWebhookErrorLength {
+			delivery.LastError = delivery.LastError[:maxWebhookErrorLength]
+		}
+	}
+
+	if delivery.Attempts >= d.config.MaxAttempts {
+		delivery.Status = model.WebhookDeliveryStatusDead
+		log.WithFields(log.Fields{"deliveryId": delivery.ID, "webhookId": delivery.WebhookID}).Warn("webhook delivery is dead")
+		return
+	}
+
+	delivery.NextAttemptAt = d.now().Add(d.backoff(delivery.Attempts))
+}
+
+// backoff returns delay before the next attempt after the given number of failed attempts
+func (d *WebhookDispatcher) backoff(attempts int) time.Duration {
+	delay := d.config.BaseBackoff
+	for i := 1; i < attempts && delay < d.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > d.config.MaxBackoff {
+		return d.config.MaxBackoff
+	}
+
+	return delay
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"orderservice/pkg/orderservice/application/data"
+	"orderservice/pkg/orderservice/application/event"
+	"orderservice/pkg/orderservice/model"
+	"sync"
+	"testing"
+	"time"
+)
+
+type mocWebhookRepository struct {
+	model.WebhookRepository
+	webhooks []model.Webhook
+}
+
+func (r *mocWebhookRepository) List() ([]model.Webhook, error) {
+	return r.webhooks, nil
+}
+
+func (r *mocWebhookRepository) Get(id uuid.UUID) (*model.Webhook, error) {
+	for _, w := range r.webhooks {
+		if w.ID == id {
+			return &w, nil
+		}
+	}
+
+	return nil, nil
+}
+
+type mocWebhookDeliveryRepository struct {
+	mu         sync.Mutex
+	deliveries map[uuid.UUID]model.WebhookDelivery
+}
+
+func (r *mocWebhookDeliveryRepository) Add(d model.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deliveries[d.ID] = d
+	return nil
+}
+
+func (r *mocWebhookDeliveryRepository) Update(d model.WebhookDelivery) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deliveries[d.ID] = d
+	return 1, nil
+}
+
+func (r *mocWebhookDeliveryRepository) Get(id uuid.UUID) (*model.WebhookDelivery, error) {
+	d, found := r.deliveries[id]
+	if !found {
+		return nil, nil
+	}
+
+	return &d, nil
+}
+
+func (r *mocWebhookDeliveryRepository) FindDue(now time.Time, _ int) ([]model.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result := make([]model.WebhookDelivery, 0)
+	for _, d := range r.deliveries {
+		if d.Status == model.WebhookDeliveryStatusPending && !d.NextAttemptAt.After(now) {
+			result = append(result, d)
+		}
+	}
+
+	return result, nil
+}
+
+type mocWebhookSender struct {
+	mu         sync.Mutex
+	statusCode int
+	err        error
+	sent       int
+	// delay blocks Send until it passes or the context is done
+	delay time.Duration
+}
+
+func (s *mocWebhookSender) Send(ctx context.Context, _, _ string, _ model.WebhookDelivery) (int, error) {
+	s.mu.Lock()
+	s.sent++
+	s.mu.Unlock()
+
+	if s.delay > 0 {
+		select {
+		case <-time.After(s.delay):
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+
+	return s.statusCode, s.err
+}
+
+func newTestDispatcher(sender WebhookSender, webhooks ...model.Webhook) (*WebhookDispatcher, *mocWebhookDeliveryRepository) {
+	deliveryRepo := &mocWebhookDeliveryRepository{deliveries: map[uuid.UUID]model.WebhookDelivery{}}
+	d := NewWebhookDispatcher(&mocWebhookRepository{webhooks: webhooks}, deliveryRepo, sender, event.NewBroker(10), WebhookDispatcherConfig{
+		MaxAttempts: 3,
+		BaseBackoff: time.Second,
+		MaxBackoff:  3 * time.Second,
+		BatchSize:   10,
+		Workers:     4,
+	})
+
+	return d, deliveryRepo
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	d, _ := newTestDispatcher(nil)
+	want := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second}
+	for i, delay := range want {
+		if have := d.backoff(i + 1); have != delay {
+			t.Errorf("Backoff after %d attempts is wrong. Have: %v, want: %v", i+1, have, delay)
+		}
+	}
+}
+
+func TestWebhookDeliveryOnlyForSubscribedEvents(t *testing.T) {
+	sender := &mocWebhookSender{statusCode: 204}
+	d, deliveries := newTestDispatcher(sender,
+		model.Webhook{ID: uuid.New(), EventTypes: []string{data.OrderCreatedEvent}},
+		model.Webhook{ID: uuid.New(), EventTypes: []string{data.OrderDeletedEvent}},
+	)
+
+	d.enqueue(data.OrderEvent{ID: 1, Type: data.OrderCreatedEvent})
+	d.processDue(context.Background())
+
+	if len(deliveries.deliveries) != 1 || sender.sent != 1 {
+		t.Fatalf("Deliveries count is wrong. Have: %d, sent: %d, want: 1", len(deliveries.deliveries), sender.sent)
+	}
+
+	for _, delivery := range deliveries.deliveries {
+		if delivery.Status != model.WebhookDeliveryStatusDelivered {
+			t.Errorf("Delivery status is wrong. Have: %s, want: %s", delivery.Status, model.WebhookDeliveryStatusDelivered)
+		}
+	}
+}
+
+func TestWebhookDeliveryBecomesDeadAfterMaxAttempts(t *testing.T) {
+	sender := &mocWebhookSender{err: errors.New("connection refused")}
+	d, deliveries := newTestDispatcher(sender, model.Webhook{ID: uuid.New(), EventTypes: []string{model.WebhookAllEvents}})
+	now := time.Now()
+	d.now = func() time.Time { return now }
+
+	d.enqueue(data.OrderEvent{ID: 1, Type: data.OrderUpdatedEvent})
+	for i := 0; i < 3; i++ {
+		d.processDue(context.Background())
+		now = now.Add(time.Hour)
+	}
+	d.processDue(context.Background())
+
+	if sender.sent != 3 {
+		t.Errorf("Attempts count is wrong. Have: %d, want: %d", sender.sent, 3)
+	}
+
+	for _, delivery := range deliveries.deliveries {
+		if delivery.Status != model.WebhookDeliveryStatusDead || delivery.LastError != "connection refused" {
+			t.Errorf("Delivery is wrong: %s, %s", delivery.Status, delivery.LastError)
+		}
+	}
+}
+
+func TestWebhookAttemptTimesOut(t *testing.T) {
+	sender := &mocWebhookSender{statusCode: 204, delay: time.Minute}
+	d, deliveries := newTestDispatcher(sender,
+		model.Webhook{ID: uuid.New(), EventTypes: []string{model.WebhookAllEvents}},
+		model.Webhook{ID: uuid.New(), EventTypes: []string{model.WebhookAllEvents}},
+	)
+	d.config.AttemptTimeout = 10 * time.Millisecond
+
+	d.enqueue(data.OrderEvent{ID: 1, Type: data.OrderCreatedEvent})
+	start := time.Now()
+	d.processDue(context.Background())
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("Attempts aren't bounded by timeout. Have: %v", elapsed)
+	}
+
+	for _, delivery := range deliveries.deliveries {
+		if delivery.Status != model.WebhookDeliveryStatusPending || delivery.Attempts != 1 || delivery.LastError != context.DeadlineExceeded.Error() {
+			t.Errorf("Delivery is wrong: %s, %d, %s", delivery.Status, delivery.Attempts, delivery.LastError)
+		}
+	}
+}
+
+func TestValidateWebhookRejectsInternalAddresses(t *testing.T) {
+	cases := map[string]bool{
+		"https://example.com/hook":        true,
+		"https://93.184.216.34/hook":      true,
+		"http://localhost/hook":           false,
+		"http://api.localhost/hook":       false,
+		"http://127.0.0.1:8080/hook":      false,
+		"http://169.254.169.254/latest":   false,
+		"http://10.1.2.3/hook":            false,
+		"http://172.16.0.1/hook":          false,
+		"http://192.168.1.1/hook":         false,
+		"http://0.0.0.0/hook":             false,
+		"http://[::1]/hook":               false,
+		"http://[fd00::1]/hook":           false,
+		"http://[::ffff:127.0.0.1]/hook":  false,
+		"http://[fe80::1%25eth0]:80/hook": false,
+	}
+	for u, valid := range cases {
+		err := validateWebhook(WebhookRequest{URL: u, EventTypes: []string{model.WebhookAllEvents}})
+		if (err == nil) != valid {
+			t.Errorf("Validation of %s is wrong. Have: %v, want valid: %v", u, err, valid)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"net/url"
+	"orderservice/pkg/orderservice/application/data"
+	"orderservice/pkg/orderservice/model"
+	"strings"
+	"time"
+)
+
+const webhookSecretSize = 32
+
+// maxWebhookSecretLength keeps encrypted secrets within the column size
+const maxWebhookSecretLength = 128
+
+// deniedWebhookNetworks are private, shared and reserved ranges webhooks must not reach
+var deniedWebhookNetworks = parseNetworks(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"240.0.0.0/4",
+	"fc00::/7",
+)
+
+var webhookEventTypes = map[string]bool{
+	model.WebhookAllEvents: true,
+	data.OrderCreatedEvent: true,
+	data.OrderUpdatedEvent: true,
+	data.OrderDeletedEvent: true,
+}
+
+type WebhookRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"eventTypes"`
+	// Secret is generated when empty
+	Secret string `json:"secret"`
+}
+
+type webhookService struct {
+	repo         model.WebhookRepository
+	deliveryRepo model.WebhookDeliveryRepository
+}
+
+type WebhookService interface {
+	// Add returns created webhook with its secret, the secret isn't shown later
+	Add(r WebhookRequest) (*data.Webhook, error)
+	Update(id string, r WebhookRequest) error
+	Delete(id string) error
+	// Redeliver schedules delivery for an immediate new attempt
+	Redeliver(deliveryID string) error
+}
+
+func NewWebhookService(repo model.WebhookRepository, deliveryRepo model.WebhookDeliveryRepository) WebhookService {
+	return &webhookService{repo: repo, deliveryRepo: deliveryRepo}
+}
+
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+
+	return networks
+}
+
+// WebhookAddressAllowed rejects loopback, link-local, private and unspecified addresses,
+// the sender checks it again after DNS resolution
+func WebhookAddressAllowed(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+
+	for _, network := range deniedWebhookNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func validateWebhook(r WebhookRequest) error {
+	u, err := url.Parse(r.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("invalid webhook url: %s", r.URL)
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("webhook url must not point to a local host: %s", r.URL)
+	}
+
+	// zone of an IPv6 address doesn't change where it points to
+	if zone := strings.LastIndex(host, "%"); zone >= 0 {
+		host = host[:zone]
+	}
+	if ip := net.ParseIP(host); ip != nil && !WebhookAddressAllowed(ip) {
+		return fmt.Errorf("webhook url must not point to a private address: %s", r.URL)
+	}
+
+	if len(r.Secret) > maxWebhookSecretLength {
+		return fmt.Errorf("webhook secret must be at most %d characters", maxWebhookSecretLength)
+	}
+
+	if len(r.EventTypes) == 0 {
+		return fmt.Errorf("webhook must have at least one event type")
+	}
+
+	for _, t := range r.EventTypes {
+		if !webhookEventTypes[t] {
+			return fmt.Errorf("invalid event type: %s", t)
+		}
+	}
+
+	return nil
+}
+
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, webhookSecretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+func (s *webhookService) Add(r WebhookRequest) (*data.Webhook, error) {
+	if err := validateWebhook(r); err != nil {
+		return nil, err
+	}
+
+	secret := r.Secret
+	if secret == "" {
+		var err error
+		secret, err = generateWebhookSecret()
+		if err != nil {
+			log.Error(err)
+			return nil, data.InternalError
+		}
+	}
+
+	w := model.Webhook{
+		ID:         uuid.New(),
+		URL:        r.URL,
+		EventTypes: r.EventTypes,
+		Secret:     secret,
+		CreatedAt:  time.Now(),
+	}
+
+	if err := s.repo.Add(w); err != nil {
+		log.Error(err)
+		return nil, data.InternalError
+	}
+
+	return &data.Webhook{
+		ID:         w.ID.String(),
+		URL:        w.URL,
+		EventTypes: w.EventTypes,
+		Secret:     w.Secret,
+		CreatedAt:  w.CreatedAt,
+	}, nil
+}
+
+func (s *webhookService) Update(id string, r WebhookRequest) error {
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return fmt.Errorf("invalid uuid: %s", id)
+	}
+
+	if err = validateWebhook(r); err != nil {
+		return err
+	}
+
+	w, err := s.repo.Get(uid)
+	if err != nil {
+		log.Error(err)
+		return data.InternalError
+	}
+
+	if w == nil {
+		return data.WebhookNotFoundError
+	}
+
+	w.URL = r.URL
+	w.EventTypes = r.EventTypes
+	if r.Secret != "" {
+		w.Secret = r.Secret
+	}
+
+	_, err = s.repo.Update(*w)
+	if err != nil {
+		log.Error(err)
+		return data.InternalError
+	}
+
+	return nil
+}
+
+func (s *webhookService) Delete(id string) error {
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return fmt.Errorf("invalid uuid: %s", id)
+	}
+
+	affected, err := s.repo.Delete(uid)
+	if err != nil {
+		log.Error(err)
+		return data.InternalError
+	}
+
+	if affected == 0 {
+		return data.WebhookNotFoundError
+	}
+
+	return nil
+}
+
+func (s *webhookService) Redeliver(deliveryID string) error {
+	uid, err := uuid.Parse(deliveryID)
+	if err != nil {
+		return fmt.Errorf("invalid uuid: %s", deliveryID)
+	}
+
+	d, err := s.deliveryRepo.Get(uid)
+	if err != nil {
+		log.Error(err)
+		return data.InternalError
+	}
+
+	if d == nil {
+		return data.WebhookDeliveryNotFoundError
+	}
+
+	d.Status = model.WebhookDeliveryStatusPending
+	d.Attempts = 0
+	d.NextAttemptAt = time.Now()
+
+	_, err = s.deliveryRepo.Update(*d)
+	if err != nil {
+		log.Error(err)
+		return data.InternalError
+	}
+
+	return nil
+}
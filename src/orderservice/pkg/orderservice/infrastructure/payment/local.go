@@ -0,0 +1,86 @@
+package payment
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"orderservice/pkg/orderservice/application/service"
+	"orderservice/pkg/orderservice/model"
+	"strings"
+)
+
+const LocalProviderName = "local"
+
+const signaturePrefix = "sha256="
+
+// localProvider accepts every payment without external calls, it is used for development
+// and tests. With autoCapture disabled intents stay pending until a signed webhook arrives.
+type localProvider struct {
+	secret      []byte
+	autoCapture bool
+}
+
+type localWebhookPayload struct {
+	Reference string `json:"reference"`
+	Status    string `json:"status"`
+}
+
+func NewLocalProvider(secret string, autoCapture bool) service.PaymentProvider {
+	return &localProvider{secret: []byte(secret), autoCapture: autoCapture}
+}
+
+func (p *localProvider) Name() string {
+	return LocalProviderName
+}
+
+func (p *localProvider) CreateIntent(paymentID uuid.UUID, amount model.Money) (*service.PaymentIntent, error) {
+	status := model.PaymentStatusPending
+	if p.autoCapture {
+		status = model.PaymentStatusSucceeded
+	}
+
+	log.WithFields(log.Fields{"paymentId": paymentID, "amount": amount.String()}).Info("local payment intent created")
+
+	return &service.PaymentIntent{ProviderRef: "local_" + paymentID.String(), Status: status}, nil
+}
+
+func (p *localProvider) Refund(providerRef string, amount model.Money) error {
+	log.WithFields(log.Fields{"reference": providerRef, "amount": amount.String()}).Info("local payment refunded")
+	return nil
+}
+
+// Sign returns signature expected in webhook header for the payload
+func (p *localProvider) Sign(payload []byte) string {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write(payload)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (p *localProvider) ParseWebhook(payload []byte, signature string) (*service.PaymentEvent, error) {
+	if len(p.secret) == 0 {
+		return nil, errors.New("webhook secret is not configured")
+	}
+
+	if !strings.HasPrefix(signature, signaturePrefix) || !hmac.Equal([]byte(signature), []byte(p.Sign(payload))) {
+		return nil, errors.New("signature mismatch")
+	}
+
+	var webhook localWebhookPayload
+	if err := json.Unmarshal(payload, &webhook); err != nil {
+		return nil, err
+	}
+
+	status := model.PaymentStatus(webhook.Status)
+	switch status {
+	case model.PaymentStatusSucceeded, model.PaymentStatusFailed, model.PaymentStatusRefunded:
+	default:
+		return nil, fmt.Errorf("unknown payment status: %s", webhook.Status)
+	}
+
+	return &service.PaymentEvent{ProviderRef: webhook.Reference, Status: status}, nil
+}
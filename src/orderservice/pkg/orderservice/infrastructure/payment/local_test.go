@@ -0,0 +1,28 @@
+package payment
+
+import (
+	"orderservice/pkg/orderservice/model"
+	"testing"
+)
+
+func TestLocalProviderWebhookSignature(t *testing.T) {
+	provider := &localProvider{secret: []byte("secret")}
+	payload := []byte(`{"reference": "local_1", "status": "succeeded"}`)
+
+	event, err := provider.ParseWebhook(payload, provider.Sign(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.ProviderRef != "local_1" || event.Status != model.PaymentStatusSucceeded {
+		t.Errorf("Event is wrong: %v", event)
+	}
+
+	if _, err = provider.ParseWebhook(payload, "sha256=00"); err == nil {
+		t.Error("Webhook with wrong signature must be rejected")
+	}
+
+	tampered := []byte(`{"reference": "local_1", "status": "refunded"}`)
+	if _, err = provider.ParseWebhook(tampered, provider.Sign(payload)); err == nil {
+		t.Error("Webhook with tampered payload must be rejected")
+	}
+}
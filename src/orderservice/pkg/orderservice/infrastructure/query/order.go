@@ -2,15 +2,41 @@ package query
 
 import (
 	"database/sql"
-	"fmt"
+	"encoding/json"
 	log "github.com/sirupsen/logrus"
 	"orderservice/pkg/orderservice/application/data"
 	"orderservice/pkg/orderservice/application/query"
-	"strconv"
-	"strings"
+	"orderservice/pkg/orderservice/model"
 	"time"
 )
 
+const selectOrders = "" +
+	"SELECT " +
+	"BIN_TO_UUID(o.order_id) AS order_id, " +
+	"o.cost, " +
+	"o.currency, " +
+	"o.subtotal, " +
+	"o.discount, " +
+	"o.tax, " +
+	"IFNULL(o.promo_code, ''), " +
+	"o.created_at, " +
+	"o.notes, " +
+	"o.delivery_type, " +
+	"o.delivery_address, " +
+	"o.phone, " +
+	"o.status " +
+	"FROM `order` o "
+
+const selectMenuItems = "" +
+	"SELECT " +
+	"BIN_TO_UUID(oi.order_id) AS order_id, " +
+	"BIN_TO_UUID(oi.menu_item_id) AS menu_item_id, " +
+	"oi.quantity, " +
+	"IFNULL(oi.modifiers, 'null'), " +
+	"oi.comment " +
+	"FROM order_item oi " +
+	"INNER JOIN `order` o ON (o.order_id = oi.order_id) "
+
 type orderQueryService struct {
 	db *sql.DB
 }
@@ -19,65 +45,89 @@ func NewOrderQueryService(db *sql.DB) query.OrderQueryService {
 	return &orderQueryService{db: db}
 }
 
-func parseMenuItems(itemsStr string) ([]data.MenuItem, error) {
-	if len(itemsStr) == 0 {
-		return make([]data.MenuItem, 0), nil
-	}
-
-	items := strings.Split(itemsStr, ",")
-	result := make([]data.MenuItem, len(items))
-	for i, pairStr := range items {
-		pair := strings.Split(pairStr, "=")
-		if len(pair) != 2 {
-			return nil, fmt.Errorf("invalid pair: %s", pairStr)
-		}
+func parseMenuItem(r *sql.Rows) (string, *data.MenuItem, error) {
+	var orderId string
+	var item data.MenuItem
+	var modifiers []byte
 
-		quantity, err := strconv.Atoi(pair[1])
-		if err != nil {
-			return nil, fmt.Errorf("invalid quantity: %s", pair[1])
-		}
+	err := r.Scan(&orderId, &item.ID, &item.Quantity, &modifiers, &item.Comment)
+	if err != nil {
+		return "", nil, err
+	}
 
-		result[i] = data.MenuItem{ID: pair[0], Quantity: quantity}
+	err = json.Unmarshal(modifiers, &item.Modifiers)
+	if err != nil {
+		return "", nil, err
 	}
 
-	return result, nil
+	return orderId, &item, nil
 }
 
 func parseOrder(r *sql.Rows) (*data.OrderInfo, error) {
 	var orderId string
-	var cost int
+	var cost, subtotal, discount, tax int64
+	var currency string
+	var pricing data.Pricing
 	var createdAt time.Time
-	var items string
+	var notes string
+	var delivery data.Delivery
+	var status string
 
-	err := r.Scan(&orderId, &cost, &createdAt, &items)
+	err := r.Scan(&orderId, &cost, &currency, &subtotal, &discount, &tax, &pricing.PromoCode, &createdAt, &notes, &delivery.Type, &delivery.Address, &delivery.Phone, &status)
 	if err != nil {
 		return nil, err
 	}
 
-	menuItems, err := parseMenuItems(items)
-	if err != nil {
-		return nil, err
-	}
+	pricing.Subtotal = data.NewMoney(model.NewMoney(subtotal, currency))
+	pricing.Discount = data.NewMoney(model.NewMoney(discount, currency))
+	pricing.Tax = data.NewMoney(model.NewMoney(tax, currency))
+	pricing.Total = data.NewMoney(model.NewMoney(cost, currency))
 
 	return &data.OrderInfo{
 		ID:        orderId,
-		MenuItems: menuItems,
+		MenuItems: make([]data.MenuItem, 0),
 		OrderedAt: createdAt,
-		Cost:      cost,
+		Cost:      pricing.Total,
+		Notes:     notes,
+		Delivery:  delivery,
+		Pricing:   pricing,
+		Status:    status,
 	}, nil
 }
 
+// fillMenuItems attaches items selected with the same filter to the matching orders
+func (qs *orderQueryService) fillMenuItems(orders []data.OrderInfo, where string, args ...interface{}) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	rows, err := qs.db.Query(selectMenuItems+where+" ORDER BY oi.menu_item_id", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	indexes := map[string]int{}
+	for i, order := range orders {
+		indexes[order.ID] = i
+	}
+
+	for rows.Next() {
+		orderId, item, err := parseMenuItem(rows)
+		if err != nil {
+			return err
+		}
+
+		if i, found := indexes[orderId]; found {
+			orders[i].MenuItems = append(orders[i].MenuItems, *item)
+		}
+	}
+
+	return rows.Err()
+}
+
 func (qs *orderQueryService) GetOrders() (*data.OrdersList, error) {
-	rows, err := qs.db.Query("" +
-		"SELECT " +
-		"BIN_TO_UUID(o.order_id) AS order_id, " +
-		"o.cost, " +
-		"o.created_at, " +
-		"IFNULL(GROUP_CONCAT(CONCAT(BIN_TO_UUID(oi.menu_item_id), '=', oi.quantity)), '') AS items " +
-		"FROM `order` o " +
-		"LEFT JOIN order_item oi ON (o.order_id = oi.order_id) " +
-		"WHERE o.deleted_at IS NULL " +
-		"GROUP BY o.order_id")
+	rows, err := qs.db.Query(selectOrders + "WHERE o.deleted_at IS NULL")
 
 	if err != nil {
 		log.Error(err)
@@ -96,20 +146,17 @@ func (qs *orderQueryService) GetOrders() (*data.OrdersList, error) {
 		orders = append(orders, *order)
 	}
 
+	err = qs.fillMenuItems(orders, "WHERE o.deleted_at IS NULL")
+	if err != nil {
+		log.Error(err)
+		return nil, data.InternalError
+	}
+
 	return &data.OrdersList{Orders: orders}, nil
 }
 
 func (qs *orderQueryService) GetOrderInfo(id string) (*data.OrderInfo, error) {
-	rows, err := qs.db.Query(""+
-		"SELECT "+
-		"BIN_TO_UUID(o.order_id) AS order_id, "+
-		"o.cost, "+
-		"o.created_at, "+
-		"IFNULL(GROUP_CONCAT(CONCAT(BIN_TO_UUID(oi.menu_item_id), '=', oi.quantity)), '') AS items "+
-		"FROM `order` o "+
-		"LEFT JOIN order_item oi ON (o.order_id = oi.order_id) "+
-		"WHERE o.deleted_at IS NULL AND BIN_TO_UUID(o.order_id) = ? "+
-		"GROUP BY o.order_id", id)
+	rows, err := qs.db.Query(selectOrders+"WHERE o.deleted_at IS NULL AND BIN_TO_UUID(o.order_id) = ?", id)
 
 	if err != nil {
 		log.Error(err)
@@ -124,7 +171,14 @@ func (qs *orderQueryService) GetOrderInfo(id string) (*data.OrderInfo, error) {
 			return nil, data.InternalError
 		}
 
-		return order, nil
+		orders := []data.OrderInfo{*order}
+		err = qs.fillMenuItems(orders, "WHERE o.deleted_at IS NULL AND BIN_TO_UUID(o.order_id) = ?", id)
+		if err != nil {
+			log.Error(err)
+			return nil, data.InternalError
+		}
+
+		return &orders[0], nil
 	}
 
 	return nil, nil // not found
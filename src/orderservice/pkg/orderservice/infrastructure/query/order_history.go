@@ -0,0 +1,198 @@
+package query
+
+import (
+	"database/sql"
+	"encoding/json"
+	log "github.com/sirupsen/logrus"
+	"orderservice/pkg/orderservice/application/data"
+	"orderservice/pkg/orderservice/infrastructure/repository"
+	"orderservice/pkg/orderservice/model"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+const menuItemsPath = "/menuItems"
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// parseSnapshot reads the order state written to order_audit by the order repository
+func parseSnapshot(b []byte) (*data.OrderSnapshot, error) {
+	s, err := repository.ParseOrderSnapshot(b)
+	if err != nil || s == nil {
+		return nil, err
+	}
+
+	items := make([]data.MenuItem, len(s.MenuItems))
+	for i, item := range s.MenuItems {
+		items[i] = data.MenuItem{ID: item.ID, Quantity: item.Quantity, Modifiers: item.Modifiers, Comment: item.Comment}
+	}
+
+	pricing := data.Pricing{
+		Subtotal:  data.NewMoney(model.NewMoney(s.Subtotal, s.Currency)),
+		Discount:  data.NewMoney(model.NewMoney(s.Discount, s.Currency)),
+		Tax:       data.NewMoney(model.NewMoney(s.Tax, s.Currency)),
+		Total:     data.NewMoney(model.NewMoney(s.Cost, s.Currency)),
+		PromoCode: s.PromoCode,
+	}
+
+	return &data.OrderSnapshot{
+		MenuItems: items,
+		Cost:      pricing.Total,
+		Pricing:   pricing,
+		Notes:     s.Notes,
+		Delivery:  data.Delivery{Type: s.Delivery.Type, Address: s.Delivery.Address, Phone: s.Delivery.Phone},
+		Status:    s.Status,
+	}, nil
+}
+
+func (qs *orderQueryService) GetOrderHistory(id string) (*data.OrderHistory, error) {
+	rows, err := qs.db.Query(""+
+		"SELECT revision, action, actor, `before`, `after`, created_at "+
+		"FROM order_audit "+
+		"WHERE BIN_TO_UUID(order_id) = ? "+
+		"ORDER BY revision", id)
+	if err != nil {
+		log.Error(err)
+		return nil, data.InternalError
+	}
+	defer rows.Close()
+
+	revisions := make([]data.OrderRevision, 0)
+	for rows.Next() {
+		revision, err := parseRevision(rows)
+		if err != nil {
+			log.Error(err)
+			return nil, data.InternalError
+		}
+
+		revisions = append(revisions, *revision)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Error(err)
+		return nil, data.InternalError
+	}
+
+	if len(revisions) == 0 {
+		return nil, nil // not found
+	}
+
+	return &data.OrderHistory{OrderID: id, Revisions: revisions}, nil
+}
+
+func parseRevision(r *sql.Rows) (*data.OrderRevision, error) {
+	var revision data.OrderRevision
+	var before, after []byte
+	var changedAt time.Time
+
+	err := r.Scan(&revision.Revision, &revision.Action, &revision.Actor, &before, &after, &changedAt)
+	if err != nil {
+		return nil, err
+	}
+	revision.ChangedAt = changedAt
+
+	revision.Before, err = parseSnapshot(before)
+	if err != nil {
+		return nil, err
+	}
+
+	revision.After, err = parseSnapshot(after)
+	if err != nil {
+		return nil, err
+	}
+
+	revision.Changes, err = diffSnapshots(revision.Before, revision.After)
+	if err != nil {
+		return nil, err
+	}
+
+	return &revision, nil
+}
+
+// diffSnapshots compares leaf values of both snapshots, changes are sorted by path
+func diffSnapshots(before, after *data.OrderSnapshot) ([]data.FieldChange, error) {
+	beforeValues, err := flattenSnapshot(before)
+	if err != nil {
+		return nil, err
+	}
+
+	afterValues, err := flattenSnapshot(after)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(beforeValues)+len(afterValues))
+	for path := range beforeValues {
+		paths = append(paths, path)
+	}
+	for path := range afterValues {
+		if _, found := beforeValues[path]; !found {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	changes := make([]data.FieldChange, 0)
+	for _, path := range paths {
+		b, a := beforeValues[path], afterValues[path]
+		if !reflect.DeepEqual(b, a) {
+			changes = append(changes, data.FieldChange{Path: path, Before: b, After: a})
+		}
+	}
+
+	return changes, nil
+}
+
+func flattenSnapshot(s *data.OrderSnapshot) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	if s == nil {
+		return values, nil
+	}
+
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var v interface{}
+	if err = json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+
+	flatten("", v, values)
+	return values, nil
+}
+
+// flatten keys menu items by id so reordering or removing an item changes only its own paths,
+// other arrays like modifiers are compared as a whole
+func flatten(path string, v interface{}, values map[string]interface{}) {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			flatten(path+"/"+pointerEscaper.Replace(key), child, values)
+		}
+	case []interface{}:
+		if path != menuItemsPath {
+			values[path] = value
+			return
+		}
+
+		for _, item := range value {
+			fields, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			id, _ := fields["id"].(string)
+			for key, child := range fields {
+				if key != "id" {
+					flatten(path+"/"+pointerEscaper.Replace(id)+"/"+key, child, values)
+				}
+			}
+		}
+	default:
+		values[path] = value
+	}
+}
@@ -0,0 +1,56 @@
+package query
+
+import (
+	"orderservice/pkg/orderservice/application/data"
+	"reflect"
+	"testing"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	before := &data.OrderSnapshot{
+		MenuItems: []data.MenuItem{
+			{ID: "a", Quantity: 1, Modifiers: []string{"spicy"}},
+			{ID: "b", Quantity: 2},
+		},
+		Cost:   data.Money{Amount: "1.26", Currency: "EUR"},
+		Notes:  "ring twice",
+		Status: "created",
+	}
+	after := &data.OrderSnapshot{
+		MenuItems: []data.MenuItem{
+			{ID: "c", Quantity: 1},
+			{ID: "a", Quantity: 3, Modifiers: []string{"spicy"}},
+		},
+		Cost:   data.Money{Amount: "1.68", Currency: "EUR"},
+		Notes:  "ring twice",
+		Status: "created",
+	}
+
+	changes, err := diffSnapshots(before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []data.FieldChange{
+		{Path: "/cost/amount", Before: "1.26", After: "1.68"},
+		{Path: "/menuItems/a/quantity", Before: float64(1), After: float64(3)},
+		{Path: "/menuItems/b/quantity", Before: float64(2)},
+		{Path: "/menuItems/c/quantity", After: float64(1)},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("Changes are wrong. Have: %v, want: %v", changes, want)
+	}
+}
+
+func TestDiffSnapshotsOfDeletedOrder(t *testing.T) {
+	changes, err := diffSnapshots(&data.OrderSnapshot{Notes: "note"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range changes {
+		if c.After != nil {
+			t.Errorf("Deleted order has after value: %v", c)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package query
+
+import (
+	"database/sql"
+	log "github.com/sirupsen/logrus"
+	"orderservice/pkg/orderservice/application/data"
+	"orderservice/pkg/orderservice/application/query"
+)
+
+const selectPromoCodes = "" +
+	"SELECT code, type, value, IFNULL(currency, ''), buy_quantity, free_quantity, IFNULL(BIN_TO_UUID(menu_item_id), ''), " +
+	"valid_from, valid_to, usage_limit, used_count " +
+	"FROM promo_code "
+
+type promoCodeQueryService struct {
+	db *sql.DB
+}
+
+func NewPromoCodeQueryService(db *sql.DB) query.PromoCodeQueryService {
+	return &promoCodeQueryService{db: db}
+}
+
+func parsePromoCode(r *sql.Rows) (*data.PromoCode, error) {
+	var p data.PromoCode
+	var validFrom, validTo sql.NullTime
+
+	err := r.Scan(&p.Code, &p.Type, &p.Value, &p.Currency, &p.BuyQuantity, &p.FreeQuantity, &p.MenuItemID, &validFrom, &validTo, &p.UsageLimit, &p.UsedCount)
+	if err != nil {
+		return nil, err
+	}
+
+	if validFrom.Valid {
+		p.ValidFrom = &validFrom.Time
+	}
+	if validTo.Valid {
+		p.ValidTo = &validTo.Time
+	}
+
+	return &p, nil
+}
+
+func (qs *promoCodeQueryService) GetPromoCodes() (*data.PromoCodesList, error) {
+	rows, err := qs.db.Query(selectPromoCodes + "ORDER BY code")
+	if err != nil {
+		log.Error(err)
+		return nil, data.InternalError
+	}
+	defer rows.Close()
+
+	promoCodes := make([]data.PromoCode, 0)
+	for rows.Next() {
+		p, err := parsePromoCode(rows)
+		if err != nil {
+			log.Error(err)
+			return nil, data.InternalError
+		}
+
+		promoCodes = append(promoCodes, *p)
+	}
+
+	return &data.PromoCodesList{PromoCodes: promoCodes}, nil
+}
+
+func (qs *promoCodeQueryService) GetPromoCode(code string) (*data.PromoCode, error) {
+	rows, err := qs.db.Query(selectPromoCodes+"WHERE code = ?", code)
+	if err != nil {
+		log.Error(err)
+		return nil, data.InternalError
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		p, err := parsePromoCode(rows)
+		if err != nil {
+			log.Error(err)
+			return nil, data.InternalError
+		}
+
+		return p, nil
+	}
+
+	return nil, nil // not found
+}
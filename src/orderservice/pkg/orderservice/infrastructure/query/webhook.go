@@ -0,0 +1,108 @@
+package query
+
+import (
+	"database/sql"
+	"encoding/json"
+	log "github.com/sirupsen/logrus"
+	"orderservice/pkg/orderservice/application/data"
+	"orderservice/pkg/orderservice/application/query"
+)
+
+const deliveriesListLimit = 500
+
+type webhookQueryService struct {
+	db *sql.DB
+}
+
+func NewWebhookQueryService(db *sql.DB) query.WebhookQueryService {
+	return &webhookQueryService{db: db}
+}
+
+func parseWebhook(r *sql.Rows) (*data.Webhook, error) {
+	var w data.Webhook
+	var eventTypes []byte
+
+	err := r.Scan(&w.ID, &w.URL, &eventTypes, &w.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(eventTypes, &w.EventTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &w, nil
+}
+
+func (qs *webhookQueryService) GetWebhooks() (*data.WebhooksList, error) {
+	rows, err := qs.db.Query("SELECT BIN_TO_UUID(webhook_id), url, event_types, created_at FROM webhook ORDER BY created_at")
+	if err != nil {
+		log.Error(err)
+		return nil, data.InternalError
+	}
+	defer rows.Close()
+
+	webhooks := make([]data.Webhook, 0)
+	for rows.Next() {
+		w, err := parseWebhook(rows)
+		if err != nil {
+			log.Error(err)
+			return nil, data.InternalError
+		}
+
+		webhooks = append(webhooks, *w)
+	}
+
+	return &data.WebhooksList{Webhooks: webhooks}, nil
+}
+
+func (qs *webhookQueryService) GetWebhook(id string) (*data.Webhook, error) {
+	rows, err := qs.db.Query("SELECT BIN_TO_UUID(webhook_id), url, event_types, created_at FROM webhook WHERE BIN_TO_UUID(webhook_id) = ?", id)
+	if err != nil {
+		log.Error(err)
+		return nil, data.InternalError
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		w, err := parseWebhook(rows)
+		if err != nil {
+			log.Error(err)
+			return nil, data.InternalError
+		}
+
+		return w, nil
+	}
+
+	return nil, nil // not found
+}
+
+func (qs *webhookQueryService) GetDeliveries(status string) (*data.WebhookDeliveriesList, error) {
+	rows, err := qs.db.Query(""+
+		"SELECT BIN_TO_UUID(delivery_id), BIN_TO_UUID(webhook_id), event_id, event_type, status, attempts, "+
+		"next_attempt_at, last_status_code, last_error, created_at "+
+		"FROM webhook_delivery "+
+		"WHERE ? = '' OR status = ? "+
+		"ORDER BY created_at DESC LIMIT ?", status, status, deliveriesListLimit)
+	if err != nil {
+		log.Error(err)
+		return nil, data.InternalError
+	}
+	defer rows.Close()
+
+	deliveries := make([]data.WebhookDelivery, 0)
+	for rows.Next() {
+		var d data.WebhookDelivery
+		err = rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Status, &d.Attempts,
+			&d.NextAttemptAt, &d.LastStatusCode, &d.LastError, &d.CreatedAt)
+		if err != nil {
+			log.Error(err)
+			return nil, data.InternalError
+		}
+
+		deliveries = append(deliveries, d)
+	}
+
+	return &data.WebhookDeliveriesList{Deliveries: deliveries}, nil
+}
@@ -3,65 +3,194 @@ package repository
 import (
 	"context"
 	"database/sql"
-	"fmt"
+	"encoding/json"
 	"github.com/google/uuid"
 	"orderservice/pkg/orderservice/model"
-	"strconv"
 	"strings"
 	"time"
 )
 
+// orderRepository changes order rows directly, changed rows lose event_version
+// so events stored before aren't replayed over them
 type orderRepository struct {
 	db *sql.DB
 }
 
-func (o *orderRepository) Add(order model.Order) error {
+// queryer is implemented by both *sql.DB and *sql.Tx so reads can be done inside a transaction
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func (o *orderRepository) Add(order model.Order, actor string) error {
+	promoTerms, err := promoTermsJson(order.Pricing.Promo)
+	if err != nil {
+		return err
+	}
+
 	return o.withTx(func(tx *sql.Tx, ctx context.Context, closeTx func(error) error) error {
-		_, err := tx.ExecContext(ctx, "INSERT INTO `order` (`order_id`, `cost`, `created_at`, `updated_at`, `deleted_at`) VALUES (UUID_TO_BIN(?), ?, ?, ?, NULL)", order.ID, order.Cost, order.OrderedAt, order.OrderedAt)
+		_, err := tx.ExecContext(ctx, ""+
+			"INSERT INTO `order` (`order_id`, `cost`, `currency`, `subtotal`, `discount`, `tax`, `promo_code`, `promo_terms`, `notes`, `delivery_type`, `delivery_address`, `phone`, `status`, `created_at`, `updated_at`, `deleted_at`) "+
+			"VALUES (UUID_TO_BIN(?), ?, ?, ?, ?, ?, NULLIF(?, ''), ?, ?, ?, ?, ?, ?, ?, ?, NULL)",
+			order.ID, order.Cost.Amount, order.Cost.Currency, order.Pricing.Subtotal.Amount, order.Pricing.Discount.Amount, order.Pricing.Tax.Amount, order.Pricing.PromoCode, promoTerms,
+			order.Notes, order.Delivery.Type, order.Delivery.Address, order.Delivery.Phone, order.Status, order.OrderedAt, order.OrderedAt)
 		if err != nil {
 			return closeTx(err)
 		}
 
 		for _, item := range order.MenuItems {
-			_, err = tx.ExecContext(ctx, "INSERT INTO order_item (order_id, menu_item_id, quantity) VALUES (UUID_TO_BIN(?), UUID_TO_BIN(?), ?)", order.ID, item.ID, item.Quantity)
+			err = upsertItem(ctx, tx, order.ID, item)
 			if err != nil {
 				return closeTx(err)
 			}
 		}
 
-		return closeTx(nil)
+		return closeTx(addAuditRecord(ctx, tx, order.ID, auditActionCreated, actor, nil, &order))
 	})
 }
 
-func (o *orderRepository) Update(order model.Order) error {
-	return o.withTx(func(tx *sql.Tx, ctx context.Context, closeTx func(error) error) error {
-		_, err := tx.ExecContext(ctx, "UPDATE `order` SET cost = ?, updated_at = NOW() WHERE BIN_TO_UUID(order_id) = ?", order.Cost, order.ID)
+func (o *orderRepository) Update(order model.Order, actor string) (int64, error) {
+	promoTerms, err := promoTermsJson(order.Pricing.Promo)
+	if err != nil {
+		return 0, err
+	}
+
+	var affected int64
+	err = o.withTx(func(tx *sql.Tx, ctx context.Context, closeTx func(error) error) error {
+		before, err := getOrder(ctx, tx, order.ID, true)
+		if err != nil || before == nil {
+			return closeTx(err)
+		}
+		// the status may have changed since the order was read, e.g. a payment has started
+		if !before.IsEditable() {
+			return closeTx(model.ErrOrderNotEditable)
+		}
+
+		res, err := tx.ExecContext(ctx, ""+
+			"UPDATE `order` SET cost = ?, currency = ?, subtotal = ?, discount = ?, tax = ?, promo_code = NULLIF(?, ''), promo_terms = ?, "+
+			"notes = ?, delivery_type = ?, delivery_address = ?, phone = ?, updated_at = NOW(), event_version = NULL "+
+			"WHERE deleted_at IS NULL AND BIN_TO_UUID(order_id) = ?",
+			order.Cost.Amount, order.Cost.Currency, order.Pricing.Subtotal.Amount, order.Pricing.Discount.Amount, order.Pricing.Tax.Amount, order.Pricing.PromoCode, promoTerms,
+			order.Notes, order.Delivery.Type, order.Delivery.Address, order.Delivery.Phone, order.ID)
 		if err != nil {
 			return closeTx(err)
 		}
 
-		_, err = tx.ExecContext(ctx, "DELETE FROM order_item WHERE BIN_TO_UUID(order_id) = ?", order.ID)
+		affected, err = res.RowsAffected()
+		if err != nil || affected == 0 {
+			return closeTx(err)
+		}
+
+		err = deleteMissingItems(ctx, tx, order)
 		if err != nil {
 			return closeTx(err)
 		}
 
 		for _, item := range order.MenuItems {
-			_, err = tx.ExecContext(ctx, "INSERT INTO order_item (order_id, menu_item_id, quantity) VALUES (UUID_TO_BIN(?), UUID_TO_BIN(?), ?)", order.ID, item.ID, item.Quantity)
+			err = upsertItem(ctx, tx, order.ID, item)
 			if err != nil {
 				return closeTx(err)
 			}
 		}
 
-		return closeTx(nil)
+		// creation time and status aren't changed by update
+		after := order
+		after.OrderedAt = before.OrderedAt
+		after.Status = before.Status
+
+		return closeTx(addAuditRecord(ctx, tx, order.ID, auditActionUpdated, actor, before, &after))
 	})
+
+	return affected, err
 }
 
-func (o *orderRepository) Delete(id uuid.UUID) error {
-	return o.withTx(func(tx *sql.Tx, ctx context.Context, closeTx func(error) error) error {
-		_, err := tx.ExecContext(ctx, "UPDATE `order` SET deleted_at = NOW() WHERE BIN_TO_UUID(order_id) = ?", id)
+func upsertItem(ctx context.Context, tx *sql.Tx, orderID uuid.UUID, item model.MenuItem) error {
+	modifiers, err := json.Marshal(item.Modifiers)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, ""+
+		"INSERT INTO order_item (order_id, menu_item_id, quantity, modifiers, comment) "+
+		"VALUES (UUID_TO_BIN(?), UUID_TO_BIN(?), ?, ?, ?) "+
+		"ON DUPLICATE KEY UPDATE quantity = VALUES(quantity), modifiers = VALUES(modifiers), comment = VALUES(comment)",
+		orderID, item.ID, item.Quantity, modifiers, item.Comment)
+
+	return err
+}
+
+func deleteMissingItems(ctx context.Context, tx *sql.Tx, order model.Order) error {
+	query := "DELETE FROM order_item WHERE BIN_TO_UUID(order_id) = ?"
+	args := []interface{}{order.ID}
+	if len(order.MenuItems) > 0 {
+		placeholders := make([]string, len(order.MenuItems))
+		for i, item := range order.MenuItems {
+			placeholders[i] = "?"
+			args = append(args, item.ID)
+		}
+		query += " AND BIN_TO_UUID(menu_item_id) NOT IN (" + strings.Join(placeholders, ", ") + ")"
+	}
+
+	_, err := tx.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (o *orderRepository) Delete(id uuid.UUID, actor string, beforeDelete func(model.Order) error) (int64, error) {
+	var affected int64
+	err := o.withTx(func(tx *sql.Tx, ctx context.Context, closeTx func(error) error) error {
+		before, err := getOrder(ctx, tx, id, true)
+		if err != nil || before == nil {
+			return closeTx(err)
+		}
 
-		return closeTx(err)
+		// the row stays locked, status can't change until the order is deleted
+		if err = beforeDelete(*before); err != nil {
+			return closeTx(err)
+		}
+
+		res, err := tx.ExecContext(ctx, "UPDATE `order` SET deleted_at = NOW(), event_version = NULL WHERE deleted_at IS NULL AND BIN_TO_UUID(order_id) = ?", id)
+		if err != nil {
+			return closeTx(err)
+		}
+
+		affected, err = res.RowsAffected()
+		if err != nil || affected == 0 {
+			return closeTx(err)
+		}
+
+		return closeTx(addAuditRecord(ctx, tx, id, auditActionDeleted, actor, before, nil))
 	})
+
+	return affected, err
+}
+
+func (o *orderRepository) UpdateStatus(id uuid.UUID, status model.OrderStatus, actor string) (int64, error) {
+	var affected int64
+	err := o.withTx(func(tx *sql.Tx, ctx context.Context, closeTx func(error) error) error {
+		before, err := getOrder(ctx, tx, id, true)
+		if err != nil || before == nil {
+			return closeTx(err)
+		}
+		if !before.Status.CanTransitTo(status) {
+			return closeTx(model.ErrInvalidStatusTransition)
+		}
+
+		res, err := tx.ExecContext(ctx, "UPDATE `order` SET status = ?, updated_at = NOW(), event_version = NULL WHERE deleted_at IS NULL AND BIN_TO_UUID(order_id) = ?", status, id)
+		if err != nil {
+			return closeTx(err)
+		}
+
+		affected, err = res.RowsAffected()
+		if err != nil || affected == 0 {
+			return closeTx(err)
+		}
+
+		after := *before
+		after.Status = status
+
+		return closeTx(addAuditRecord(ctx, tx, id, auditActionStatusChanged, actor, before, &after))
+	})
+
+	return affected, err
 }
 
 func NewOrderRepository(db *sql.DB) model.OrderRepository {
@@ -69,88 +198,152 @@ func NewOrderRepository(db *sql.DB) model.OrderRepository {
 }
 
 func (o *orderRepository) Get(id uuid.UUID) (*model.Order, error) {
-	rows, err := o.db.Query(""+
+	return getOrder(context.Background(), o.db, id, false)
+}
+
+// getOrder with forUpdate locks the order row until the end of transaction
+func getOrder(ctx context.Context, q queryer, id uuid.UUID, forUpdate bool) (*model.Order, error) {
+	lock := ""
+	if forUpdate {
+		lock = " FOR UPDATE"
+	}
+
+	rows, err := q.QueryContext(ctx, ""+
 		"SELECT "+
 		"BIN_TO_UUID(o.order_id) AS order_id, "+
 		"o.cost, "+
+		"o.currency, "+
+		"o.subtotal, "+
+		"o.discount, "+
+		"o.tax, "+
+		"IFNULL(o.promo_code, ''), "+
+		"o.promo_terms, "+
 		"o.created_at, "+
-		"IFNULL(GROUP_CONCAT(CONCAT(BIN_TO_UUID(oi.menu_item_id), '=', oi.quantity)), '') AS items "+
+		"o.notes, "+
+		"o.delivery_type, "+
+		"o.delivery_address, "+
+		"o.phone, "+
+		"o.status "+
 		"FROM `order` o "+
-		"LEFT JOIN order_item oi ON (o.order_id = oi.order_id) "+
-		"WHERE o.deleted_at IS NULL AND BIN_TO_UUID(o.order_id) = ? "+
-		"GROUP BY o.order_id", id)
+		"WHERE o.deleted_at IS NULL AND BIN_TO_UUID(o.order_id) = ?"+lock, id)
 
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	if rows.Next() {
-		order, err := parseOrder(rows)
-		if err != nil {
-			return nil, err
-		}
+	if !rows.Next() {
+		return nil, rows.Err() // not found
+	}
+
+	order, err := parseOrder(rows)
+	if err != nil {
+		return nil, err
+	}
+	rows.Close()
 
-		return order, nil
+	order.MenuItems, err = getMenuItems(ctx, q, id)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, nil // not found
+	return order, nil
 }
 
-func parseMenuItems(itemsStr string) ([]model.MenuItem, error) {
-	if len(itemsStr) == 0 {
-		return make([]model.MenuItem, 0), nil
+func (o *orderRepository) IsDeleted(id uuid.UUID) (bool, error) {
+	var deleted bool
+	err := o.db.QueryRow("SELECT deleted_at IS NOT NULL FROM `order` WHERE BIN_TO_UUID(order_id) = ?", id).Scan(&deleted)
+	if err == sql.ErrNoRows {
+		return false, nil
 	}
 
-	items := strings.Split(itemsStr, ",")
-	result := make([]model.MenuItem, len(items))
-	for i, pairStr := range items {
-		pair := strings.Split(pairStr, "=")
-		if len(pair) != 2 {
-			return nil, fmt.Errorf("invalid pair: %s", pairStr)
-		}
+	return deleted, err
+}
 
-		quantity, err := strconv.Atoi(pair[1])
-		if err != nil {
-			return nil, fmt.Errorf("invalid quantity: %s", pair[1])
-		}
+func getMenuItems(ctx context.Context, q queryer, orderID uuid.UUID) ([]model.MenuItem, error) {
+	rows, err := q.QueryContext(ctx, ""+
+		"SELECT BIN_TO_UUID(menu_item_id), quantity, IFNULL(modifiers, 'null'), comment "+
+		"FROM order_item "+
+		"WHERE BIN_TO_UUID(order_id) = ? "+
+		"ORDER BY menu_item_id", orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-		itemId, err := uuid.Parse(pair[0])
+	items := make([]model.MenuItem, 0)
+	for rows.Next() {
+		item, err := parseMenuItem(rows)
 		if err != nil {
 			return nil, err
 		}
 
-		result[i] = model.MenuItem{ID: itemId, Quantity: quantity}
+		items = append(items, *item)
+	}
+
+	return items, rows.Err()
+}
+
+func parseMenuItem(r *sql.Rows) (*model.MenuItem, error) {
+	var itemId string
+	var item model.MenuItem
+	var modifiers []byte
+
+	err := r.Scan(&itemId, &item.Quantity, &modifiers, &item.Comment)
+	if err != nil {
+		return nil, err
+	}
+
+	item.ID, err = uuid.Parse(itemId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(modifiers, &item.Modifiers)
+	if err != nil {
+		return nil, err
 	}
 
-	return result, nil
+	return &item, nil
 }
 
 func parseOrder(r *sql.Rows) (*model.Order, error) {
 	var orderId string
-	var cost int
+	var cost, subtotal, discount, tax int64
+	var currency string
+	var pricing model.Pricing
 	var createdAt time.Time
-	var items string
+	var delivery model.Delivery
+	var notes string
+	var status model.OrderStatus
+	var promoTerms []byte
 
-	err := r.Scan(&orderId, &cost, &createdAt, &items)
+	err := r.Scan(&orderId, &cost, &currency, &subtotal, &discount, &tax, &pricing.PromoCode, &promoTerms, &createdAt, &notes, &delivery.Type, &delivery.Address, &delivery.Phone, &status)
 	if err != nil {
 		return nil, err
 	}
 
-	orderUid, err := uuid.Parse(orderId)
+	pricing.Promo, err = parsePromoTerms(promoTerms)
 	if err != nil {
 		return nil, err
 	}
 
-	menuItems, err := parseMenuItems(items)
+	pricing.Subtotal = model.NewMoney(subtotal, currency)
+	pricing.Discount = model.NewMoney(discount, currency)
+	pricing.Tax = model.NewMoney(tax, currency)
+
+	orderUid, err := uuid.Parse(orderId)
 	if err != nil {
 		return nil, err
 	}
 
 	return &model.Order{
 		ID:        orderUid,
-		MenuItems: menuItems,
 		OrderedAt: createdAt,
-		Cost:      cost,
+		Cost:      model.NewMoney(cost, currency),
+		Notes:     notes,
+		Delivery:  delivery,
+		Pricing:   pricing,
+		Status:    status,
 	}, nil
 }
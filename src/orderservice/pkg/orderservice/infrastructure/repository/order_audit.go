@@ -0,0 +1,216 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/google/uuid"
+	"orderservice/pkg/orderservice/model"
+	"time"
+)
+
+const (
+	auditActionCreated       = "created"
+	auditActionUpdated       = "updated"
+	auditActionStatusChanged = "status_changed"
+	auditActionDeleted       = "deleted"
+)
+
+// OrderSnapshot is the state of an order stored in order_audit and order_event, amounts are in minor units,
+// the order history query reads the same format
+type OrderSnapshot struct {
+	MenuItems []SnapshotMenuItem `json:"menuItems"`
+	Currency  string             `json:"currency"`
+	Cost      int64              `json:"cost"`
+	Subtotal  int64              `json:"subtotal"`
+	Discount  int64              `json:"discount"`
+	Tax       int64              `json:"tax"`
+	PromoCode string             `json:"promoCode"`
+	Promo     *SnapshotPromo     `json:"promo,omitempty"`
+	Notes     string             `json:"notes"`
+	Delivery  SnapshotDelivery   `json:"delivery"`
+	Status    string             `json:"status"`
+}
+
+type SnapshotMenuItem struct {
+	ID        string   `json:"id"`
+	Quantity  int      `json:"quantity"`
+	Modifiers []string `json:"modifiers"`
+	Comment   string   `json:"comment"`
+}
+
+type SnapshotDelivery struct {
+	Type    string `json:"type"`
+	Address string `json:"address"`
+	Phone   string `json:"phone"`
+}
+
+// SnapshotPromo is the format of promo terms in snapshots and in promo_terms column of orders
+type SnapshotPromo struct {
+	Type         string  `json:"type"`
+	Value        int     `json:"value"`
+	Currency     string  `json:"currency,omitempty"`
+	BuyQuantity  int     `json:"buyQuantity"`
+	FreeQuantity int     `json:"freeQuantity"`
+	MenuItemID   *string `json:"menuItemId"`
+}
+
+func newSnapshotPromo(t *model.PromoTerms) *SnapshotPromo {
+	if t == nil {
+		return nil
+	}
+
+	p := SnapshotPromo{Type: string(t.Type), Value: t.Value, Currency: t.Currency, BuyQuantity: t.BuyQuantity, FreeQuantity: t.FreeQuantity}
+	if t.MenuItemID != nil {
+		itemId := t.MenuItemID.String()
+		p.MenuItemID = &itemId
+	}
+
+	return &p
+}
+
+func (p *SnapshotPromo) toTerms() (*model.PromoTerms, error) {
+	if p == nil {
+		return nil, nil
+	}
+
+	t := model.PromoTerms{Type: model.PromoCodeType(p.Type), Value: p.Value, Currency: p.Currency, BuyQuantity: p.BuyQuantity, FreeQuantity: p.FreeQuantity}
+	if p.MenuItemID != nil {
+		itemId, err := uuid.Parse(*p.MenuItemID)
+		if err != nil {
+			return nil, err
+		}
+		t.MenuItemID = &itemId
+	}
+
+	return &t, nil
+}
+
+// promoTermsJson is the value of promo_terms column, NULL for orders without terms
+func promoTermsJson(t *model.PromoTerms) (interface{}, error) {
+	if t == nil {
+		return nil, nil
+	}
+
+	return json.Marshal(newSnapshotPromo(t))
+}
+
+func parsePromoTerms(b []byte) (*model.PromoTerms, error) {
+	if b == nil {
+		return nil, nil
+	}
+
+	var p SnapshotPromo
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, err
+	}
+
+	return p.toTerms()
+}
+
+func (s OrderSnapshot) toOrder(id uuid.UUID) (*model.Order, error) {
+	items := make([]model.MenuItem, len(s.MenuItems))
+	for i, item := range s.MenuItems {
+		itemId, err := uuid.Parse(item.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		items[i] = model.MenuItem{ID: itemId, Quantity: item.Quantity, Modifiers: item.Modifiers, Comment: item.Comment}
+	}
+
+	promo, err := s.Promo.toTerms()
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Order{
+		ID:        id,
+		MenuItems: items,
+		Cost:      model.NewMoney(s.Cost, s.Currency),
+		Notes:     s.Notes,
+		Delivery:  model.Delivery{Type: model.DeliveryType(s.Delivery.Type), Address: s.Delivery.Address, Phone: s.Delivery.Phone},
+		Pricing: model.Pricing{
+			Subtotal:  model.NewMoney(s.Subtotal, s.Currency),
+			Discount:  model.NewMoney(s.Discount, s.Currency),
+			Tax:       model.NewMoney(s.Tax, s.Currency),
+			PromoCode: s.PromoCode,
+			Promo:     promo,
+		},
+		Status: model.OrderStatus(s.Status),
+	}, nil
+}
+
+// ParseOrderSnapshot returns nil for a missing snapshot, like before of created orders
+func ParseOrderSnapshot(b []byte) (*OrderSnapshot, error) {
+	if b == nil {
+		return nil, nil
+	}
+
+	var s OrderSnapshot
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+func newOrderSnapshot(o *model.Order) ([]byte, error) {
+	if o == nil {
+		return nil, nil
+	}
+
+	items := make([]SnapshotMenuItem, len(o.MenuItems))
+	for i, item := range o.MenuItems {
+		items[i] = SnapshotMenuItem{ID: item.ID.String(), Quantity: item.Quantity, Modifiers: item.Modifiers, Comment: item.Comment}
+	}
+
+	return json.Marshal(OrderSnapshot{
+		MenuItems: items,
+		Currency:  o.Cost.Currency,
+		Cost:      o.Cost.Amount,
+		Subtotal:  o.Pricing.Subtotal.Amount,
+		Discount:  o.Pricing.Discount.Amount,
+		Tax:       o.Pricing.Tax.Amount,
+		PromoCode: o.Pricing.PromoCode,
+		Promo:     newSnapshotPromo(o.Pricing.Promo),
+		Notes:     o.Notes,
+		Delivery:  SnapshotDelivery{Type: string(o.Delivery.Type), Address: o.Delivery.Address, Phone: o.Delivery.Phone},
+		Status:    string(o.Status),
+	})
+}
+
+// addAuditRecord appends the next revision of the order, it must run in the transaction of the change
+// after the order row is locked so concurrent changes get sequential revisions
+func addAuditRecord(ctx context.Context, tx *sql.Tx, orderID uuid.UUID, action, actor string, before, after *model.Order) error {
+	beforeJson, err := newOrderSnapshot(before)
+	if err != nil {
+		return err
+	}
+
+	afterJson, err := newOrderSnapshot(after)
+	if err != nil {
+		return err
+	}
+
+	var revision int
+	err = tx.QueryRowContext(ctx, "SELECT IFNULL(MAX(revision), 0) + 1 FROM order_audit WHERE order_id = UUID_TO_BIN(?)", orderID).Scan(&revision)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, ""+
+		"INSERT INTO order_audit (audit_id, order_id, revision, action, actor, `before`, `after`, created_at) "+
+		"VALUES (UUID_TO_BIN(?), UUID_TO_BIN(?), ?, ?, ?, ?, ?, ?)",
+		uuid.New(), orderID, revision, action, actor, nullableJson(beforeJson), nullableJson(afterJson), time.Now())
+
+	return err
+}
+
+func nullableJson(b []byte) interface{} {
+	if b == nil {
+		return nil
+	}
+
+	return b
+}
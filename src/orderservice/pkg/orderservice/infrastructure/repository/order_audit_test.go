@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"orderservice/pkg/orderservice/model"
+	"reflect"
+	"testing"
+)
+
+func TestOrderSnapshotKeepsPromoTerms(t *testing.T) {
+	itemId := uuid.New()
+	o := &model.Order{
+		ID:        uuid.New(),
+		MenuItems: []model.MenuItem{{ID: itemId, Quantity: 3}},
+		Pricing: model.Pricing{
+			PromoCode: "SPRING",
+			Promo:     &model.PromoTerms{Type: model.PromoCodeTypeBuyXGetY, BuyQuantity: 2, FreeQuantity: 1, MenuItemID: &itemId},
+		},
+	}
+
+	b, err := newOrderSnapshot(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := ParseOrderSnapshot(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := s.toOrder(o.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(restored.Pricing.Promo, o.Pricing.Promo) {
+		t.Errorf("Have: %v, want: %v", restored.Pricing.Promo, o.Pricing.Promo)
+	}
+
+	terms, err := promoTermsJson(nil)
+	if err != nil || terms != nil {
+		t.Errorf("Order without terms must have NULL promo_terms. Have: %v", terms)
+	}
+}
@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"orderservice/pkg/orderservice/model"
+	"time"
+)
+
+// maxAppendAttempts bounds retries of changes which don't depend on the state read by the caller
+const maxAppendAttempts = 3
+
+// eventSourcedOrderRepository stores orders as events, the order tables read by
+// OrderQueryService are kept as a projection updated in the same transaction
+type eventSourcedOrderRepository struct {
+	db *sql.DB
+}
+
+func NewEventSourcedOrderRepository(db *sql.DB) model.OrderRepository {
+	return &eventSourcedOrderRepository{db: db}
+}
+
+func (r *eventSourcedOrderRepository) load(id uuid.UUID) (*model.OrderAggregate, error) {
+	events, err := loadOrderEvents(context.Background(), r.db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return model.RebuildOrder(events)
+}
+
+func (r *eventSourcedOrderRepository) Get(id uuid.UUID) (*model.Order, error) {
+	a, err := r.load(id)
+	if err != nil || a == nil || a.IsDeleted() {
+		return nil, err
+	}
+
+	return &a.Order, nil
+}
+
+func (r *eventSourcedOrderRepository) IsDeleted(id uuid.UUID) (bool, error) {
+	a, err := r.load(id)
+	if err != nil || a == nil {
+		return false, err
+	}
+
+	return a.IsDeleted(), nil
+}
+
+func (r *eventSourcedOrderRepository) Add(order model.Order, actor string) error {
+	return r.commit(&model.OrderAggregate{}, model.OrderEvent{
+		OrderID:    order.ID,
+		Type:       model.OrderEventCreated,
+		Actor:      actor,
+		OccurredAt: order.OrderedAt,
+		Order:      &order,
+	}, auditActionCreated)
+}
+
+// Update fails with model.ErrConcurrentModification if the order has changed since it was read
+func (r *eventSourcedOrderRepository) Update(order model.Order, actor string) (int64, error) {
+	a, err := r.load(order.ID)
+	if err != nil || a == nil || a.IsDeleted() {
+		return 0, err
+	}
+
+	if a.Order.Version != order.Version {
+		return 0, model.ErrConcurrentModification
+	}
+	if !a.Order.IsEditable() {
+		return 0, model.ErrOrderNotEditable
+	}
+
+	err = r.commit(a, model.OrderEvent{
+		OrderID:    order.ID,
+		Type:       model.OrderEventUpdated,
+		Actor:      actor,
+		OccurredAt: time.Now(),
+		Order:      &order,
+	}, auditActionUpdated)
+	if err != nil {
+		return 0, err
+	}
+
+	return 1, nil
+}
+
+func (r *eventSourcedOrderRepository) Delete(id uuid.UUID, actor string, beforeDelete func(model.Order) error) (int64, error) {
+	return r.retry(id, func(a *model.OrderAggregate) error {
+		// a change made meanwhile fails the commit, the next attempt checks the changed order again
+		if err := beforeDelete(a.Order); err != nil {
+			return err
+		}
+
+		return r.commit(a, model.OrderEvent{
+			OrderID:    id,
+			Type:       model.OrderEventDeleted,
+			Actor:      actor,
+			OccurredAt: time.Now(),
+		}, auditActionDeleted)
+	})
+}
+
+func (r *eventSourcedOrderRepository) UpdateStatus(id uuid.UUID, status model.OrderStatus, actor string) (int64, error) {
+	return r.retry(id, func(a *model.OrderAggregate) error {
+		if !a.Order.Status.CanTransitTo(status) {
+			return model.ErrInvalidStatusTransition
+		}
+
+		return r.commit(a, model.OrderEvent{
+			OrderID:    id,
+			Type:       model.OrderEventStatusChanged,
+			Actor:      actor,
+			OccurredAt: time.Now(),
+			Status:     status,
+		}, auditActionStatusChanged)
+	})
+}
+
+// retry applies change to the latest state until it doesn't conflict with another one
+func (r *eventSourcedOrderRepository) retry(id uuid.UUID, change func(*model.OrderAggregate) error) (int64, error) {
+	var err error
+	for i := 0; i < maxAppendAttempts; i++ {
+		var a *model.OrderAggregate
+		a, err = r.load(id)
+		if err != nil || a == nil || a.IsDeleted() {
+			return 0, err
+		}
+
+		err = change(a)
+		if err != model.ErrConcurrentModification {
+			break
+		}
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return 1, nil
+}
+
+// commit applies event to the aggregate, then stores the event, projection and audit record together
+func (r *eventSourcedOrderRepository) commit(a *model.OrderAggregate, e model.OrderEvent, auditAction string) error {
+	var before *model.Order
+	if a.Order.Version > 0 {
+		state := a.Order
+		before = &state
+	}
+
+	expectedVersion := a.Order.Version
+	e.Version = expectedVersion + 1
+	if err := a.Apply(e); err != nil {
+		return err
+	}
+
+	var after *model.Order
+	if !a.IsDeleted() {
+		after = &a.Order
+	}
+
+	return withTx(r.db, func(tx *sql.Tx, ctx context.Context, closeTx func(error) error) error {
+		err := appendOrderEvents(ctx, tx, expectedVersion, e)
+		if err != nil {
+			return closeTx(err)
+		}
+
+		err = projectOrder(ctx, tx, a)
+		if err != nil {
+			return closeTx(err)
+		}
+
+		return closeTx(addAuditRecord(ctx, tx, e.OrderID, auditAction, e.Actor, before, after))
+	})
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
+	"orderservice/pkg/orderservice/model"
+	"time"
+)
+
+const mysqlDuplicateEntry = 1062
+
+type statusPayload struct {
+	Status string `json:"status"`
+}
+
+// appendOrderEvents relies on unique aggregate version, a concurrent append of the same
+// version waits for the other transaction and fails with duplicate entry
+func appendOrderEvents(ctx context.Context, tx *sql.Tx, expectedVersion int, events ...model.OrderEvent) error {
+	for i, e := range events {
+		payload, err := encodeOrderEvent(e)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, ""+
+			"INSERT INTO order_event (aggregate_id, version, event_type, payload, actor, occurred_at) "+
+			"VALUES (UUID_TO_BIN(?), ?, ?, ?, ?, ?)",
+			e.OrderID, expectedVersion+i+1, e.Type, payload, e.Actor, e.OccurredAt)
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == mysqlDuplicateEntry {
+			return model.ErrConcurrentModification
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func encodeOrderEvent(e model.OrderEvent) ([]byte, error) {
+	switch e.Type {
+	case model.OrderEventCreated, model.OrderEventUpdated:
+		return newOrderSnapshot(e.Order)
+	case model.OrderEventStatusChanged:
+		return json.Marshal(statusPayload{Status: string(e.Status)})
+	default:
+		return []byte("{}"), nil
+	}
+}
+
+func loadOrderEvents(ctx context.Context, q queryer, orderID uuid.UUID) ([]model.OrderEvent, error) {
+	rows, err := q.QueryContext(ctx, ""+
+		"SELECT version, event_type, payload, actor, occurred_at "+
+		"FROM order_event "+
+		"WHERE aggregate_id = UUID_TO_BIN(?) "+
+		"ORDER BY version", orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]model.OrderEvent, 0)
+	for rows.Next() {
+		e, err := parseOrderEvent(rows, orderID)
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, *e)
+	}
+
+	return events, rows.Err()
+}
+
+func parseOrderEvent(r *sql.Rows, orderID uuid.UUID) (*model.OrderEvent, error) {
+	var payload []byte
+	var occurredAt time.Time
+	e := model.OrderEvent{OrderID: orderID}
+
+	err := r.Scan(&e.Version, &e.Type, &payload, &e.Actor, &occurredAt)
+	if err != nil {
+		return nil, err
+	}
+	e.OccurredAt = occurredAt
+
+	// status events have only the status field of a snapshot
+	var snapshot OrderSnapshot
+	if err = json.Unmarshal(payload, &snapshot); err != nil {
+		return nil, err
+	}
+
+	switch e.Type {
+	case model.OrderEventCreated, model.OrderEventUpdated:
+		e.Order, err = snapshot.toOrder(orderID)
+		if err != nil {
+			return nil, err
+		}
+		e.Order.OrderedAt = occurredAt
+	case model.OrderEventStatusChanged:
+		e.Status = model.OrderStatus(snapshot.Status)
+	}
+
+	return &e, nil
+}
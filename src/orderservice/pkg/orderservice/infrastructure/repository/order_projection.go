@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"orderservice/pkg/orderservice/model"
+	"time"
+)
+
+// projectOrder writes the aggregate state to the order tables read by OrderQueryService,
+// event_version tells which event the row is projected from
+func projectOrder(ctx context.Context, tx *sql.Tx, a *model.OrderAggregate) error {
+	order := a.Order
+	promoTerms, err := promoTermsJson(order.Pricing.Promo)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, ""+
+		"INSERT INTO `order` (`order_id`, `cost`, `currency`, `subtotal`, `discount`, `tax`, `promo_code`, `promo_terms`, `notes`, `delivery_type`, `delivery_address`, `phone`, `status`, `created_at`, `updated_at`, `deleted_at`, `event_version`) "+
+		"VALUES (UUID_TO_BIN(?), ?, ?, ?, ?, ?, NULLIF(?, ''), ?, ?, ?, ?, ?, ?, ?, NOW(), ?, ?) "+
+		"ON DUPLICATE KEY UPDATE cost = VALUES(cost), currency = VALUES(currency), subtotal = VALUES(subtotal), "+
+		"discount = VALUES(discount), tax = VALUES(tax), promo_code = VALUES(promo_code), promo_terms = VALUES(promo_terms), notes = VALUES(notes), "+
+		"delivery_type = VALUES(delivery_type), delivery_address = VALUES(delivery_address), phone = VALUES(phone), "+
+		"status = VALUES(status), updated_at = NOW(), deleted_at = VALUES(deleted_at), event_version = VALUES(event_version)",
+		order.ID, order.Cost.Amount, order.Cost.Currency, order.Pricing.Subtotal.Amount, order.Pricing.Discount.Amount, order.Pricing.Tax.Amount, order.Pricing.PromoCode, promoTerms,
+		order.Notes, order.Delivery.Type, order.Delivery.Address, order.Delivery.Phone, order.Status, order.OrderedAt, a.DeletedAt, order.Version)
+	if err != nil {
+		return err
+	}
+
+	err = deleteMissingItems(ctx, tx, order)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range order.MenuItems {
+		err = upsertItem(ctx, tx, order.ID, item)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lockProjection returns the event version of the order row, found is false without the row
+// and version is nil when the row was last written without event sourcing
+func lockProjection(ctx context.Context, tx *sql.Tx, id uuid.UUID) (version *int, deleted bool, found bool, err error) {
+	err = tx.QueryRowContext(ctx,
+		"SELECT event_version, deleted_at IS NOT NULL FROM `order` WHERE order_id = UUID_TO_BIN(?) FOR UPDATE", id).
+		Scan(&version, &deleted)
+	if err == sql.ErrNoRows {
+		return nil, false, false, nil
+	}
+
+	return version, deleted, err == nil, err
+}
+
+// RebuildOrderProjection replays events of every order into the order tables. Orders without
+// events and rows changed without event sourcing are left untouched, events are older than
+// such rows, so ImportOrderEvents must run first. It returns the number of rebuilt orders.
+func RebuildOrderProjection(db *sql.DB) (int, error) {
+	ids, err := queryIds(db, "SELECT DISTINCT BIN_TO_UUID(aggregate_id) FROM order_event")
+	if err != nil {
+		return 0, err
+	}
+
+	rebuilt := 0
+	for _, id := range ids {
+		projected := false
+		err = withTx(db, func(tx *sql.Tx, ctx context.Context, closeTx func(error) error) error {
+			version, _, found, err := lockProjection(ctx, tx, id)
+			if err != nil || (found && version == nil) {
+				return closeTx(err)
+			}
+
+			events, err := loadOrderEvents(ctx, tx, id)
+			if err != nil {
+				return closeTx(err)
+			}
+
+			a, err := model.RebuildOrder(events)
+			if err != nil || a == nil {
+				return closeTx(err)
+			}
+
+			projected = true
+			return closeTx(projectOrder(ctx, tx, a))
+		})
+		if err != nil {
+			return rebuilt, err
+		}
+
+		if !projected {
+			log.WithFields(log.Fields{"orderId": id}).Warn("order changed without events is skipped, import it first")
+			continue
+		}
+
+		rebuilt++
+		log.WithFields(log.Fields{"orderId": id}).Debug("order projection rebuilt")
+	}
+
+	return rebuilt, nil
+}
+
+// ImportOrderEvents appends events from the state of orders written without event sourcing:
+// orders stored before it was enabled get the created event, orders changed while it was
+// switched off get events up to their current state. Deleted orders without events aren't imported.
+// It returns the number of imported orders.
+func ImportOrderEvents(db *sql.DB, actor string) (int, error) {
+	ids, err := queryIds(db, "SELECT BIN_TO_UUID(order_id) FROM `order` WHERE event_version IS NULL")
+	if err != nil {
+		return 0, err
+	}
+
+	imported := 0
+	for _, id := range ids {
+		appended := false
+		err = withTx(db, func(tx *sql.Tx, ctx context.Context, closeTx func(error) error) error {
+			version, deleted, found, err := lockProjection(ctx, tx, id)
+			if err != nil || !found || version != nil {
+				return closeTx(err) // projected by the running service meanwhile
+			}
+
+			events, err := loadOrderEvents(ctx, tx, id)
+			if err != nil {
+				return closeTx(err)
+			}
+
+			a, err := model.RebuildOrder(events)
+			if err != nil {
+				return closeTx(err)
+			}
+
+			var order *model.Order
+			if !deleted {
+				order, err = getOrder(ctx, tx, id, false)
+				if err != nil {
+					return closeTx(err)
+				}
+			}
+
+			expectedVersion := len(events)
+			missing := missingOrderEvents(a, id, order, actor)
+			if a == nil && len(missing) == 0 {
+				return closeTx(nil)
+			}
+
+			err = appendOrderEvents(ctx, tx, expectedVersion, missing...)
+			if err != nil {
+				return closeTx(err)
+			}
+
+			appended = len(missing) > 0
+			_, err = tx.ExecContext(ctx, "UPDATE `order` SET event_version = ? WHERE order_id = UUID_TO_BIN(?)",
+				expectedVersion+len(missing), id)
+			return closeTx(err)
+		})
+		if err == model.ErrConcurrentModification {
+			continue // imported by the running service meanwhile
+		}
+		if err != nil {
+			return imported, err
+		}
+
+		if appended {
+			imported++
+		}
+	}
+
+	return imported, nil
+}
+
+// missingOrderEvents returns events bringing the aggregate to the row state, nil order is a deleted row
+func missingOrderEvents(a *model.OrderAggregate, id uuid.UUID, order *model.Order, actor string) []model.OrderEvent {
+	now := time.Now()
+	switch {
+	case a == nil && order == nil:
+		return nil
+	case a == nil:
+		return []model.OrderEvent{{OrderID: id, Type: model.OrderEventCreated, Actor: actor, OccurredAt: order.OrderedAt, Order: order}}
+	case a.IsDeleted():
+		return nil
+	case order == nil:
+		return []model.OrderEvent{{OrderID: id, Type: model.OrderEventDeleted, Actor: actor, OccurredAt: now}}
+	}
+
+	events := []model.OrderEvent{{OrderID: id, Type: model.OrderEventUpdated, Actor: actor, OccurredAt: now, Order: order}}
+	if order.Status != a.Order.Status {
+		events = append(events, model.OrderEvent{OrderID: id, Type: model.OrderEventStatusChanged, Actor: actor, OccurredAt: now, Status: order.Status})
+	}
+
+	return events
+}
+
+func queryIds(db *sql.DB, query string) ([]uuid.UUID, error) {
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var value string
+		if err = rows.Scan(&value); err != nil {
+			return nil, err
+		}
+
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
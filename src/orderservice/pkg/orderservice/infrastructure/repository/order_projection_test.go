@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"orderservice/pkg/orderservice/model"
+	"testing"
+	"time"
+)
+
+func TestMissingOrderEvents(t *testing.T) {
+	id := uuid.New()
+	deletedAt := time.Now()
+	stored := &model.OrderAggregate{Order: model.Order{ID: id, Status: model.OrderStatusCreated, Version: 2}}
+	row := &model.Order{ID: id, Notes: "changed", Status: model.OrderStatusPaid}
+
+	cases := []struct {
+		name      string
+		aggregate *model.OrderAggregate
+		order     *model.Order
+		want      []model.OrderEventType
+	}{
+		{"deleted without events", nil, nil, nil},
+		{"without events", nil, row, []model.OrderEventType{model.OrderEventCreated}},
+		{"deleted in events", &model.OrderAggregate{Order: stored.Order, DeletedAt: &deletedAt}, nil, nil},
+		{"deleted row", stored, nil, []model.OrderEventType{model.OrderEventDeleted}},
+		{"changed row", stored, row, []model.OrderEventType{model.OrderEventUpdated, model.OrderEventStatusChanged}},
+	}
+
+	for _, c := range cases {
+		events := missingOrderEvents(c.aggregate, id, c.order, "import")
+		types := make([]model.OrderEventType, 0, len(events))
+		for _, e := range events {
+			types = append(types, e.Type)
+		}
+		if len(types) != len(c.want) {
+			t.Errorf("Events of %s are wrong. Have: %v, want: %v", c.name, types, c.want)
+			continue
+		}
+		for i := range types {
+			if types[i] != c.want[i] {
+				t.Errorf("Events of %s are wrong. Have: %v, want: %v", c.name, types, c.want)
+			}
+		}
+	}
+
+	a := *stored
+	for i, e := range missingOrderEvents(stored, id, row, "import") {
+		e.Version = stored.Order.Version + i + 1
+		if err := a.Apply(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if a.Order.Notes != row.Notes || a.Order.Status != row.Status {
+		t.Errorf("Imported state is wrong. Have: %s %s, want: %s %s", a.Order.Notes, a.Order.Status, row.Notes, row.Status)
+	}
+}
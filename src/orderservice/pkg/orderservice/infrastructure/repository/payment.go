@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"database/sql"
+	"github.com/google/uuid"
+	"orderservice/pkg/orderservice/model"
+	"time"
+)
+
+const selectPayments = "" +
+	"SELECT BIN_TO_UUID(payment_id), BIN_TO_UUID(order_id), provider, provider_ref, amount, currency, status, created_at " +
+	"FROM payment "
+
+type paymentRepository struct {
+	db *sql.DB
+}
+
+func NewPaymentRepository(db *sql.DB) model.PaymentRepository {
+	return &paymentRepository{db: db}
+}
+
+func (p *paymentRepository) Add(payment model.Payment) error {
+	_, err := p.db.Exec(""+
+		"INSERT INTO payment (payment_id, order_id, provider, provider_ref, amount, currency, status, created_at, updated_at) "+
+		"VALUES (UUID_TO_BIN(?), UUID_TO_BIN(?), ?, ?, ?, ?, ?, ?, ?)",
+		payment.ID, payment.OrderID, payment.Provider, payment.ProviderRef, payment.Amount.Amount, payment.Amount.Currency,
+		payment.Status, payment.CreatedAt, payment.CreatedAt)
+
+	return err
+}
+
+func (p *paymentRepository) Update(payment model.Payment, previous model.PaymentStatus) (int64, error) {
+	res, err := p.db.Exec(""+
+		"UPDATE payment SET provider_ref = ?, status = ?, updated_at = NOW() "+
+		"WHERE BIN_TO_UUID(payment_id) = ? AND status = ?",
+		payment.ProviderRef, payment.Status, payment.ID, previous)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func (p *paymentRepository) Get(id uuid.UUID) (*model.Payment, error) {
+	payments, err := p.query(selectPayments+"WHERE BIN_TO_UUID(payment_id) = ?", id)
+	if err != nil || len(payments) == 0 {
+		return nil, err
+	}
+
+	return &payments[0], nil
+}
+
+func (p *paymentRepository) GetByProviderRef(provider string, ref string) (*model.Payment, error) {
+	payments, err := p.query(selectPayments+"WHERE provider = ? AND provider_ref = ?", provider, ref)
+	if err != nil || len(payments) == 0 {
+		return nil, err
+	}
+
+	return &payments[0], nil
+}
+
+func (p *paymentRepository) FindByOrder(orderID uuid.UUID) ([]model.Payment, error) {
+	return p.query(selectPayments+"WHERE BIN_TO_UUID(order_id) = ? ORDER BY created_at", orderID)
+}
+
+func (p *paymentRepository) query(query string, args ...interface{}) ([]model.Payment, error) {
+	rows, err := p.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	payments := make([]model.Payment, 0)
+	for rows.Next() {
+		payment, err := parsePayment(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		payments = append(payments, *payment)
+	}
+
+	return payments, rows.Err()
+}
+
+func parsePayment(r *sql.Rows) (*model.Payment, error) {
+	var paymentId, orderId, currency string
+	var amount int64
+	var createdAt time.Time
+	var payment model.Payment
+
+	err := r.Scan(&paymentId, &orderId, &payment.Provider, &payment.ProviderRef, &amount, &currency, &payment.Status, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+
+	payment.ID, err = uuid.Parse(paymentId)
+	if err != nil {
+		return nil, err
+	}
+
+	payment.OrderID, err = uuid.Parse(orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	payment.Amount = model.NewMoney(amount, currency)
+	payment.CreatedAt = createdAt
+
+	return &payment, nil
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"database/sql"
+	"github.com/google/uuid"
+	"orderservice/pkg/orderservice/model"
+)
+
+type promoCodeRepository struct {
+	db *sql.DB
+}
+
+func NewPromoCodeRepository(db *sql.DB) model.PromoCodeRepository {
+	return &promoCodeRepository{db: db}
+}
+
+func nullableUUID(id *uuid.UUID) interface{} {
+	if id == nil {
+		return nil
+	}
+
+	return id.String()
+}
+
+func (p *promoCodeRepository) Add(promo model.PromoCode) error {
+	_, err := p.db.Exec(""+
+		"INSERT INTO promo_code (code, type, value, currency, buy_quantity, free_quantity, menu_item_id, valid_from, valid_to, usage_limit, used_count, created_at, updated_at) "+
+		"VALUES (?, ?, ?, NULLIF(?, ''), ?, ?, UUID_TO_BIN(?), ?, ?, ?, 0, NOW(), NOW())",
+		promo.Code, promo.Type, promo.Value, promo.Currency, promo.BuyQuantity, promo.FreeQuantity, nullableUUID(promo.MenuItemID),
+		promo.ValidFrom, promo.ValidTo, promo.UsageLimit)
+
+	return err
+}
+
+func (p *promoCodeRepository) Update(promo model.PromoCode) (int64, error) {
+	res, err := p.db.Exec(""+
+		"UPDATE promo_code SET type = ?, value = ?, currency = NULLIF(?, ''), buy_quantity = ?, free_quantity = ?, menu_item_id = UUID_TO_BIN(?), "+
+		"valid_from = ?, valid_to = ?, usage_limit = ?, updated_at = NOW() "+
+		"WHERE code = ?",
+		promo.Type, promo.Value, promo.Currency, promo.BuyQuantity, promo.FreeQuantity, nullableUUID(promo.MenuItemID),
+		promo.ValidFrom, promo.ValidTo, promo.UsageLimit, promo.Code)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func (p *promoCodeRepository) Delete(code string) (int64, error) {
+	res, err := p.db.Exec("DELETE FROM promo_code WHERE code = ?", code)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func (p *promoCodeRepository) Get(code string) (*model.PromoCode, error) {
+	var promo model.PromoCode
+	var menuItemId sql.NullString
+	var validFrom, validTo sql.NullTime
+
+	err := p.db.QueryRow(""+
+		"SELECT code, type, value, IFNULL(currency, ''), buy_quantity, free_quantity, BIN_TO_UUID(menu_item_id), valid_from, valid_to, usage_limit, used_count "+
+		"FROM promo_code WHERE code = ?", code).
+		Scan(&promo.Code, &promo.Type, &promo.Value, &promo.Currency, &promo.BuyQuantity, &promo.FreeQuantity, &menuItemId, &validFrom, &validTo, &promo.UsageLimit, &promo.UsedCount)
+	if err == sql.ErrNoRows {
+		return nil, nil // not found
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if menuItemId.Valid {
+		itemId, err := uuid.Parse(menuItemId.String)
+		if err != nil {
+			return nil, err
+		}
+		promo.MenuItemID = &itemId
+	}
+	if validFrom.Valid {
+		promo.ValidFrom = &validFrom.Time
+	}
+	if validTo.Valid {
+		promo.ValidTo = &validTo.Time
+	}
+
+	return &promo, nil
+}
+
+func (p *promoCodeRepository) Redeem(code string) (int64, error) {
+	res, err := p.db.Exec(""+
+		"UPDATE promo_code SET used_count = used_count + 1 "+
+		"WHERE code = ? AND (usage_limit = 0 OR used_count < usage_limit) "+
+		"AND (valid_from IS NULL OR valid_from <= NOW()) AND (valid_to IS NULL OR valid_to > NOW())", code)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func (p *promoCodeRepository) Release(code string) error {
+	_, err := p.db.Exec("UPDATE promo_code SET used_count = used_count - 1 WHERE code = ? AND used_count > 0", code)
+	return err
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// encryptedSecretPrefix marks sealed values, values without it are stored before encryption was added
+const encryptedSecretPrefix = "enc:v1:"
+
+const SecretKeySize = 32
+
+var ErrSecretKeyRequired = errors.New("secret key is not configured")
+
+// SecretCipher encrypts secrets at rest with AES-GCM, nil SecretCipher can't seal secrets
+type SecretCipher struct {
+	aead cipher.AEAD
+}
+
+func NewSecretCipher(key []byte) (*SecretCipher, error) {
+	if len(key) != SecretKeySize {
+		return nil, fmt.Errorf("secret key must be %d bytes, have %d", SecretKeySize, len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SecretCipher{aead: aead}, nil
+}
+
+func (c *SecretCipher) Seal(secret string) (string, error) {
+	if c == nil {
+		return "", ErrSecretKeyRequired
+	}
+
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, []byte(secret), nil)
+	return encryptedSecretPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Open returns plaintext secrets stored before encryption as is
+func (c *SecretCipher) Open(stored string) (string, error) {
+	if !IsSealedSecret(stored) {
+		return stored, nil
+	}
+
+	if c == nil {
+		return "", ErrSecretKeyRequired
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(stored[len(encryptedSecretPrefix):])
+	if err != nil {
+		return "", err
+	}
+
+	nonceSize := c.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return "", errors.New("sealed secret is too short")
+	}
+
+	secret, err := c.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(secret), nil
+}
+
+func IsSealedSecret(stored string) bool {
+	return strings.HasPrefix(stored, encryptedSecretPrefix)
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSecretCipher(t *testing.T) {
+	c, err := NewSecretCipher(bytes.Repeat([]byte{7}, SecretKeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, err := c.Seal("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !IsSealedSecret(sealed) || bytes.Contains([]byte(sealed), []byte("secret")) {
+		t.Errorf("Secret isn't sealed: %s", sealed)
+	}
+
+	if opened, err := c.Open(sealed); err != nil || opened != "secret" {
+		t.Errorf("Opened secret is wrong. Have: %s, %v, want: secret", opened, err)
+	}
+
+	if opened, err := c.Open("legacy"); err != nil || opened != "legacy" {
+		t.Errorf("Plaintext secret is wrong. Have: %s, %v, want: legacy", opened, err)
+	}
+
+	other, _ := NewSecretCipher(bytes.Repeat([]byte{8}, SecretKeySize))
+	if _, err = other.Open(sealed); err == nil {
+		t.Errorf("Secret is opened with another key")
+	}
+
+	var missing *SecretCipher
+	if _, err = missing.Seal("secret"); err != ErrSecretKeyRequired {
+		t.Errorf("Seal without key is wrong. Have: %v, want: %v", err, ErrSecretKeyRequired)
+	}
+}
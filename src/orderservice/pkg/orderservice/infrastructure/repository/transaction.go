@@ -6,9 +6,9 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-func (o *orderRepository) withTx(fn func(*sql.Tx, context.Context, func(error) error) error) error {
+func withTx(db *sql.DB, fn func(*sql.Tx, context.Context, func(error) error) error) error {
 	ctx := context.Background()
-	tx, err := o.db.BeginTx(ctx, nil)
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
@@ -24,3 +24,7 @@ func (o *orderRepository) withTx(fn func(*sql.Tx, context.Context, func(error) e
 
 	return fn(tx, ctx, closeTx)
 }
+
+func (o *orderRepository) withTx(fn func(*sql.Tx, context.Context, func(error) error) error) error {
+	return withTx(o.db, fn)
+}
@@ -0,0 +1,247 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/google/uuid"
+	"orderservice/pkg/orderservice/model"
+	"time"
+)
+
+const selectWebhooks = "" +
+	"SELECT BIN_TO_UUID(webhook_id), url, event_types, secret, created_at " +
+	"FROM webhook "
+
+const selectWebhookDeliveries = "" +
+	"SELECT BIN_TO_UUID(delivery_id), BIN_TO_UUID(webhook_id), event_id, event_type, payload, status, attempts, " +
+	"next_attempt_at, last_status_code, last_error, created_at " +
+	"FROM webhook_delivery "
+
+type webhookRepository struct {
+	db      *sql.DB
+	secrets *SecretCipher
+}
+
+type webhookDeliveryRepository struct {
+	db *sql.DB
+}
+
+// NewWebhookRepository stores signing secrets encrypted, without cipher webhooks can't be saved
+func NewWebhookRepository(db *sql.DB, secrets *SecretCipher) model.WebhookRepository {
+	return &webhookRepository{db: db, secrets: secrets}
+}
+
+func NewWebhookDeliveryRepository(db *sql.DB) model.WebhookDeliveryRepository {
+	return &webhookDeliveryRepository{db: db}
+}
+
+func (wr *webhookRepository) Add(w model.Webhook) error {
+	eventTypes, err := json.Marshal(w.EventTypes)
+	if err != nil {
+		return err
+	}
+
+	secret, err := wr.secrets.Seal(w.Secret)
+	if err != nil {
+		return err
+	}
+
+	_, err = wr.db.Exec(""+
+		"INSERT INTO webhook (webhook_id, url, event_types, secret, created_at, updated_at) "+
+		"VALUES (UUID_TO_BIN(?), ?, ?, ?, ?, ?)",
+		w.ID, w.URL, eventTypes, secret, w.CreatedAt, w.CreatedAt)
+
+	return err
+}
+
+func (wr *webhookRepository) Update(w model.Webhook) (int64, error) {
+	eventTypes, err := json.Marshal(w.EventTypes)
+	if err != nil {
+		return 0, err
+	}
+
+	secret, err := wr.secrets.Seal(w.Secret)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := wr.db.Exec(""+
+		"UPDATE webhook SET url = ?, event_types = ?, secret = ?, updated_at = NOW() "+
+		"WHERE BIN_TO_UUID(webhook_id) = ?",
+		w.URL, eventTypes, secret, w.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func (wr *webhookRepository) Delete(id uuid.UUID) (int64, error) {
+	res, err := wr.db.Exec("DELETE FROM webhook WHERE BIN_TO_UUID(webhook_id) = ?", id)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func (wr *webhookRepository) Get(id uuid.UUID) (*model.Webhook, error) {
+	webhooks, err := wr.query(selectWebhooks+"WHERE BIN_TO_UUID(webhook_id) = ?", id)
+	if err != nil || len(webhooks) == 0 {
+		return nil, err
+	}
+
+	return &webhooks[0], nil
+}
+
+func (wr *webhookRepository) List() ([]model.Webhook, error) {
+	return wr.query(selectWebhooks + "ORDER BY created_at")
+}
+
+func (wr *webhookRepository) query(query string, args ...interface{}) ([]model.Webhook, error) {
+	rows, err := wr.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := make([]model.Webhook, 0)
+	for rows.Next() {
+		var id, secret string
+		var eventTypes []byte
+		var w model.Webhook
+
+		err = rows.Scan(&id, &w.URL, &eventTypes, &secret, &w.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		w.Secret, err = wr.secrets.Open(secret)
+		if err != nil {
+			return nil, err
+		}
+
+		w.ID, err = uuid.Parse(id)
+		if err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal(eventTypes, &w.EventTypes)
+		if err != nil {
+			return nil, err
+		}
+
+		webhooks = append(webhooks, w)
+	}
+
+	return webhooks, rows.Err()
+}
+
+func (dr *webhookDeliveryRepository) Add(d model.WebhookDelivery) error {
+	_, err := dr.db.Exec(""+
+		"INSERT INTO webhook_delivery (delivery_id, webhook_id, event_id, event_type, payload, status, attempts, "+
+		"next_attempt_at, last_status_code, last_error, created_at, updated_at) "+
+		"VALUES (UUID_TO_BIN(?), UUID_TO_BIN(?), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		d.ID, d.WebhookID, d.EventID, d.EventType, d.Payload, d.Status, d.Attempts,
+		d.NextAttemptAt, d.LastStatusCode, d.LastError, d.CreatedAt, d.CreatedAt)
+
+	return err
+}
+
+func (dr *webhookDeliveryRepository) Update(d model.WebhookDelivery) (int64, error) {
+	res, err := dr.db.Exec(""+
+		"UPDATE webhook_delivery SET status = ?, attempts = ?, next_attempt_at = ?, last_status_code = ?, last_error = ?, updated_at = NOW() "+
+		"WHERE BIN_TO_UUID(delivery_id) = ?",
+		d.Status, d.Attempts, d.NextAttemptAt, d.LastStatusCode, d.LastError, d.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func (dr *webhookDeliveryRepository) Get(id uuid.UUID) (*model.WebhookDelivery, error) {
+	deliveries, err := dr.query(selectWebhookDeliveries+"WHERE BIN_TO_UUID(delivery_id) = ?", id)
+	if err != nil || len(deliveries) == 0 {
+		return nil, err
+	}
+
+	return &deliveries[0], nil
+}
+
+func (dr *webhookDeliveryRepository) FindDue(now time.Time, limit int) ([]model.WebhookDelivery, error) {
+	return dr.query(selectWebhookDeliveries+"WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at LIMIT ?",
+		model.WebhookDeliveryStatusPending, now, limit)
+}
+
+func (dr *webhookDeliveryRepository) query(query string, args ...interface{}) ([]model.WebhookDelivery, error) {
+	rows, err := dr.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]model.WebhookDelivery, 0)
+	for rows.Next() {
+		var id, webhookId string
+		var d model.WebhookDelivery
+
+		err = rows.Scan(&id, &webhookId, &d.EventID, &d.EventType, &d.Payload, &d.Status, &d.Attempts,
+			&d.NextAttemptAt, &d.LastStatusCode, &d.LastError, &d.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		d.ID, err = uuid.Parse(id)
+		if err != nil {
+			return nil, err
+		}
+
+		d.WebhookID, err = uuid.Parse(webhookId)
+		if err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// EncryptWebhookSecrets seals secrets stored in plaintext before encryption was added
+func EncryptWebhookSecrets(db *sql.DB, secrets *SecretCipher) (int, error) {
+	rows, err := db.Query("SELECT BIN_TO_UUID(webhook_id), secret FROM webhook")
+	if err != nil {
+		return 0, err
+	}
+
+	plaintext := map[string]string{}
+	for rows.Next() {
+		var id, secret string
+		if err = rows.Scan(&id, &secret); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if !IsSealedSecret(secret) {
+			plaintext[id] = secret
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for id, secret := range plaintext {
+		sealed, err := secrets.Seal(secret)
+		if err != nil {
+			return 0, err
+		}
+
+		_, err = db.Exec("UPDATE webhook SET secret = ? WHERE BIN_TO_UUID(webhook_id) = ? AND secret = ?", sealed, id, secret)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(plaintext), nil
+}
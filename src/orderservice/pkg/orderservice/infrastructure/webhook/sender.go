@@ -0,0 +1,98 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"orderservice/pkg/orderservice/application/service"
+	"orderservice/pkg/orderservice/model"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+const signaturePrefix = "sha256="
+
+// responseBodyLimit bounds how much of a response is read so connections can be reused
+const responseBodyLimit = 64 * 1024
+
+type httpSender struct {
+	client *http.Client
+}
+
+// NewHTTPSender connects only to public addresses, the check runs on resolved addresses
+// so host names and redirects can't lead to internal services
+func NewHTTPSender(timeout time.Duration) service.WebhookSender {
+	return newHTTPSender(timeout, checkDialAddress)
+}
+
+func newHTTPSender(timeout time.Duration, control func(network, address string, c syscall.RawConn) error) *httpSender {
+	dialer := &net.Dialer{Timeout: timeout, Control: control}
+	transport := &http.Transport{
+		// proxy would hide the real destination from the dialer check
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+		MaxIdleConnsPerHost:   2,
+	}
+
+	return &httpSender{client: &http.Client{Timeout: timeout, Transport: transport}}
+}
+
+func checkDialAddress(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !service.WebhookAddressAllowed(ip) {
+		return fmt.Errorf("webhook address %s is not allowed", host)
+	}
+
+	return nil
+}
+
+// Sign returns HMAC SHA256 of payload which receivers compare with signature header
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *httpSender) Send(ctx context.Context, url, secret string, d model.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	req.Header.Set(SignatureHeader, Sign(secret, d.Payload))
+	req.Header.Set(EventHeader, d.EventType)
+	req.Header.Set(DeliveryHeader, d.ID.String())
+	req.Header.Set("X-Webhook-Attempt", strconv.Itoa(d.Attempts))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, responseBodyLimit))
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,52 @@
+package webhook
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"orderservice/pkg/orderservice/model"
+	"testing"
+	"time"
+)
+
+func TestSendSignsPayload(t *testing.T) {
+	payload := []byte(`{"type":"order.created"}`)
+	var signature, eventType string
+	var body []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature = r.Header.Get(SignatureHeader)
+		eventType = r.Header.Get(EventHeader)
+		body, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+
+	// test server listens on loopback which the default sender refuses
+	code, err := newHTTPSender(time.Second, nil).Send(context.Background(), ts.URL, "secret", model.WebhookDelivery{ID: uuid.New(), EventType: "order.created", Payload: payload})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code != http.StatusAccepted {
+		t.Errorf("Status code is wrong. Have: %d, want: %d", code, http.StatusAccepted)
+	}
+
+	if signature != Sign("secret", payload) || eventType != "order.created" || string(body) != string(payload) {
+		t.Errorf("Request is wrong. Signature: %s, event: %s, body: %s", signature, eventType, body)
+	}
+}
+
+func TestSendRejectsPrivateAddresses(t *testing.T) {
+	requested := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+	}))
+	defer ts.Close()
+
+	_, err := NewHTTPSender(time.Second).Send(context.Background(), ts.URL, "secret", model.WebhookDelivery{ID: uuid.New()})
+	if err == nil || requested {
+		t.Errorf("Loopback address is reached. Error: %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var currencyRegexp = regexp.MustCompile(`^[A-Z]{3}$`)
+var amountRegexp = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// minorUnits holds ISO 4217 exponents different from the default 2
+var minorUnits = map[string]int{
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0,
+	"PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+}
+
+var ErrCurrencyMismatch = errors.New("currency mismatch")
+var ErrMoneyOverflow = errors.New("money overflow")
+
+// Money is an amount in minor units of ISO 4217 currency, e.g. cents for EUR
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+func ValidateCurrency(currency string) error {
+	if !currencyRegexp.MatchString(currency) {
+		return fmt.Errorf("invalid currency: %s", currency)
+	}
+
+	return nil
+}
+
+func CurrencyExponent(currency string) int {
+	if exp, found := minorUnits[currency]; found {
+		return exp
+	}
+
+	return 2
+}
+
+// ParseMoney parses decimal amount in major units, e.g. "12.50"
+func ParseMoney(amount string, currency string) (Money, error) {
+	if err := ValidateCurrency(currency); err != nil {
+		return Money{}, err
+	}
+	if !amountRegexp.MatchString(amount) {
+		return Money{}, fmt.Errorf("invalid amount: %s", amount)
+	}
+
+	exp := CurrencyExponent(currency)
+	parts := strings.SplitN(amount, ".", 2)
+	fraction := ""
+	if len(parts) == 2 {
+		fraction = parts[1]
+	}
+	if len(fraction) > exp {
+		return Money{}, fmt.Errorf("amount %s has more than %d decimal places for %s", amount, exp, currency)
+	}
+
+	minor, err := strconv.ParseInt(parts[0]+fraction+strings.Repeat("0", exp-len(fraction)), 10, 64)
+	if err != nil {
+		return Money{}, ErrMoneyOverflow
+	}
+
+	return NewMoney(minor, currency), nil
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	if (o.Amount > 0 && m.Amount > math.MaxInt64-o.Amount) || (o.Amount < 0 && m.Amount < math.MinInt64-o.Amount) {
+		return Money{}, ErrMoneyOverflow
+	}
+
+	return NewMoney(m.Amount+o.Amount, m.Currency), nil
+}
+
+func (m Money) Sub(o Money) (Money, error) {
+	if o.Amount == math.MinInt64 {
+		return Money{}, ErrMoneyOverflow
+	}
+
+	return m.Add(NewMoney(-o.Amount, o.Currency))
+}
+
+func (m Money) Mul(n int64) (Money, error) {
+	if m.Amount == 0 || n == 0 {
+		return NewMoney(0, m.Currency), nil
+	}
+
+	result := m.Amount * n
+	if result/n != m.Amount || (m.Amount == -1 && n == math.MinInt64) || (n == -1 && m.Amount == math.MinInt64) {
+		return Money{}, ErrMoneyOverflow
+	}
+
+	return NewMoney(result, m.Currency), nil
+}
+
+// MulRate multiplies by numerator/denominator rounding half away from zero
+func (m Money) MulRate(numerator, denominator int64) (Money, error) {
+	if denominator <= 0 {
+		return Money{}, fmt.Errorf("invalid denominator: %d", denominator)
+	}
+
+	product, err := m.Mul(numerator)
+	if err != nil {
+		return Money{}, err
+	}
+
+	quotient := product.Amount / denominator
+	remainder := product.Amount % denominator
+	if remainder < 0 {
+		remainder = -remainder
+	}
+	if remainder*2 >= denominator {
+		if product.Amount < 0 {
+			quotient--
+		} else {
+			quotient++
+		}
+	}
+
+	return NewMoney(quotient, m.Currency), nil
+}
+
+func (m Money) Min(o Money) Money {
+	if o.Amount < m.Amount {
+		return o
+	}
+
+	return m
+}
+
+// Format returns decimal amount in major units, e.g. "12.50"
+func (m Money) Format() string {
+	exp := CurrencyExponent(m.Currency)
+	sign := ""
+	abs := uint64(m.Amount)
+	if m.Amount < 0 {
+		sign = "-"
+		abs = uint64(-(m.Amount + 1)) + 1
+	}
+
+	digits := strconv.FormatUint(abs, 10)
+	if exp == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+
+	return sign + digits[:len(digits)-exp] + "." + digits[len(digits)-exp:]
+}
+
+func (m Money) String() string {
+	return m.Format() + " " + m.Currency
+}
@@ -0,0 +1,57 @@
+package model
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMoneyFormat(t *testing.T) {
+	cases := map[Money]string{
+		NewMoney(1250, "EUR"): "12.50",
+		NewMoney(5, "EUR"):    "0.05",
+		NewMoney(-5, "EUR"):   "-0.05",
+		NewMoney(1250, "JPY"): "1250",
+		NewMoney(1250, "KWD"): "1.250",
+	}
+
+	for m, expected := range cases {
+		if m.Format() != expected {
+			t.Errorf("Format is wrong for %d %s. Have: %s, want: %s", m.Amount, m.Currency, m.Format(), expected)
+		}
+
+		parsed, err := ParseMoney(expected, m.Currency)
+		if err != nil || parsed != m {
+			t.Errorf("Parse is wrong for %s. Have: %v, %v, want: %v", expected, parsed, err, m)
+		}
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	if _, err := NewMoney(1, "EUR").Add(NewMoney(1, "USD")); err != ErrCurrencyMismatch {
+		t.Errorf("Add must fail on different currencies, have: %v", err)
+	}
+
+	if _, err := NewMoney(math.MaxInt64, "EUR").Add(NewMoney(1, "EUR")); err != ErrMoneyOverflow {
+		t.Errorf("Add must fail on overflow, have: %v", err)
+	}
+
+	if _, err := NewMoney(math.MaxInt64/2+1, "EUR").Mul(2); err != ErrMoneyOverflow {
+		t.Errorf("Mul must fail on overflow, have: %v", err)
+	}
+
+	rounded, err := NewMoney(125, "EUR").MulRate(1, 10)
+	if err != nil || rounded.Amount != 13 {
+		t.Errorf("MulRate must round half up, have: %v, %v", rounded, err)
+	}
+
+	rounded, err = NewMoney(-125, "EUR").MulRate(1, 10)
+	if err != nil || rounded.Amount != -13 {
+		t.Errorf("MulRate must round half away from zero, have: %v, %v", rounded, err)
+	}
+}
+
+func TestParseMoneyRejectsExtraPrecision(t *testing.T) {
+	if _, err := ParseMoney("1.005", "EUR"); err == nil {
+		t.Error("Parse must fail on more decimal places than currency has")
+	}
+}
@@ -1,26 +1,110 @@
 package model
 
 import (
+	"errors"
 	"github.com/google/uuid"
 	"time"
 )
 
+type DeliveryType string
+
+const (
+	DeliveryTypePickup   DeliveryType = "pickup"
+	DeliveryTypeDelivery DeliveryType = "delivery"
+)
+
+type OrderStatus string
+
+const (
+	OrderStatusCreated        OrderStatus = "created"
+	OrderStatusPaymentPending OrderStatus = "payment_pending"
+	OrderStatusPaid           OrderStatus = "paid"
+	OrderStatusPaymentFailed  OrderStatus = "payment_failed"
+	OrderStatusRefunded       OrderStatus = "refunded"
+)
+
+var ErrInvalidStatusTransition = errors.New("invalid order status transition")
+var ErrOrderNotEditable = errors.New("order can't be changed in its status")
+
+// orderStatusTransitions only move forward, a failed payment may be retried and refunded is final
+var orderStatusTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusCreated:        {OrderStatusPaymentPending},
+	OrderStatusPaymentFailed:  {OrderStatusPaymentPending},
+	OrderStatusPaymentPending: {OrderStatusPaid, OrderStatusPaymentFailed},
+	OrderStatusPaid:           {OrderStatusRefunded},
+}
+
+func (s OrderStatus) CanTransitTo(status OrderStatus) bool {
+	for _, next := range orderStatusTransitions[s] {
+		if next == status {
+			return true
+		}
+	}
+
+	return false
+}
+
 type Order struct {
 	ID        uuid.UUID
 	MenuItems []MenuItem
-	Cost      int
+	Cost      Money
 	OrderedAt time.Time
+	Notes     string
+	Delivery  Delivery
+	Pricing   Pricing
+	Status    OrderStatus
+	// Version is the number of stored events of an event sourced order, 0 for state based storage
+	Version int
+}
+
+// IsEditable reports whether order items can still be changed, orders being paid or paid are fixed
+func (o Order) IsEditable() bool {
+	return o.Status == OrderStatusCreated || o.Status == OrderStatusPaymentFailed
+}
+
+// Pricing is a breakdown of Order.Cost, Cost = Subtotal - Discount + Tax
+type Pricing struct {
+	Subtotal  Money
+	Discount  Money
+	Tax       Money
+	PromoCode string
+	// Promo keeps the discount of PromoCode as it was applied, nil for orders priced before it was kept
+	Promo *PromoTerms
+}
+
+func (p Pricing) Total() (Money, error) {
+	total, err := p.Subtotal.Sub(p.Discount)
+	if err != nil {
+		return Money{}, err
+	}
+
+	return total.Add(p.Tax)
 }
 
 type MenuItem struct {
-	ID       uuid.UUID
-	Quantity int
+	ID        uuid.UUID
+	Quantity  int
+	Modifiers []string
+	Comment   string
+}
+
+type Delivery struct {
+	Type    DeliveryType
+	Address string
+	Phone   string
 }
 
+// OrderRepository records every change with its actor in the order audit trail
 type OrderRepository interface {
-	Add(order Order) error
-	Update(order Order) error
-	Delete(id uuid.UUID) error
+	Add(order Order, actor string) error
+	// Update fails with ErrOrderNotEditable if the stored order isn't editable any more
+	Update(order Order, actor string) (int64, error)
+	// Delete calls beforeDelete with the stored order guarded against concurrent changes,
+	// the order is kept and its error is returned if it fails
+	Delete(id uuid.UUID, actor string, beforeDelete func(Order) error) (int64, error)
+	// UpdateStatus fails with ErrInvalidStatusTransition if the stored status can't change to status
+	UpdateStatus(id uuid.UUID, status OrderStatus, actor string) (int64, error)
 
 	Get(id uuid.UUID) (*Order, error)
+	IsDeleted(id uuid.UUID) (bool, error)
 }
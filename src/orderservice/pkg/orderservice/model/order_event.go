@@ -0,0 +1,97 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"time"
+)
+
+var ErrConcurrentModification = errors.New("order is modified concurrently")
+
+type OrderEventType string
+
+const (
+	OrderEventCreated       OrderEventType = "order_created"
+	OrderEventUpdated       OrderEventType = "order_updated"
+	OrderEventStatusChanged OrderEventType = "order_status_changed"
+	OrderEventDeleted       OrderEventType = "order_deleted"
+)
+
+// OrderEvent is a stored change of an event sourced order
+type OrderEvent struct {
+	OrderID    uuid.UUID
+	Version    int
+	Type       OrderEventType
+	Actor      string
+	OccurredAt time.Time
+	// Order holds the new state for created and updated events
+	Order *Order
+	// Status is set for status changed events
+	Status OrderStatus
+}
+
+// OrderAggregate is an order rebuilt from its events
+type OrderAggregate struct {
+	Order     Order
+	DeletedAt *time.Time
+}
+
+func (a *OrderAggregate) IsDeleted() bool {
+	return a.DeletedAt != nil
+}
+
+// Apply changes state by the next event, events must follow each other without gaps
+func (a *OrderAggregate) Apply(e OrderEvent) error {
+	if e.Version != a.Order.Version+1 {
+		return fmt.Errorf("order %s event version %d doesn't follow %d", e.OrderID, e.Version, a.Order.Version)
+	}
+	if a.IsDeleted() {
+		return fmt.Errorf("order %s has event %s after deletion", e.OrderID, e.Type)
+	}
+
+	switch e.Type {
+	case OrderEventCreated:
+		if e.Order == nil || a.Order.Version != 0 {
+			return fmt.Errorf("order %s has invalid %s event", e.OrderID, e.Type)
+		}
+		a.Order = *e.Order
+		a.Order.ID = e.OrderID
+	case OrderEventUpdated:
+		if e.Order == nil {
+			return fmt.Errorf("order %s has invalid %s event", e.OrderID, e.Type)
+		}
+		// update doesn't change identity, creation time and status
+		a.Order.MenuItems = e.Order.MenuItems
+		a.Order.Cost = e.Order.Cost
+		a.Order.Notes = e.Order.Notes
+		a.Order.Delivery = e.Order.Delivery
+		a.Order.Pricing = e.Order.Pricing
+	case OrderEventStatusChanged:
+		a.Order.Status = e.Status
+	case OrderEventDeleted:
+		deletedAt := e.OccurredAt
+		a.DeletedAt = &deletedAt
+	default:
+		return fmt.Errorf("order %s has unknown event %s", e.OrderID, e.Type)
+	}
+
+	a.Order.Version = e.Version
+	return nil
+}
+
+// RebuildOrder replays events in version order, nil is returned for no events
+func RebuildOrder(events []OrderEvent) (*OrderAggregate, error) {
+	if len(events) == 0 {
+		return nil, nil
+	}
+
+	a := &OrderAggregate{}
+	for _, e := range events {
+		if err := a.Apply(e); err != nil {
+			return nil, err
+		}
+	}
+
+	return a, nil
+}
@@ -0,0 +1,68 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"testing"
+	"time"
+)
+
+func TestRebuildOrder(t *testing.T) {
+	id := uuid.New()
+	orderedAt := time.Now()
+	events := []OrderEvent{
+		{OrderID: id, Version: 1, Type: OrderEventCreated, Order: &Order{Notes: "first", OrderedAt: orderedAt, Status: OrderStatusCreated}},
+		{OrderID: id, Version: 2, Type: OrderEventUpdated, Order: &Order{Notes: "second", Status: OrderStatusRefunded}},
+		{OrderID: id, Version: 3, Type: OrderEventStatusChanged, Status: OrderStatusPaid},
+	}
+
+	a, err := RebuildOrder(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o := a.Order
+	if o.ID != id || o.Notes != "second" || o.Status != OrderStatusPaid || !o.OrderedAt.Equal(orderedAt) || o.Version != 3 || a.IsDeleted() {
+		t.Errorf("Order is wrong: %v", a)
+	}
+}
+
+func TestRebuildOrderRejectsInvalidHistory(t *testing.T) {
+	id := uuid.New()
+	cases := [][]OrderEvent{
+		{{OrderID: id, Version: 2, Type: OrderEventCreated, Order: &Order{}}},
+		{{OrderID: id, Version: 1, Type: OrderEventUpdated}},
+		{
+			{OrderID: id, Version: 1, Type: OrderEventCreated, Order: &Order{}},
+			{OrderID: id, Version: 2, Type: OrderEventDeleted},
+			{OrderID: id, Version: 3, Type: OrderEventStatusChanged, Status: OrderStatusPaid},
+		},
+	}
+
+	for _, events := range cases {
+		if _, err := RebuildOrder(events); err == nil {
+			t.Errorf("Invalid history is accepted: %v", events)
+		}
+	}
+}
+
+func TestOrderStatusTransitions(t *testing.T) {
+	cases := []struct {
+		from    OrderStatus
+		to      OrderStatus
+		allowed bool
+	}{
+		{OrderStatusCreated, OrderStatusPaymentPending, true},
+		{OrderStatusCreated, OrderStatusPaid, false},
+		{OrderStatusPaymentPending, OrderStatusPaid, true},
+		{OrderStatusPaymentFailed, OrderStatusPaymentPending, true},
+		{OrderStatusPaid, OrderStatusPaymentFailed, false},
+		{OrderStatusPaid, OrderStatusRefunded, true},
+		{OrderStatusRefunded, OrderStatusPaid, false},
+	}
+
+	for _, c := range cases {
+		if c.from.CanTransitTo(c.to) != c.allowed {
+			t.Errorf("Transition %s -> %s is wrong, want allowed: %v", c.from, c.to, c.allowed)
+		}
+	}
+}
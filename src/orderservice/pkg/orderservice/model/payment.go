@@ -0,0 +1,54 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type PaymentStatus string
+
+const (
+	PaymentStatusPending   PaymentStatus = "pending"
+	PaymentStatusSucceeded PaymentStatus = "succeeded"
+	PaymentStatusFailed    PaymentStatus = "failed"
+	PaymentStatusRefunded  PaymentStatus = "refunded"
+)
+
+// Payment is an intent to pay an order through a payment provider
+type Payment struct {
+	ID          uuid.UUID
+	OrderID     uuid.UUID
+	Provider    string
+	ProviderRef string
+	Amount      Money
+	Status      PaymentStatus
+	CreatedAt   time.Time
+}
+
+// CanTransitTo reports whether provider result may change payment status,
+// pending payments are resolved once and only succeeded payments are refunded
+func (p Payment) CanTransitTo(status PaymentStatus) bool {
+	switch p.Status {
+	case PaymentStatusPending:
+		return status != PaymentStatusPending && status != PaymentStatusRefunded
+	case PaymentStatusSucceeded:
+		return status == PaymentStatusRefunded
+	default:
+		return false
+	}
+}
+
+// IsActive reports whether the payment is in progress or has taken the money
+func (p Payment) IsActive() bool {
+	return p.Status == PaymentStatusPending || p.Status == PaymentStatusSucceeded
+}
+
+type PaymentRepository interface {
+	Add(p Payment) error
+	// Update stores the payment if its stored status is still previous, 0 is returned otherwise
+	Update(p Payment, previous PaymentStatus) (int64, error)
+
+	Get(id uuid.UUID) (*Payment, error)
+	GetByProviderRef(provider string, ref string) (*Payment, error)
+	FindByOrder(orderID uuid.UUID) ([]Payment, error)
+}
@@ -0,0 +1,68 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type PromoCodeType string
+
+const (
+	PromoCodeTypePercentage PromoCodeType = "percentage"
+	PromoCodeTypeFixed      PromoCodeType = "fixed"
+	PromoCodeTypeBuyXGetY   PromoCodeType = "buy_x_get_y"
+)
+
+type PromoCode struct {
+	Code string
+	Type PromoCodeType
+	// Value is a percent for percentage codes and an amount in minor units of Currency for fixed codes
+	Value        int
+	Currency     string
+	BuyQuantity  int
+	FreeQuantity int
+	// MenuItemID restricts buy_x_get_y codes to a single menu item, any item if nil
+	MenuItemID *uuid.UUID
+	ValidFrom  *time.Time
+	ValidTo    *time.Time
+	// UsageLimit is a maximum number of orders with the code, unlimited if 0
+	UsageLimit int
+	UsedCount  int
+}
+
+// PromoTerms is the discount of a promo code, orders keep the terms they were priced with,
+// so a changed or deleted code doesn't change them
+type PromoTerms struct {
+	Type         PromoCodeType
+	Value        int
+	Currency     string
+	BuyQuantity  int
+	FreeQuantity int
+	MenuItemID   *uuid.UUID
+}
+
+func (p PromoCode) Terms() PromoTerms {
+	return PromoTerms{Type: p.Type, Value: p.Value, Currency: p.Currency, BuyQuantity: p.BuyQuantity, FreeQuantity: p.FreeQuantity, MenuItemID: p.MenuItemID}
+}
+
+func (p PromoCode) IsActive(at time.Time) bool {
+	if p.ValidFrom != nil && at.Before(*p.ValidFrom) {
+		return false
+	}
+	if p.ValidTo != nil && !at.Before(*p.ValidTo) {
+		return false
+	}
+
+	return p.UsageLimit == 0 || p.UsedCount < p.UsageLimit
+}
+
+type PromoCodeRepository interface {
+	Add(p PromoCode) error
+	Update(p PromoCode) (int64, error)
+	Delete(code string) (int64, error)
+
+	Get(code string) (*PromoCode, error)
+	// Redeem increments usage of the code unless its usage limit is reached
+	Redeem(code string) (int64, error)
+	Release(code string) error
+}
@@ -0,0 +1,68 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// WebhookAllEvents subscribes a webhook to every event type
+const WebhookAllEvents = "*"
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryStatusPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryStatusDelivered WebhookDeliveryStatus = "delivered"
+	// WebhookDeliveryStatusDead marks deliveries which ran out of attempts
+	WebhookDeliveryStatusDead WebhookDeliveryStatus = "dead"
+)
+
+type Webhook struct {
+	ID         uuid.UUID
+	URL        string
+	EventTypes []string
+	Secret     string
+	CreatedAt  time.Time
+}
+
+func (w Webhook) Accepts(eventType string) bool {
+	for _, t := range w.EventTypes {
+		if t == WebhookAllEvents || t == eventType {
+			return true
+		}
+	}
+
+	return false
+}
+
+type WebhookDelivery struct {
+	ID             uuid.UUID
+	WebhookID      uuid.UUID
+	EventID        uint64
+	EventType      string
+	Payload        []byte
+	Status         WebhookDeliveryStatus
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastStatusCode int
+	LastError      string
+	CreatedAt      time.Time
+}
+
+type WebhookRepository interface {
+	Add(w Webhook) error
+	Update(w Webhook) (int64, error)
+	Delete(id uuid.UUID) (int64, error)
+
+	Get(id uuid.UUID) (*Webhook, error)
+	List() ([]Webhook, error)
+}
+
+type WebhookDeliveryRepository interface {
+	Add(d WebhookDelivery) error
+	Update(d WebhookDelivery) (int64, error)
+
+	Get(id uuid.UUID) (*WebhookDelivery, error)
+	// FindDue returns pending deliveries with next attempt before now
+	FindDue(now time.Time, limit int) ([]WebhookDelivery, error)
+}
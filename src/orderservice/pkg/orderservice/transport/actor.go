@@ -0,0 +1,110 @@
+package transport
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"strings"
+)
+
+// adminPrincipal is the actor of requests made with the admin token
+const adminPrincipal = "admin"
+
+const anonymousActor = "anonymous"
+
+const maxActorLength = 255
+
+type principalKey struct{}
+
+func normalizeActor(actor string) string {
+	actor = strings.TrimSpace(actor)
+	if actor == "" {
+		return anonymousActor
+	}
+	if len(actor) > maxActorLength {
+		actor = actor[:maxActorLength]
+	}
+
+	return actor
+}
+
+// principal returns the principal of a bearer token, every token is compared so
+// the time doesn't tell which one matched
+func (c AuthConfig) principal(token string) (string, bool) {
+	var principal string
+	found := false
+	if c.AdminToken != "" && secureEqual(token, c.AdminToken) {
+		principal, found = adminPrincipal, true
+	}
+	for apiToken, p := range c.APITokens {
+		if apiToken != "" && secureEqual(token, apiToken) && !found {
+			principal, found = p, true
+		}
+	}
+
+	return normalizeActor(principal), found
+}
+
+func withPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// principalFromContext is set only for authenticated requests
+func principalFromContext(ctx context.Context) (string, bool) {
+	principal, ok := ctx.Value(principalKey{}).(string)
+	return principal, ok
+}
+
+// authenticationMiddleware resolves the bearer token to a principal, requests without token stay anonymous
+// and requests with unknown token are rejected, client supplied identity headers are never trusted
+func authenticationMiddleware(c AuthConfig, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := bearerToken(r)
+		if token == "" {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		principal, found := c.principal(token)
+		if !found {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="orderservice"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		h.ServeHTTP(w, r.WithContext(withPrincipal(r.Context(), principal)))
+	})
+}
+
+// grpcAuthenticationInterceptor reads the bearer token of authorization metadata the same way
+func grpcAuthenticationInterceptor(c AuthConfig) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get("authorization")
+		if len(values) == 0 || !strings.HasPrefix(values[0], bearerPrefix) {
+			return handler(ctx, req)
+		}
+
+		principal, found := c.principal(strings.TrimSpace(values[0][len(bearerPrefix):]))
+		if !found {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+
+		return handler(withPrincipal(ctx, principal), req)
+	}
+}
+
+func actorFromRequest(r *http.Request) string {
+	return actorFromContext(r.Context())
+}
+
+func actorFromContext(ctx context.Context) string {
+	if principal, ok := principalFromContext(ctx); ok {
+		return principal
+	}
+
+	return anonymousActor
+}
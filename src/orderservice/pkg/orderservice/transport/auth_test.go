@@ -0,0 +1,87 @@
+package transport
+
+import (
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAdminAuth(t *testing.T) {
+	cases := []struct {
+		adminToken string
+		header     string
+		status     int
+	}{
+		{"", "", http.StatusForbidden},
+		{"", "Bearer ", http.StatusForbidden},
+		{"secret", "", http.StatusUnauthorized},
+		{"secret", "Bearer wrong", http.StatusUnauthorized},
+		{"secret", "Bearer secret", http.StatusOK},
+	}
+
+	for _, c := range cases {
+		h := adminAuthMiddleware(AuthConfig{AdminToken: c.adminToken})(http.HandlerFunc(helloWorld))
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/admin/promo-codes", nil)
+		if c.header != "" {
+			r.Header.Set("Authorization", c.header)
+		}
+		h.ServeHTTP(w, r)
+		if w.Code != c.status {
+			t.Errorf("Status code is wrong for %q with token %q. Have: %d, want: %d", c.header, c.adminToken, w.Code, c.status)
+		}
+	}
+}
+
+func TestWebhookRoutesRequireAdmin(t *testing.T) {
+	r := mux.NewRouter()
+	routeV1(r.PathPrefix(apiV1Prefix).Subrouter(), &server{}, AuthConfig{AdminToken: "secret"})
+
+	requests := []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/api/v1/webhooks", nil),
+		httptest.NewRequest(http.MethodPost, "/api/v1/webhooks", nil),
+		httptest.NewRequest(http.MethodGet, "/api/v1/webhooks/deliveries", nil),
+		httptest.NewRequest(http.MethodDelete, "/api/v1/webhooks/0b4a0d6e-0000-0000-0000-000000000000", nil),
+	}
+	for _, req := range requests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("Status code of %s %s is wrong. Have: %d, want: %d", req.Method, req.URL, w.Code, http.StatusUnauthorized)
+		}
+	}
+}
+
+func TestActorComesFromToken(t *testing.T) {
+	auth := AuthConfig{AdminToken: "admin-secret", APITokens: map[string]string{"alice-token": "alice"}}
+	var actor string
+	h := authenticationMiddleware(auth, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor = actorFromRequest(r)
+	}))
+
+	cases := []struct {
+		header string
+		status int
+		actor  string
+	}{
+		{"", http.StatusOK, anonymousActor},
+		{"Bearer alice-token", http.StatusOK, "alice"},
+		{"Bearer admin-secret", http.StatusOK, adminPrincipal},
+		{"Bearer wrong", http.StatusUnauthorized, ""},
+	}
+
+	for _, c := range cases {
+		actor = ""
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/order", nil)
+		r.Header.Set("X-User-ID", "mallory")
+		if c.header != "" {
+			r.Header.Set("Authorization", c.header)
+		}
+		h.ServeHTTP(w, r)
+		if w.Code != c.status || actor != c.actor {
+			t.Errorf("Request with %q is wrong. Have: %d %q, want: %d %q", c.header, w.Code, actor, c.status, c.actor)
+		}
+	}
+}
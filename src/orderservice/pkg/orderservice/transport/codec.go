@@ -0,0 +1,296 @@
+package transport
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"orderservice/pkg/orderservice/application/data"
+	"orderservice/pkg/orderservice/transport/orderpb"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	jsonMediaType      = "application/json"
+	msgpackMediaType   = "application/msgpack"
+	protoJsonMediaType = "application/protobuf+json"
+)
+
+// errUnsupportedMediaType is returned for request bodies which aren't JSON
+var errUnsupportedMediaType = errors.New("unsupported media type")
+
+// decodeError describes invalid request body without repeating it, field is a dotted path if known
+type decodeError struct {
+	field   string
+	message string
+}
+
+func (e *decodeError) Error() string {
+	if e.field == "" {
+		return e.message
+	}
+
+	return fmt.Sprintf("field %s: %s", e.field, e.message)
+}
+
+// encoder writes v in a media type, ok is false when v has no representation in it
+type encoder func(v interface{}) (b []byte, ok bool, err error)
+
+type responseFormat struct {
+	mediaType string
+	encode    encoder
+	// textual formats declare charset, binary ones like msgpack have none
+	textual bool
+}
+
+// responseFormats are in order of preference for equally acceptable types
+var responseFormats = []responseFormat{
+	{mediaType: jsonMediaType, encode: encodeJson, textual: true},
+	{mediaType: msgpackMediaType, encode: encodeMsgpack},
+	{mediaType: protoJsonMediaType, encode: encodeProtoJson, textual: true},
+}
+
+func encodeJson(v interface{}) ([]byte, bool, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, true, err
+	}
+
+	return append(b, '\n'), true, nil
+}
+
+func encodeMsgpack(v interface{}) ([]byte, bool, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	// the same field names as in JSON
+	enc.SetCustomStructTag("json")
+	err := enc.Encode(v)
+	return buf.Bytes(), true, err
+}
+
+// encodeProtoJson supports only values defined in the gRPC API
+func encodeProtoJson(v interface{}) ([]byte, bool, error) {
+	var m proto.Message
+	switch value := v.(type) {
+	case *data.OrderInfo:
+		m = orderToPb(*value)
+	case *data.OrdersList:
+		orders := make([]*orderpb.Order, len(value.Orders))
+		for i, info := range value.Orders {
+			orders[i] = orderToPb(info)
+		}
+		m = &orderpb.ListOrdersResponse{Orders: orders}
+	default:
+		return nil, false, nil
+	}
+
+	b, err := protojson.Marshal(m)
+	return b, true, err
+}
+
+type acceptedType struct {
+	mediaType string
+	q         float64
+}
+
+// specificity ranks type/subtype over type/* over */*
+func specificity(mediaType string) int {
+	switch {
+	case mediaType == "*/*":
+		return 0
+	case strings.HasSuffix(mediaType, "/*"):
+		return 1
+	default:
+		return 2
+	}
+}
+
+// parseAccept returns accepted media types sorted by quality and then by specificity,
+// types with q=0 are dropped
+func parseAccept(accept string) []acceptedType {
+	types := make([]acceptedType, 0)
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if value, found := params["q"]; found {
+			if q, err = strconv.ParseFloat(value, 64); err != nil || q < 0 || q > 1 {
+				continue
+			}
+		}
+
+		if q > 0 {
+			types = append(types, acceptedType{mediaType: mediaType, q: q})
+		}
+	}
+
+	sort.SliceStable(types, func(i, j int) bool {
+		if types[i].q != types[j].q {
+			return types[i].q > types[j].q
+		}
+		return specificity(types[i].mediaType) > specificity(types[j].mediaType)
+	})
+
+	return types
+}
+
+func matchesMediaType(pattern, mediaType string) bool {
+	if pattern == "*/*" || pattern == mediaType {
+		return true
+	}
+
+	return strings.HasSuffix(pattern, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(pattern, "*"))
+}
+
+// render encodes v in the best format accepted by the client before writing anything,
+// so an encoding error can still be reported as 500
+func render(w http.ResponseWriter, r *http.Request, v interface{}) {
+	renderWithStatus(w, r, http.StatusOK, v)
+}
+
+func renderWithStatus(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	accept := ""
+	if r != nil {
+		accept = r.Header.Get("Accept")
+	}
+
+	accepted := []acceptedType{{mediaType: "*/*", q: 1}}
+	if accept != "" {
+		accepted = parseAccept(accept)
+	}
+
+	w.Header().Add("Vary", "Accept")
+	for _, a := range accepted {
+		for _, format := range responseFormats {
+			if !matchesMediaType(a.mediaType, format.mediaType) {
+				continue
+			}
+
+			b, ok, err := format.encode(v)
+			if !ok {
+				continue
+			}
+			if err != nil {
+				log.Error(err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+
+			contentType := format.mediaType
+			if format.textual {
+				contentType += "; charset=UTF-8"
+			}
+			w.Header().Set("Content-Type", contentType)
+			w.Header().Set("Content-Length", strconv.Itoa(len(b)))
+			w.WriteHeader(status)
+			if _, err = w.Write(b); err != nil {
+				log.Debug(err) // client has gone, nothing can be sent
+			}
+			return
+		}
+	}
+
+	http.Error(w, "Not Acceptable", http.StatusNotAcceptable)
+}
+
+// isJsonMediaType accepts application/json and structured suffix types like application/merge-patch+json,
+// missing Content-Type is treated as JSON
+func isJsonMediaType(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return mediaType == jsonMediaType || (strings.HasPrefix(mediaType, "application/") && strings.HasSuffix(mediaType, "+json"))
+}
+
+// jsonFromRequest decodes exactly one JSON value without unknown fields
+func jsonFromRequest(r *http.Request, output interface{}) error {
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			log.Error(err)
+		}
+	}()
+
+	if !isJsonMediaType(r.Header.Get("Content-Type")) {
+		_, _ = io.Copy(ioutil.Discard, r.Body)
+		return errUnsupportedMediaType
+	}
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(output); err != nil {
+		return newDecodeError(err)
+	}
+
+	if _, err := dec.Token(); err != io.EOF {
+		if errors.Is(err, errRequestTooLarge) {
+			return err
+		}
+		return &decodeError{message: "unexpected data after JSON value"}
+	}
+
+	return nil
+}
+
+// newDecodeError keeps read errors like errRequestTooLarge and describes JSON errors without the body
+func newDecodeError(err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		return &decodeError{message: fmt.Sprintf("invalid JSON at offset %d", syntaxErr.Offset)}
+	case errors.As(err, &typeErr):
+		return &decodeError{field: typeErr.Field, message: fmt.Sprintf("expected %s, got %s", typeErr.Type, typeErr.Value)}
+	case err == io.EOF:
+		return &decodeError{message: "empty body"}
+	case err == io.ErrUnexpectedEOF:
+		return &decodeError{message: "unexpected end of JSON"}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		return &decodeError{
+			field:   strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`),
+			message: "unknown field",
+		}
+	default:
+		var d *decodeError
+		if errors.As(err, &d) {
+			return err
+		}
+		if strings.HasPrefix(err.Error(), "json: ") {
+			return &decodeError{message: strings.TrimPrefix(err.Error(), "json: ")}
+		}
+		return err
+	}
+}
+
+// processRequestError replies to a request which body can't be read or decoded
+func processRequestError(w http.ResponseWriter, e error) {
+	var d *decodeError
+	switch {
+	case errors.Is(e, errRequestTooLarge):
+		http.Error(w, "Request Entity Too Large", http.StatusRequestEntityTooLarge)
+	case e == errUnsupportedMediaType:
+		http.Error(w, "Unsupported Media Type", http.StatusUnsupportedMediaType)
+	case errors.As(e, &d):
+		http.Error(w, "Invalid Request: "+d.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Invalid Request", http.StatusBadRequest)
+	}
+}
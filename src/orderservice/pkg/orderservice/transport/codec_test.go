@@ -0,0 +1,95 @@
+package transport
+
+import (
+	"github.com/vmihailenco/msgpack/v5"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestJsonFromRequestIsStrict(t *testing.T) {
+	cases := []struct {
+		contentType string
+		body        string
+		status      int
+		message     string
+	}{
+		{"application/json", `{"menuItems":[]}`, http.StatusOK, ""},
+		{"application/json; charset=utf-8", `{"menuItems":[]}`, http.StatusOK, ""},
+		{"text/plain", `{"menuItems":[]}`, http.StatusUnsupportedMediaType, ""},
+		{"application/json", `{"menuItems":[], "extra": "secret"}`, http.StatusBadRequest, "field extra: unknown field"},
+		{"application/json", `{"menuItems":[]} {"menuItems":[]}`, http.StatusBadRequest, "unexpected data after JSON value"},
+		{"application/json", `{"menuItems":[{"id": "a", "quantity": "secret"}]}`, http.StatusBadRequest, "quantity: expected int, got string"},
+		{"application/json", `{"notes": "secret`, http.StatusBadRequest, "unexpected end of JSON"},
+	}
+
+	for _, c := range cases {
+		srv := server{orderService: mocOrderService{}}
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/order", strings.NewReader(c.body))
+		r.Header.Set("Content-Type", c.contentType)
+		srv.addOrder(w, r)
+		if w.Code != c.status {
+			t.Errorf("Status code is wrong for %s. Have: %d, want: %d", c.body, w.Code, c.status)
+		}
+
+		if !strings.Contains(w.Body.String(), c.message) || strings.Contains(w.Body.String(), "secret") {
+			t.Errorf("Error message is wrong for %s: %s", c.body, w.Body.String())
+		}
+	}
+}
+
+func TestRenderNegotiatesFormat(t *testing.T) {
+	cases := []struct {
+		accept      string
+		status      int
+		contentType string
+	}{
+		{"", http.StatusOK, jsonMediaType + "; charset=UTF-8"},
+		{"text/html, application/*;q=0.5", http.StatusOK, jsonMediaType + "; charset=UTF-8"},
+		{"application/msgpack, application/json;q=0.9", http.StatusOK, msgpackMediaType},
+		{"*/*, application/msgpack", http.StatusOK, msgpackMediaType},
+		{"application/*, application/protobuf+json", http.StatusOK, protoJsonMediaType + "; charset=UTF-8"},
+		{"application/protobuf+json", http.StatusOK, protoJsonMediaType + "; charset=UTF-8"},
+		{"application/json;q=0, text/html", http.StatusNotAcceptable, ""},
+	}
+
+	for _, c := range cases {
+		srv := server{orderQueryService: mocOrderQueryService{}}
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/orders", nil)
+		r.Header.Set("Accept", c.accept)
+		srv.getOrdersList(w, r)
+		if w.Code != c.status {
+			t.Errorf("Status code is wrong for %s. Have: %d, want: %d", c.accept, w.Code, c.status)
+		}
+
+		if contentType := w.Header().Get("Content-Type"); c.contentType != "" && contentType != c.contentType {
+			t.Errorf("Content type is wrong for %s. Have: %s, want: %s", c.accept, contentType, c.contentType)
+		}
+	}
+}
+
+func TestRenderMsgpackUsesJsonNames(t *testing.T) {
+	srv := server{orderQueryService: mocOrderQueryService{}}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/orders", nil)
+	r.Header.Set("Accept", msgpackMediaType)
+	srv.getOrdersList(w, r)
+
+	b, err := ioutil.ReadAll(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var orders map[string][]map[string]interface{}
+	if err = msgpack.Unmarshal(b, &orders); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(orders["orders"]) != 1 || orders["orders"][0]["id"] != "3fa85f64-5717-4562-b3fc-2c963f66afa6" {
+		t.Errorf("Orders are wrong: %v", orders)
+	}
+}
@@ -0,0 +1,135 @@
+package transport
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const allOrigins = "*"
+
+type CORSConfig struct {
+	// AllowedOrigins are exact origins like "https://shop.example.com" or "*", empty disables CORS
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache preflight responses
+	MaxAge time.Duration
+}
+
+// Validate rejects the wildcard origin with credentials, any site could make credentialed requests
+func (c CORSConfig) Validate() error {
+	if c.AllowCredentials && c.allowsOrigin(allOrigins) {
+		return errors.New("cors origin * can't be used with credentials, list the allowed origins")
+	}
+
+	return nil
+}
+
+func (c CORSConfig) allowsOrigin(origin string) bool {
+	for _, o := range c.AllowedOrigins {
+		if o == allOrigins || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c CORSConfig) allowsMethod(method string) bool {
+	for _, m := range c.AllowedMethods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c CORSConfig) allowsHeaders(requested string) bool {
+	for _, h := range strings.Split(requested, ",") {
+		if h = strings.TrimSpace(h); h == "" {
+			continue
+		}
+
+		allowed := false
+		for _, a := range c.AllowedHeaders {
+			if strings.EqualFold(a, h) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+
+	return true
+}
+
+// corsMiddleware answers preflight requests itself, routes don't have OPTIONS handlers
+func corsMiddleware(c CORSConfig, h http.Handler) http.Handler {
+	if len(c.AllowedOrigins) == 0 {
+		return h
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if preflight {
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		if !c.allowsOrigin(origin) {
+			if preflight {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		// Validate keeps credentials away from the wildcard, it is never echoed with them
+		if c.allowsOrigin(allOrigins) {
+			w.Header().Set("Access-Control-Allow-Origin", allOrigins)
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			if c.AllowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+		}
+
+		if !preflight {
+			if len(c.ExposedHeaders) > 0 {
+				w.Header().Set("Access-Control-Expose-Headers", strings.Join(c.ExposedHeaders, ", "))
+			}
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		if !c.allowsMethod(r.Header.Get("Access-Control-Request-Method")) || !c.allowsHeaders(r.Header.Get("Access-Control-Request-Headers")) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(c.AllowedMethods, ", "))
+		if len(c.AllowedHeaders) > 0 {
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(c.AllowedHeaders, ", "))
+		}
+		if c.MaxAge > 0 {
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(c.MaxAge.Seconds())))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
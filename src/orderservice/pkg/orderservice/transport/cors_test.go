@@ -0,0 +1,68 @@
+package transport
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var testCORSConfig = CORSConfig{
+	AllowedOrigins: []string{"https://shop.example.com"},
+	AllowedMethods: []string{http.MethodGet, http.MethodPost},
+	AllowedHeaders: []string{"Content-Type"},
+	ExposedHeaders: []string{"Retry-After"},
+	MaxAge:         10 * time.Minute,
+}
+
+func TestCORSPreflight(t *testing.T) {
+	cases := []struct {
+		origin string
+		method string
+		status int
+	}{
+		{"https://shop.example.com", http.MethodPost, http.StatusNoContent},
+		{"https://evil.example.com", http.MethodPost, http.StatusForbidden},
+		{"https://shop.example.com", http.MethodDelete, http.StatusForbidden},
+	}
+
+	h := corsMiddleware(testCORSConfig, http.HandlerFunc(helloWorld))
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodOptions, "/api/v1/order", nil)
+		r.Header.Set("Origin", c.origin)
+		r.Header.Set("Access-Control-Request-Method", c.method)
+		r.Header.Set("Access-Control-Request-Headers", "content-type")
+		h.ServeHTTP(w, r)
+		if w.Code != c.status {
+			t.Errorf("Status code is wrong for %s %s. Have: %d, want: %d", c.origin, c.method, w.Code, c.status)
+		}
+	}
+}
+
+func TestCORSHeaders(t *testing.T) {
+	h := securityHeadersMiddleware(SecurityHeadersConfig{}, corsMiddleware(testCORSConfig, http.HandlerFunc(helloWorld)))
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/hello-world", nil)
+	r.Header.Set("Origin", "https://shop.example.com")
+	h.ServeHTTP(w, r)
+
+	header := w.Header()
+	if header.Get("Access-Control-Allow-Origin") != "https://shop.example.com" || header.Get("Access-Control-Expose-Headers") != "Retry-After" {
+		t.Errorf("CORS headers are wrong: %v", header)
+	}
+
+	if header.Get("X-Content-Type-Options") != "nosniff" || header.Get("Strict-Transport-Security") != "" {
+		t.Errorf("Security headers are wrong: %v", header)
+	}
+}
+
+func TestCORSRejectsWildcardWithCredentials(t *testing.T) {
+	if err := (CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}).Validate(); err == nil {
+		t.Errorf("Wildcard origin with credentials is accepted")
+	}
+
+	if err := (CORSConfig{AllowedOrigins: []string{"https://shop.example.com"}, AllowCredentials: true}).Validate(); err != nil {
+		t.Errorf("Listed origin with credentials is rejected: %s", err)
+	}
+}
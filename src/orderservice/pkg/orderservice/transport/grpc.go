@@ -0,0 +1,182 @@
+package transport
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"orderservice/pkg/orderservice/application/data"
+	"orderservice/pkg/orderservice/application/service"
+	"orderservice/pkg/orderservice/transport/orderpb"
+)
+
+const orderServiceName = "orderservice.v1.OrderService"
+
+type grpcServer struct {
+	orderpb.UnimplementedOrderServiceServer
+	srv *server
+}
+
+// GRPCServer exposes the same application services as Router over gRPC
+func GRPCServer(services *Services) *grpc.Server {
+	c := services.config
+	options := []grpc.ServerOption{grpc.ChainUnaryInterceptor(
+		grpcIPRateLimitInterceptor(services.limiter),
+		grpcAuthenticationInterceptor(c.Auth),
+		grpcPrincipalRateLimitInterceptor(services.limiter),
+	)}
+	if c.Limits.MaxBodySize > 0 {
+		options = append(options, grpc.MaxRecvMsgSize(int(c.Limits.MaxBodySize)))
+	}
+	if c.TLS != nil {
+		options = append(options, grpc.Creds(credentials.NewTLS(c.TLS)))
+	}
+
+	s := grpc.NewServer(options...)
+	orderpb.RegisterOrderServiceServer(s, &grpcServer{srv: services.srv})
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(orderServiceName, grpc_health_v1.HealthCheckResponse_SERVING)
+	grpc_health_v1.RegisterHealthServer(s, healthServer)
+
+	reflection.Register(s)
+
+	return s
+}
+
+func grpcError(e error) error {
+	switch e {
+	case data.InternalError:
+		return status.Error(codes.Internal, "internal error")
+	case data.OrderNotFoundError, data.OrderDeletedError:
+		return status.Error(codes.NotFound, e.Error())
+	case data.OrderConflictError:
+		return status.Error(codes.Aborted, e.Error())
+	default:
+		return status.Error(codes.InvalidArgument, e.Error())
+	}
+}
+
+func menuItemsFromPb(items []*orderpb.MenuItem) []data.MenuItem {
+	result := make([]data.MenuItem, len(items))
+	for i, item := range items {
+		result[i] = data.MenuItem{
+			ID:        item.GetId(),
+			Quantity:  int(item.GetQuantity()),
+			Modifiers: item.GetModifiers(),
+			Comment:   item.GetComment(),
+		}
+	}
+
+	return result
+}
+
+func deliveryFromPb(d *orderpb.Delivery) data.Delivery {
+	return data.Delivery{Type: d.GetType(), Address: d.GetAddress(), Phone: d.GetPhone()}
+}
+
+func moneyToPb(m data.Money) *orderpb.Money {
+	return &orderpb.Money{Amount: m.Amount, Currency: m.Currency}
+}
+
+func orderToPb(info data.OrderInfo) *orderpb.Order {
+	items := make([]*orderpb.MenuItem, len(info.MenuItems))
+	for i, item := range info.MenuItems {
+		items[i] = &orderpb.MenuItem{
+			Id:        item.ID,
+			Quantity:  int32(item.Quantity),
+			Modifiers: item.Modifiers,
+			Comment:   item.Comment,
+		}
+	}
+
+	return &orderpb.Order{
+		Id:        info.ID,
+		MenuItems: items,
+		OrderedAt: timestamppb.New(info.OrderedAt),
+		Cost:      moneyToPb(info.Cost),
+		Notes:     info.Notes,
+		Delivery: &orderpb.Delivery{
+			Type:    info.Delivery.Type,
+			Address: info.Delivery.Address,
+			Phone:   info.Delivery.Phone,
+		},
+		Pricing: &orderpb.Pricing{
+			Subtotal:  moneyToPb(info.Pricing.Subtotal),
+			Discount:  moneyToPb(info.Pricing.Discount),
+			Tax:       moneyToPb(info.Pricing.Tax),
+			Total:     moneyToPb(info.Pricing.Total),
+			PromoCode: info.Pricing.PromoCode,
+		},
+		Status: info.Status,
+	}
+}
+
+func (g *grpcServer) AddOrder(ctx context.Context, r *orderpb.AddOrderRequest) (*orderpb.AddOrderResponse, error) {
+	id, err := g.srv.orderService.Add(service.AddOrderRequest{
+		MenuItems: menuItemsFromPb(r.GetMenuItems()),
+		Notes:     r.GetNotes(),
+		Delivery:  deliveryFromPb(r.GetDelivery()),
+		PromoCode: r.GetPromoCode(),
+	}, actorFromContext(ctx))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return &orderpb.AddOrderResponse{Id: id}, nil
+}
+
+func (g *grpcServer) UpdateOrder(ctx context.Context, r *orderpb.UpdateOrderRequest) (*emptypb.Empty, error) {
+	err := g.srv.orderService.Update(r.GetId(), service.UpdateOrderRequest{
+		MenuItems: menuItemsFromPb(r.GetMenuItems()),
+		Notes:     r.GetNotes(),
+		Delivery:  deliveryFromPb(r.GetDelivery()),
+	}, actorFromContext(ctx))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (g *grpcServer) DeleteOrder(ctx context.Context, r *orderpb.DeleteOrderRequest) (*emptypb.Empty, error) {
+	err := g.srv.orderService.Delete(r.GetId(), actorFromContext(ctx))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (g *grpcServer) GetOrder(_ context.Context, r *orderpb.GetOrderRequest) (*orderpb.Order, error) {
+	info, err := g.srv.orderQueryService.GetOrderInfo(r.GetId())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	if info == nil {
+		return nil, grpcError(data.OrderNotFoundError)
+	}
+
+	return orderToPb(*info), nil
+}
+
+func (g *grpcServer) ListOrders(context.Context, *orderpb.ListOrdersRequest) (*orderpb.ListOrdersResponse, error) {
+	orders, err := g.srv.orderQueryService.GetOrders()
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	result := make([]*orderpb.Order, len(orders.Orders))
+	for i, info := range orders.Orders {
+		result[i] = orderToPb(info)
+	}
+
+	return &orderpb.ListOrdersResponse{Orders: result}, nil
+}
@@ -0,0 +1,71 @@
+package transport
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"orderservice/pkg/orderservice/application/data"
+	"orderservice/pkg/orderservice/transport/orderpb"
+	"testing"
+)
+
+func dialGRPC(t *testing.T, srv *server) orderpb.OrderServiceClient {
+	listener := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer()
+	orderpb.RegisterOrderServiceServer(s, &grpcServer{srv: srv})
+	go func() {
+		_ = s.Serve(listener)
+	}()
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }),
+		grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return orderpb.NewOrderServiceClient(conn)
+}
+
+func TestGRPCListOrders(t *testing.T) {
+	client := dialGRPC(t, &server{orderQueryService: mocOrderQueryService{}})
+
+	response, err := client.ListOrders(context.Background(), &orderpb.ListOrdersRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(response.Orders) != 1 || response.Orders[0].Id != "3fa85f64-5717-4562-b3fc-2c963f66afa6" {
+		t.Errorf("Orders are wrong: %v", response.Orders)
+	}
+}
+
+func TestGRPCDeleteOrderNotFound(t *testing.T) {
+	client := dialGRPC(t, &server{orderService: mocOrderService{err: data.OrderNotFoundError}})
+
+	_, err := client.DeleteOrder(context.Background(), &orderpb.DeleteOrderRequest{Id: "3fa85f64-5717-4562-b3fc-2c963f66afa6"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Status code is wrong. Have: %v, want: %v", status.Code(err), codes.NotFound)
+	}
+}
+
+func TestGRPCRateLimit(t *testing.T) {
+	interceptor := grpcIPRateLimitInterceptor(newRateLimiter(LimitsConfig{IPRate: RateLimit{Rate: 0.5, Burst: 1}}))
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5000}})
+	handler := func(context.Context, interface{}) (interface{}, error) { return "ok", nil }
+
+	if _, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Status code is wrong. Have: %v, want: %v", status.Code(err), codes.ResourceExhausted)
+	}
+}
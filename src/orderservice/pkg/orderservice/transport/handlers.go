@@ -1,14 +1,15 @@
 package transport
 
 import (
+	"crypto/tls"
 	"database/sql"
-	"encoding/json"
 	"fmt"
 	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
-	"io/ioutil"
+	"mime"
 	"net/http"
 	"orderservice/pkg/orderservice/application/data"
+	"orderservice/pkg/orderservice/application/event"
 	query2 "orderservice/pkg/orderservice/application/query"
 	"orderservice/pkg/orderservice/application/service"
 	"orderservice/pkg/orderservice/infrastructure/query"
@@ -16,9 +17,40 @@ import (
 	"time"
 )
 
+const (
+	jsonPatchMediaType  = "application/json-patch+json"
+	mergePatchMediaType = "application/merge-patch+json"
+)
+
+const defaultEventHistorySize = 1000
+
 type server struct {
-	orderService      service.OrderService
-	orderQueryService query2.OrderQueryService
+	orderService          service.OrderService
+	orderQueryService     query2.OrderQueryService
+	promoCodeService      service.PromoCodeService
+	promoCodeQueryService query2.PromoCodeQueryService
+	paymentService        service.PaymentService
+	webhookService        service.WebhookService
+	webhookQueryService   query2.WebhookQueryService
+	events                *event.Broker
+}
+
+type Config struct {
+	Pricing service.PricingConfig
+	Payment service.PaymentConfig
+	// Events is shared by REST and gRPC servers so subscribers see changes made through both
+	Events *event.Broker
+	// EventSourcedOrders switches order storage to the event store
+	EventSourcedOrders bool
+	Limits             LimitsConfig
+	Versioning         VersioningConfig
+	CORS               CORSConfig
+	SecurityHeaders    SecurityHeadersConfig
+	Auth               AuthConfig
+	// WebhookSecrets encrypts webhook signing secrets at rest
+	WebhookSecrets *repository.SecretCipher
+	// TLS is used by both servers when set, see CertReloader
+	TLS *tls.Config
 }
 
 func helloWorld(w http.ResponseWriter, _ *http.Request) {
@@ -29,21 +61,42 @@ func helloWorld(w http.ResponseWriter, _ *http.Request) {
 }
 
 func processError(w http.ResponseWriter, e error) {
-	if e == data.InternalError {
+	switch e {
+	case data.InternalError:
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-	} else {
+	case data.OrderNotFoundError, data.PromoCodeNotFoundError, data.PaymentProviderNotFoundError,
+		data.WebhookNotFoundError, data.WebhookDeliveryNotFoundError:
+		http.Error(w, "Not Found", http.StatusNotFound)
+	case data.InvalidSignatureError:
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	case data.OrderDeletedError:
+		http.Error(w, "Gone", http.StatusGone)
+	case data.OrderConflictError:
+		http.Error(w, e.Error(), http.StatusConflict)
+	default:
 		http.Error(w, e.Error(), http.StatusBadRequest)
 	}
 }
 
-func (s *server) getOrdersList(w http.ResponseWriter, _ *http.Request) {
+func (s *server) getOrdersList(w http.ResponseWriter, r *http.Request) {
 	orders, err := s.orderQueryService.GetOrders()
 	if err != nil {
 		processError(w, err)
 		return
 	}
 
-	renderJson(w, orders)
+	if isLegacyMoneyRequest(r) {
+		legacy, err := newLegacyOrdersList(*orders)
+		if err != nil {
+			log.Error(err)
+			processError(w, data.InternalError)
+			return
+		}
+		render(w, r, legacy)
+		return
+	}
+
+	render(w, r, orders)
 }
 
 func (s *server) getOrderInfo(w http.ResponseWriter, r *http.Request) {
@@ -64,7 +117,39 @@ func (s *server) getOrderInfo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	renderJson(w, info)
+	if isLegacyMoneyRequest(r) {
+		legacy, err := newLegacyOrderInfo(*info)
+		if err != nil {
+			log.Error(err)
+			processError(w, data.InternalError)
+			return
+		}
+		render(w, r, legacy)
+		return
+	}
+
+	render(w, r, info)
+}
+
+func (s *server) getOrderHistory(w http.ResponseWriter, r *http.Request) {
+	id, found := mux.Vars(r)["ID"]
+	if !found {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	history, err := s.orderQueryService.GetOrderHistory(id)
+	if err != nil {
+		processError(w, err)
+		return
+	}
+
+	if history == nil {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	render(w, r, history)
 }
 
 func (s *server) deleteOrder(w http.ResponseWriter, r *http.Request) {
@@ -74,7 +159,7 @@ func (s *server) deleteOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := s.orderService.Delete(id)
+	err := s.orderService.Delete(id, actorFromRequest(r))
 	if err != nil {
 		log.Error(err)
 		processError(w, err)
@@ -91,56 +176,85 @@ func (s *server) updateOrder(w http.ResponseWriter, r *http.Request) {
 	var orderRequest service.UpdateOrderRequest
 	err := jsonFromRequest(r, &orderRequest)
 	if err != nil {
-		http.Error(w, "Invalid Request", http.StatusBadRequest)
+		processRequestError(w, err)
 		return
 	}
 
-	err = s.orderService.Update(id, orderRequest)
+	err = s.orderService.Update(id, orderRequest, actorFromRequest(r))
 	if err != nil {
 		processError(w, err)
 	}
 }
 
-func (s *server) addOrder(w http.ResponseWriter, r *http.Request) {
-	orderRequest := service.AddOrderRequest{}
-	err := jsonFromRequest(r, &orderRequest)
-	if err != nil {
-		http.Error(w, "Invalid Request", http.StatusBadRequest)
+func (s *server) patchOrder(w http.ResponseWriter, r *http.Request) {
+	id, found := mux.Vars(r)["ID"]
+	if !found {
+		http.Error(w, "Not Found", http.StatusNotFound)
 		return
 	}
 
-	err = s.orderService.Add(orderRequest)
+	patch, ok := patchFromRequest(w, r, func(r *http.Request) (service.OrderPatch, error) {
+		var jsonPatch service.JSONPatchRequest
+		err := jsonFromRequest(r, &jsonPatch)
+		return jsonPatch, err
+	}, func(r *http.Request) (service.OrderPatch, error) {
+		var mergePatch service.MergePatchRequest
+		err := jsonFromRequest(r, &mergePatch)
+		return mergePatch, err
+	})
+	if !ok {
+		return
+	}
 
+	err := s.orderService.Patch(id, patch, actorFromRequest(r))
 	if err != nil {
 		processError(w, err)
 	}
 }
 
-func renderJson(w http.ResponseWriter, v interface{}) {
-	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(v); err != nil {
-		log.Error(err)
-		http.Error(w, "server error", http.StatusInternalServerError)
-		return
+type patchDecoder func(r *http.Request) (service.OrderPatch, error)
+
+// patchFromRequest decodes JSON Patch or JSON Merge Patch by Content-Type,
+// errors are written to the response when it returns false
+func patchFromRequest(w http.ResponseWriter, r *http.Request, jsonPatch, mergePatch patchDecoder) (service.OrderPatch, bool) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		mediaType = ""
+	}
+
+	var patch service.OrderPatch
+	switch mediaType {
+	case jsonPatchMediaType:
+		patch, err = jsonPatch(r)
+	case mergePatchMediaType:
+		patch, err = mergePatch(r)
+	default:
+		w.Header().Set("Accept-Patch", jsonPatchMediaType+", "+mergePatchMediaType)
+		http.Error(w, "Unsupported Media Type", http.StatusUnsupportedMediaType)
+		return nil, false
 	}
-}
 
-func jsonFromRequest(r *http.Request, output interface{}) error {
-	b, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		return err
+		processRequestError(w, err)
+		return nil, false
 	}
-	defer func() {
-		log.Error(r.Body.Close())
-	}()
 
-	err = json.Unmarshal(b, &output)
+	return patch, true
+}
+
+func (s *server) addOrder(w http.ResponseWriter, r *http.Request) {
+	orderRequest := service.AddOrderRequest{}
+	err := jsonFromRequest(r, &orderRequest)
 	if err != nil {
-		err = fmt.Errorf("can't parse %s to json", b)
+		processRequestError(w, err)
+		return
 	}
 
-	return err
+	_, err = s.orderService.Add(orderRequest, actorFromRequest(r))
+
+	if err != nil {
+		processError(w, err)
+	}
 }
 
 func logMiddleware(h http.Handler) http.Handler {
@@ -158,24 +272,106 @@ func logMiddleware(h http.Handler) http.Handler {
 	})
 }
 
-func Router(db *sql.DB) http.Handler {
-	srv := makeServer(db)
+// Services are application services shared by Router and GRPCServer
+type Services struct {
+	srv    *server
+	config Config
+	// limiter is shared so REST and gRPC requests take tokens from the same buckets
+	limiter *rateLimiter
+	metrics *requestMetrics
+}
+
+// NewServices builds application services once for every transport
+func NewServices(db *sql.DB, c Config) *Services {
+	return &Services{srv: makeServer(db, c), config: c, limiter: newRateLimiter(c.Limits), metrics: newRequestMetrics()}
+}
 
+// MetricsHandler serves request metrics of Router, it belongs to an internal listener
+func MetricsHandler(services *Services) http.Handler {
 	r := mux.NewRouter()
-	s := r.PathPrefix("/api/v1").Subrouter()
+	r.Handle("/metrics", services.metrics).Methods(http.MethodGet)
+
+	return r
+}
+
+func Router(services *Services) http.Handler {
+	srv, c := services.srv, services.config
+	metrics := services.metrics
+
+	r := mux.NewRouter()
+
+	v1 := r.PathPrefix(apiV1Prefix).Subrouter()
+	v1.Use(metrics.middleware("v1"), deprecationMiddleware(c.Versioning.V1DeprecatedAt, c.Versioning.V1Sunset, apiV2Prefix))
+	routeV1(v1, srv, c.Auth)
+
+	v2 := r.PathPrefix(apiV2Prefix).Subrouter()
+	v2.Use(metrics.middleware("v2"))
+	routeV2(v2, srv)
+
+	var h http.Handler = r
+	h = bodyLimitMiddleware(c.Limits.MaxBodySize, h)
+	h = principalRateLimitMiddleware(services.limiter, h)
+	h = authenticationMiddleware(c.Auth, h)
+	h = ipRateLimitMiddleware(services.limiter, h)
+	h = corsMiddleware(c.CORS, h)
+	h = securityHeadersMiddleware(c.SecurityHeaders, h)
+
+	return logMiddleware(h)
+}
+
+func routeV1(s *mux.Router, srv *server, auth AuthConfig) {
 	s.HandleFunc("/hello-world", helloWorld).Methods(http.MethodGet)
 	s.HandleFunc("/orders", srv.getOrdersList).Methods(http.MethodGet)
+	s.HandleFunc("/orders/stream", srv.streamOrders).Methods(http.MethodGet)
+	s.HandleFunc("/orders/ws", srv.streamOrdersWebSocket).Methods(http.MethodGet)
 	s.HandleFunc("/order/{ID:[0-9a-zA-Z-]+}", srv.getOrderInfo).Methods(http.MethodGet)
 	s.HandleFunc("/order/{ID:[0-9a-zA-Z-]+}", srv.deleteOrder).Methods(http.MethodDelete)
 	s.HandleFunc("/order/{ID:[0-9a-zA-Z-]+}", srv.updateOrder).Methods(http.MethodPut)
+	s.HandleFunc("/order/{ID:[0-9a-zA-Z-]+}", srv.patchOrder).Methods(http.MethodPatch)
 	s.HandleFunc("/order", srv.addOrder).Methods(http.MethodPost)
+	s.HandleFunc("/order/{ID:[0-9a-zA-Z-]+}/pay", srv.payOrder).Methods(http.MethodPost)
+	s.HandleFunc("/order/{ID:[0-9a-zA-Z-]+}/history", srv.getOrderHistory).Methods(http.MethodGet)
+	s.HandleFunc("/payments/webhook/{PROVIDER:[0-9a-zA-Z_-]+}", srv.paymentWebhook).Methods(http.MethodPost)
 
-	return logMiddleware(r)
+	wh := s.PathPrefix("/webhooks").Subrouter()
+	wh.Use(adminAuthMiddleware(auth))
+	wh.HandleFunc("", srv.getWebhooksList).Methods(http.MethodGet)
+	wh.HandleFunc("", srv.addWebhook).Methods(http.MethodPost)
+	wh.HandleFunc("/deliveries", srv.getWebhookDeliveries).Methods(http.MethodGet)
+	wh.HandleFunc("/deliveries/{ID:[0-9a-zA-Z-]+}/redeliver", srv.redeliverWebhook).Methods(http.MethodPost)
+	wh.HandleFunc("/{ID:[0-9a-zA-Z-]+}", srv.getWebhook).Methods(http.MethodGet)
+	wh.HandleFunc("/{ID:[0-9a-zA-Z-]+}", srv.updateWebhook).Methods(http.MethodPut)
+	wh.HandleFunc("/{ID:[0-9a-zA-Z-]+}", srv.deleteWebhook).Methods(http.MethodDelete)
+
+	a := s.PathPrefix("/admin").Subrouter()
+	a.Use(adminAuthMiddleware(auth))
+	a.HandleFunc("/promo-codes", srv.getPromoCodesList).Methods(http.MethodGet)
+	a.HandleFunc("/promo-codes", srv.addPromoCode).Methods(http.MethodPost)
+	a.HandleFunc("/promo-code/{CODE:[0-9a-zA-Z_-]+}", srv.getPromoCode).Methods(http.MethodGet)
+	a.HandleFunc("/promo-code/{CODE:[0-9a-zA-Z_-]+}", srv.updatePromoCode).Methods(http.MethodPut)
+	a.HandleFunc("/promo-code/{CODE:[0-9a-zA-Z_-]+}", srv.deletePromoCode).Methods(http.MethodDelete)
 }
 
-func makeServer(db *sql.DB) *server {
+func makeServer(db *sql.DB, c Config) *server {
+	events := c.Events
+	if events == nil {
+		events = event.NewBroker(defaultEventHistorySize)
+	}
+
+	orderRepository := repository.NewOrderRepository(db)
+	if c.EventSourcedOrders {
+		orderRepository = repository.NewEventSourcedOrderRepository(db)
+	}
+	promoCodeRepository := repository.NewPromoCodeRepository(db)
+	paymentService := service.NewPaymentService(orderRepository, repository.NewPaymentRepository(db), c.Payment, events)
 	return &server{
-		orderService:      service.NewOrderService(repository.NewOrderRepository(db)),
-		orderQueryService: query.NewOrderQueryService(db),
+		orderService:          service.NewOrderService(orderRepository, promoCodeRepository, c.Pricing, paymentService, events),
+		orderQueryService:     query.NewOrderQueryService(db),
+		promoCodeService:      service.NewPromoCodeService(promoCodeRepository),
+		promoCodeQueryService: query.NewPromoCodeQueryService(db),
+		paymentService:        paymentService,
+		webhookService:        service.NewWebhookService(repository.NewWebhookRepository(db, c.WebhookSecrets), repository.NewWebhookDeliveryRepository(db)),
+		webhookQueryService:   query.NewWebhookQueryService(db),
+		events:                events,
 	}
 }
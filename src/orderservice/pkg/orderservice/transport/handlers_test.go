@@ -2,10 +2,13 @@ package transport
 
 import (
 	"encoding/json"
+	"github.com/gorilla/mux"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"orderservice/pkg/orderservice/application/data"
+	"orderservice/pkg/orderservice/application/service"
+	"strings"
 	"testing"
 )
 
@@ -23,6 +26,17 @@ func (m mocOrderQueryService) GetOrderInfo(id string) (*data.OrderInfo, error) {
 	panic("implement me")
 }
 
+func (m mocOrderQueryService) GetOrderHistory(id string) (*data.OrderHistory, error) {
+	if id != "3fa85f64-5717-4562-b3fc-2c963f66afa6" {
+		return nil, nil
+	}
+
+	return &data.OrderHistory{
+		OrderID:   id,
+		Revisions: []data.OrderRevision{{Revision: 1, Action: "created", Actor: "anonymous"}},
+	}, nil
+}
+
 func TestOrdersList(t *testing.T) {
 	srv := server{orderQueryService: mocOrderQueryService{}}
 	w := httptest.NewRecorder()
@@ -43,3 +57,117 @@ func TestOrdersList(t *testing.T) {
 		t.Errorf("Can't parse json: %s response with error %v", jsonString, err)
 	}
 }
+
+type mocOrderService struct {
+	err error
+}
+
+func (m mocOrderService) Add(service.AddOrderRequest, string) (string, error) {
+	return "3fa85f64-5717-4562-b3fc-2c963f66afa6", m.err
+}
+
+func (m mocOrderService) Update(string, service.UpdateOrderRequest, string) error {
+	return m.err
+}
+
+func (m mocOrderService) Patch(string, service.OrderPatch, string) error {
+	return m.err
+}
+
+func (m mocOrderService) Delete(string, string) error {
+	return m.err
+}
+
+func TestDeleteOrderStatus(t *testing.T) {
+	cases := map[error]int{
+		nil:                     http.StatusOK,
+		data.OrderNotFoundError: http.StatusNotFound,
+		data.OrderDeletedError:  http.StatusGone,
+		data.InternalError:      http.StatusInternalServerError,
+	}
+
+	for e, status := range cases {
+		srv := server{orderService: mocOrderService{err: e}}
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodDelete, "/api/v1/order/3fa85f64-5717-4562-b3fc-2c963f66afa6", nil)
+		r = mux.SetURLVars(r, map[string]string{"ID": "3fa85f64-5717-4562-b3fc-2c963f66afa6"})
+		srv.deleteOrder(w, r)
+		if w.Code != status {
+			t.Errorf("Status code is wrong for %v. Have: %d, want: %d", e, w.Code, status)
+		}
+	}
+}
+
+func TestUpdateOrderStatus(t *testing.T) {
+	cases := map[error]int{
+		nil:                     http.StatusOK,
+		data.OrderNotFoundError: http.StatusNotFound,
+		data.OrderDeletedError:  http.StatusGone,
+		data.OrderConflictError: http.StatusConflict,
+	}
+
+	for e, status := range cases {
+		srv := server{orderService: mocOrderService{err: e}}
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPut, "/api/v1/order/3fa85f64-5717-4562-b3fc-2c963f66afa6", strings.NewReader(`{"menuItems":[]}`))
+		r = mux.SetURLVars(r, map[string]string{"ID": "3fa85f64-5717-4562-b3fc-2c963f66afa6"})
+		srv.updateOrder(w, r)
+		if w.Code != status {
+			t.Errorf("Status code is wrong for %v. Have: %d, want: %d", e, w.Code, status)
+		}
+	}
+}
+
+func TestPatchOrderContentType(t *testing.T) {
+	cases := map[string]bool{
+		"application/json-patch+json":  true,
+		"application/merge-patch+json": true,
+		"application/json":             false,
+	}
+	versions := map[string]int{"v1": http.StatusOK, "v2": http.StatusNoContent}
+
+	for contentType, accepted := range cases {
+		for version, okStatus := range versions {
+			srv := server{orderService: mocOrderService{}}
+			w := httptest.NewRecorder()
+			body := `{"menuItems":{}}`
+			if contentType == "application/json-patch+json" {
+				body = `[]`
+			}
+			r := httptest.NewRequest(http.MethodPatch, "/api/"+version+"/order/3fa85f64-5717-4562-b3fc-2c963f66afa6", strings.NewReader(body))
+			r.Header.Set("Content-Type", contentType)
+			r = mux.SetURLVars(r, map[string]string{"ID": "3fa85f64-5717-4562-b3fc-2c963f66afa6"})
+			if version == "v1" {
+				srv.patchOrder(w, r)
+			} else {
+				srv.patchOrderV2(w, r)
+			}
+
+			status := http.StatusUnsupportedMediaType
+			if accepted {
+				status = okStatus
+			}
+			if w.Code != status {
+				t.Errorf("Status code of %s is wrong for %s. Have: %d, want: %d", version, contentType, w.Code, status)
+			}
+		}
+	}
+}
+
+func TestOrderHistory(t *testing.T) {
+	cases := map[string]int{
+		"3fa85f64-5717-4562-b3fc-2c963f66afa6": http.StatusOK,
+		"0b0e2c7e-1e0c-4a8c-9d0e-5f2f4c1d8a11": http.StatusNotFound,
+	}
+
+	for id, status := range cases {
+		srv := server{orderQueryService: mocOrderQueryService{}}
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/order/"+id+"/history", nil)
+		r = mux.SetURLVars(r, map[string]string{"ID": id})
+		srv.getOrderHistory(w, r)
+		if w.Code != status {
+			t.Errorf("Status code is wrong for %s. Have: %d, want: %d", id, w.Code, status)
+		}
+	}
+}
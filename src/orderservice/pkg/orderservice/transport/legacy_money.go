@@ -0,0 +1,88 @@
+package transport
+
+import (
+	"fmt"
+	"net/http"
+	"orderservice/pkg/orderservice/application/data"
+	"orderservice/pkg/orderservice/model"
+	"strings"
+)
+
+// Old clients expect money as integer minor units, they opt in with
+// "?moneyFormat=legacy" or "X-Money-Format: legacy" header
+const legacyMoneyFormat = "legacy"
+
+type legacyPricing struct {
+	Subtotal  int64  `json:"subtotal"`
+	Discount  int64  `json:"discount"`
+	Tax       int64  `json:"tax"`
+	Total     int64  `json:"total"`
+	PromoCode string `json:"promoCode,omitempty"`
+}
+
+// legacyOrderInfo renders OrderInfo with money fields in minor units
+type legacyOrderInfo struct {
+	Cost    int64         `json:"cost"`
+	Pricing legacyPricing `json:"pricing"`
+	// inline tag makes msgpack shadow Cost and Pricing of OrderInfo the same way as JSON does
+	data.OrderInfo `json:",inline"`
+}
+
+type legacyOrdersList struct {
+	Orders []legacyOrderInfo `json:"orders"`
+}
+
+func isLegacyMoneyRequest(r *http.Request) bool {
+	if r == nil {
+		return false
+	}
+
+	return strings.EqualFold(r.URL.Query().Get("moneyFormat"), legacyMoneyFormat) ||
+		strings.EqualFold(r.Header.Get("X-Money-Format"), legacyMoneyFormat)
+}
+
+// legacyAmount converts rendered money back to minor units, money is always rendered by model.Money
+func legacyAmount(m data.Money) (int64, error) {
+	money, err := model.ParseMoney(m.Amount, m.Currency)
+	if err != nil {
+		return 0, err
+	}
+
+	return money.Amount, nil
+}
+
+func newLegacyOrderInfo(info data.OrderInfo) (legacyOrderInfo, error) {
+	amounts := make([]int64, 5)
+	for i, m := range []data.Money{info.Cost, info.Pricing.Subtotal, info.Pricing.Discount, info.Pricing.Tax, info.Pricing.Total} {
+		amount, err := legacyAmount(m)
+		if err != nil {
+			return legacyOrderInfo{}, fmt.Errorf("order %s has invalid money: %s", info.ID, err)
+		}
+		amounts[i] = amount
+	}
+
+	return legacyOrderInfo{
+		Cost: amounts[0],
+		Pricing: legacyPricing{
+			Subtotal:  amounts[1],
+			Discount:  amounts[2],
+			Tax:       amounts[3],
+			Total:     amounts[4],
+			PromoCode: info.Pricing.PromoCode,
+		},
+		OrderInfo: info,
+	}, nil
+}
+
+func newLegacyOrdersList(list data.OrdersList) (legacyOrdersList, error) {
+	orders := make([]legacyOrderInfo, len(list.Orders))
+	for i, info := range list.Orders {
+		order, err := newLegacyOrderInfo(info)
+		if err != nil {
+			return legacyOrdersList{}, err
+		}
+		orders[i] = order
+	}
+
+	return legacyOrdersList{Orders: orders}, nil
+}
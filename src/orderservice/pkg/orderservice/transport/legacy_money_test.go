@@ -0,0 +1,55 @@
+package transport
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/vmihailenco/msgpack/v5"
+	"orderservice/pkg/orderservice/application/data"
+	"testing"
+)
+
+func TestLegacyOrderInfo(t *testing.T) {
+	zero := data.Money{Amount: "0.00", Currency: "EUR"}
+	info := data.OrderInfo{
+		ID:      "3fa85f64-5717-4562-b3fc-2c963f66afa6",
+		Cost:    data.Money{Amount: "12.50", Currency: "EUR"},
+		Pricing: data.Pricing{Subtotal: zero, Discount: zero, Tax: zero, Total: data.Money{Amount: "12.50", Currency: "EUR"}},
+		Status:  "created",
+	}
+
+	legacy, err := newLegacyOrderInfo(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded struct {
+		ID      string `json:"id"`
+		Cost    int64  `json:"cost"`
+		Pricing struct {
+			Total int64 `json:"total"`
+		} `json:"pricing"`
+	}
+	b, _, err := encodeJson(legacy)
+	if err == nil {
+		err = json.Unmarshal(b, &decoded)
+	}
+	if err != nil || decoded.ID != info.ID || decoded.Cost != 1250 || decoded.Pricing.Total != 1250 {
+		t.Errorf("Legacy json is wrong: %s, error: %v", b, err)
+	}
+
+	decoded.Cost = 0
+	b, _, err = encodeMsgpack(legacy)
+	if err == nil {
+		dec := msgpack.NewDecoder(bytes.NewReader(b))
+		dec.SetCustomStructTag("json")
+		err = dec.Decode(&decoded)
+	}
+	if err != nil || decoded.Cost != 1250 {
+		t.Errorf("Legacy msgpack is wrong, cost: %d, error: %v", decoded.Cost, err)
+	}
+
+	info.Cost.Amount = "12.5.0"
+	if _, err = newLegacyOrderInfo(info); err == nil {
+		t.Error("Invalid money must fail instead of rendering zero")
+	}
+}
@@ -0,0 +1,58 @@
+package transport
+
+import (
+	"errors"
+	"io"
+	"net/http"
+)
+
+var errRequestTooLarge = errors.New("request body too large")
+
+// limitedBody fails reading after limit bytes so handlers can tell oversized requests from malformed ones
+type limitedBody struct {
+	body      io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		// one more byte is read to tell a body of exactly limit size from a larger one
+		var probe [1]byte
+		n, err := b.body.Read(probe[:])
+		if n > 0 {
+			return 0, errRequestTooLarge
+		}
+		return 0, err
+	}
+
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+
+	n, err := b.body.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	return b.body.Close()
+}
+
+// bodyLimitMiddleware rejects requests with declared size over limit and caps reading of the others
+func bodyLimitMiddleware(limit int64, h http.Handler) http.Handler {
+	if limit <= 0 {
+		return h
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > limit {
+			http.Error(w, "Request Entity Too Large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = &limitedBody{body: r.Body, remaining: limit}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,144 @@
+package transport
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestBodyLimit(t *testing.T) {
+	cases := map[string]int{
+		`{"menuItems":[]}`: http.StatusOK,
+		`{"menuItems":[], "notes": "` + strings.Repeat("a", 64) + `"}`: http.StatusRequestEntityTooLarge,
+	}
+
+	for body, status := range cases {
+		srv := server{orderService: mocOrderService{}}
+		h := bodyLimitMiddleware(32, http.HandlerFunc(srv.addOrder))
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/order", strings.NewReader(body))
+		r.ContentLength = -1 // unknown size, the body is checked while reading
+		h.ServeHTTP(w, r)
+		if w.Code != status {
+			t.Errorf("Status code is wrong for %s. Have: %d, want: %d", body, w.Code, status)
+		}
+	}
+}
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	limit := RateLimit{Rate: 1, Burst: 2}
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if result, _ := store.Take("ip:1", limit, now); !result.Allowed {
+			t.Fatalf("Request %d is rejected within burst", i)
+		}
+	}
+
+	result, _ := store.Take("ip:1", limit, now)
+	if result.Allowed || result.RetryAfter != time.Second {
+		t.Errorf("Request over burst is wrong: %v", result)
+	}
+
+	if result, _ = store.Take("ip:2", limit, now); !result.Allowed {
+		t.Errorf("Bucket is shared between keys")
+	}
+
+	if result, _ = store.Take("ip:1", limit, now.Add(time.Second)); !result.Allowed {
+		t.Errorf("Bucket isn't refilled")
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	h := ipRateLimitMiddleware(newRateLimiter(LimitsConfig{IPRate: RateLimit{Rate: 0.5, Burst: 1}}), http.HandlerFunc(helloWorld))
+
+	codes := make([]int, 2)
+	var w *httptest.ResponseRecorder
+	for i := range codes {
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/hello-world", nil))
+		codes[i] = w.Code
+	}
+
+	if codes[0] != http.StatusOK || codes[1] != http.StatusTooManyRequests {
+		t.Errorf("Status codes are wrong. Have: %v, want: [200 429]", codes)
+	}
+
+	if w.Header().Get("Retry-After") != "2" || w.Header().Get("RateLimit-Limit") != "1" || w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("Rate limit headers are wrong: %v", w.Header())
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	cases := []struct {
+		forwardedFor   string
+		trustedProxies int
+		ip             string
+	}{
+		{"1.1.1.1", 0, "10.0.0.1"},
+		{"6.6.6.6, 1.1.1.1", 1, "1.1.1.1"},
+		{"6.6.6.6, 1.1.1.1, 10.0.0.2", 2, "1.1.1.1"},
+		{"1.1.1.1", 3, "1.1.1.1"},
+		{"", 1, "10.0.0.1"},
+	}
+
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/hello-world", nil)
+		r.RemoteAddr = "10.0.0.1:5000"
+		if c.forwardedFor != "" {
+			r.Header.Set("X-Forwarded-For", c.forwardedFor)
+		}
+		if ip := clientIP(r, c.trustedProxies); ip != c.ip {
+			t.Errorf("Client ip of %q with %d proxies is wrong. Have: %s, want: %s", c.forwardedFor, c.trustedProxies, ip, c.ip)
+		}
+	}
+}
+
+func TestPrincipalRateLimitNeedsAuthentication(t *testing.T) {
+	limiter := newRateLimiter(LimitsConfig{PrincipalRate: RateLimit{Rate: 0.5, Burst: 1}})
+	h := authenticationMiddleware(AuthConfig{APITokens: map[string]string{"token": "alice"}},
+		principalRateLimitMiddleware(limiter, http.HandlerFunc(helloWorld)))
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/hello-world", nil)
+		r.Header.Set("X-User-ID", "alice")
+		h.ServeHTTP(w, r)
+		if w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "" {
+			t.Errorf("Spoofed principal is limited. Have: %d %v", w.Code, w.Header())
+		}
+	}
+
+	codes := make([]int, 2)
+	for i := range codes {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/hello-world", nil)
+		r.Header.Set("Authorization", "Bearer token")
+		h.ServeHTTP(w, r)
+		codes[i] = w.Code
+	}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusTooManyRequests {
+		t.Errorf("Status codes are wrong. Have: %v, want: [200 429]", codes)
+	}
+}
+
+func TestInvalidTokensAreRateLimited(t *testing.T) {
+	limiter := newRateLimiter(LimitsConfig{IPRate: RateLimit{Rate: 0.5, Burst: 1}, PrincipalRate: RateLimit{Rate: 0.5, Burst: 1}})
+	h := ipRateLimitMiddleware(limiter, authenticationMiddleware(AuthConfig{APITokens: map[string]string{"token": "alice"}},
+		principalRateLimitMiddleware(limiter, http.HandlerFunc(helloWorld))))
+
+	codes := make([]int, 2)
+	for i := range codes {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/hello-world", nil)
+		r.Header.Set("Authorization", "Bearer guess")
+		h.ServeHTTP(w, r)
+		codes[i] = w.Code
+	}
+	if codes[0] != http.StatusUnauthorized || codes[1] != http.StatusTooManyRequests {
+		t.Errorf("Status codes are wrong. Have: %v, want: [401 429]", codes)
+	}
+}
@@ -0,0 +1,140 @@
+package transport
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/gorilla/mux"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+type requestKey struct {
+	version string
+	method  string
+	code    int
+}
+
+type requestStats struct {
+	count       uint64
+	durationSum float64
+}
+
+// requestMetrics counts requests per API version and serves them in Prometheus text format
+type requestMetrics struct {
+	mu       sync.Mutex
+	requests map[requestKey]*requestStats
+}
+
+func newRequestMetrics() *requestMetrics {
+	return &requestMetrics{requests: map[requestKey]*requestStats{}}
+}
+
+// statusRecorder keeps Flusher and Hijacker of the wrapped writer for event streams and WebSocket
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if r.code == 0 {
+		r.code = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.code == 0 {
+		r.code = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("hijacking is not supported")
+	}
+
+	// hijacked connections are upgraded to WebSocket
+	r.code = http.StatusSwitchingProtocols
+	return h.Hijack()
+}
+
+func (m *requestMetrics) middleware(version string) mux.MiddlewareFunc {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			startTime := time.Now()
+			recorder := &statusRecorder{ResponseWriter: w}
+			h.ServeHTTP(recorder, r)
+
+			code := recorder.code
+			if code == 0 {
+				code = http.StatusOK
+			}
+			m.observe(requestKey{version: version, method: r.Method, code: code}, time.Since(startTime))
+		})
+	}
+}
+
+func (m *requestMetrics) observe(key requestKey, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats, found := m.requests[key]
+	if !found {
+		stats = &requestStats{}
+		m.requests[key] = stats
+	}
+
+	stats.count++
+	stats.durationSum += duration.Seconds()
+}
+
+func (m *requestMetrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	m.mu.Lock()
+	keys := make([]requestKey, 0, len(m.requests))
+	stats := make(map[requestKey]requestStats, len(m.requests))
+	for key, s := range m.requests {
+		keys = append(keys, key)
+		stats[key] = *s
+	}
+	m.mu.Unlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.version != b.version {
+			return a.version < b.version
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.code < b.code
+	})
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "# HELP http_requests_total Requests by API version, method and status code.")
+	fmt.Fprintln(bw, "# TYPE http_requests_total counter")
+	for _, key := range keys {
+		fmt.Fprintf(bw, "http_requests_total{version=%q,method=%q,code=\"%d\"} %d\n", key.version, key.method, key.code, stats[key].count)
+	}
+
+	fmt.Fprintln(bw, "# HELP http_request_duration_seconds Request duration by API version, method and status code.")
+	fmt.Fprintln(bw, "# TYPE http_request_duration_seconds summary")
+	for _, key := range keys {
+		labels := fmt.Sprintf("version=%q,method=%q,code=\"%d\"", key.version, key.method, key.code)
+		fmt.Fprintf(bw, "http_request_duration_seconds_sum{%s} %g\n", labels, stats[key].durationSum)
+		fmt.Fprintf(bw, "http_request_duration_seconds_count{%s} %d\n", labels, stats[key].count)
+	}
+
+	_ = bw.Flush()
+}
@@ -0,0 +1,51 @@
+package transport
+
+import (
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net/http"
+)
+
+const signatureHeader = "X-Signature"
+
+func (s *server) payOrder(w http.ResponseWriter, r *http.Request) {
+	id, found := mux.Vars(r)["ID"]
+	if !found {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	payment, err := s.paymentService.Pay(id, actorFromRequest(r))
+	if err != nil {
+		processError(w, err)
+		return
+	}
+
+	render(w, r, payment)
+}
+
+func (s *server) paymentWebhook(w http.ResponseWriter, r *http.Request) {
+	provider, found := mux.Vars(r)["PROVIDER"]
+	if !found {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	// signature is calculated over raw bytes, so body is not decoded before verification
+	payload, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		processRequestError(w, err)
+		return
+	}
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			log.Error(err)
+		}
+	}()
+
+	err = s.paymentService.HandleWebhook(provider, payload, r.Header.Get(signatureHeader))
+	if err != nil {
+		processError(w, err)
+	}
+}
@@ -0,0 +1,85 @@
+package transport
+
+import (
+	"github.com/gorilla/mux"
+	"net/http"
+	"orderservice/pkg/orderservice/application/service"
+)
+
+func (s *server) getPromoCodesList(w http.ResponseWriter, r *http.Request) {
+	promoCodes, err := s.promoCodeQueryService.GetPromoCodes()
+	if err != nil {
+		processError(w, err)
+		return
+	}
+
+	render(w, r, promoCodes)
+}
+
+func (s *server) getPromoCode(w http.ResponseWriter, r *http.Request) {
+	code, found := mux.Vars(r)["CODE"]
+	if !found {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	promoCode, err := s.promoCodeQueryService.GetPromoCode(code)
+	if err != nil {
+		processError(w, err)
+		return
+	}
+
+	if promoCode == nil {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	render(w, r, promoCode)
+}
+
+func (s *server) addPromoCode(w http.ResponseWriter, r *http.Request) {
+	var promoCodeRequest service.AddPromoCodeRequest
+	err := jsonFromRequest(r, &promoCodeRequest)
+	if err != nil {
+		processRequestError(w, err)
+		return
+	}
+
+	err = s.promoCodeService.Add(promoCodeRequest)
+	if err != nil {
+		processError(w, err)
+	}
+}
+
+func (s *server) updatePromoCode(w http.ResponseWriter, r *http.Request) {
+	code, found := mux.Vars(r)["CODE"]
+	if !found {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	var promoCodeRequest service.PromoCodeRequest
+	err := jsonFromRequest(r, &promoCodeRequest)
+	if err != nil {
+		processRequestError(w, err)
+		return
+	}
+
+	err = s.promoCodeService.Update(code, promoCodeRequest)
+	if err != nil {
+		processError(w, err)
+	}
+}
+
+func (s *server) deletePromoCode(w http.ResponseWriter, r *http.Request) {
+	code, found := mux.Vars(r)["CODE"]
+	if !found {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	err := s.promoCodeService.Delete(code)
+	if err != nil {
+		processError(w, err)
+	}
+}
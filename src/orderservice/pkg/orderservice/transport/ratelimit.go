@@ -0,0 +1,295 @@
+package transport
+
+import (
+	"context"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const bucketSweepInterval = time.Minute
+
+// RateLimit is a token bucket refilled with Rate tokens per second up to Burst
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+func (l RateLimit) enabled() bool {
+	return l.Rate > 0 && l.Burst > 0
+}
+
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time until the next token when the request isn't allowed
+	RetryAfter time.Duration
+}
+
+// RateLimitStore keeps buckets, a shared implementation lets several instances enforce a common limit
+type RateLimitStore interface {
+	Take(key string, limit RateLimit, now time.Time) (RateLimitResult, error)
+}
+
+type LimitsConfig struct {
+	// MaxBodySize is in bytes, 0 disables the limit
+	MaxBodySize   int64
+	IPRate        RateLimit
+	PrincipalRate RateLimit
+	// TrustedProxies is the number of proxies in front of the service appending to X-Forwarded-For,
+	// the client ip is the entry added by the farthest of them, 0 ignores the header
+	TrustedProxies int
+	// Store is in-memory when nil
+	Store RateLimitStore
+}
+
+type tokenBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	fullAt    time.Time
+}
+
+type memoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	sweptAt time.Time
+}
+
+func NewMemoryRateLimitStore() RateLimitStore {
+	return &memoryRateLimitStore{buckets: map[string]*tokenBucket{}}
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+func (s *memoryRateLimitStore) Take(key string, limit RateLimit, now time.Time) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	burst := float64(limit.Burst)
+	b, found := s.buckets[key]
+	if !found {
+		b = &tokenBucket{tokens: burst, updatedAt: now}
+		s.buckets[key] = b
+	}
+
+	if elapsed := now.Sub(b.updatedAt).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(burst, b.tokens+elapsed*limit.Rate)
+		b.updatedAt = now
+	}
+
+	var result RateLimitResult
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - b.tokens) / limit.Rate)
+	}
+
+	result.Remaining = int(b.tokens)
+	result.Reset = secondsToDuration((burst - b.tokens) / limit.Rate)
+	b.fullAt = now.Add(result.Reset)
+
+	s.sweep(now)
+	return result, nil
+}
+
+// sweep drops full buckets, they are the same as missing ones
+func (s *memoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.sweptAt) < bucketSweepInterval {
+		return
+	}
+
+	s.sweptAt = now
+	for key, b := range s.buckets {
+		if !now.Before(b.fullAt) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// clientIP counts trusted proxies from the right, entries left of them are set by the client
+func clientIP(r *http.Request, trustedProxies int) string {
+	if trustedProxies > 0 {
+		if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+			entries := strings.Split(strings.Join(forwarded, ","), ",")
+			i := len(entries) - trustedProxies
+			if i < 0 {
+				i = 0
+			}
+			if ip := strings.TrimSpace(entries[i]); ip != "" {
+				return ip
+			}
+		}
+	}
+
+	return hostOf(r.RemoteAddr)
+}
+
+func hostOf(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+
+	return host
+}
+
+func isStricter(a, b RateLimitResult) bool {
+	if a.Allowed != b.Allowed {
+		return !a.Allowed
+	}
+
+	return a.Remaining < b.Remaining
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
+
+// rateLimiter checks the client ip bucket before authentication and the bucket of the
+// authenticated principal after it, so requests with invalid tokens are limited too
+type rateLimiter struct {
+	config LimitsConfig
+	store  RateLimitStore
+}
+
+func newRateLimiter(c LimitsConfig) *rateLimiter {
+	store := c.Store
+	if store == nil {
+		store = NewMemoryRateLimitStore()
+	}
+
+	return &rateLimiter{config: c, store: store}
+}
+
+// take returns nil when the bucket isn't checked
+func (l *rateLimiter) take(key string, limit RateLimit) *RateLimitResult {
+	if !limit.enabled() {
+		return nil
+	}
+
+	result, err := l.store.Take(key, limit, time.Now())
+	if err != nil {
+		// limiting is best effort, a broken shared store must not take the service down
+		log.Error(err)
+		return nil
+	}
+
+	return &result
+}
+
+// ipRateLimitKey keeps the client ip result for the principal check of the same request
+type ipRateLimitKey struct{}
+
+// writeRateLimit sets RateLimit-* headers, it rejects the request and reports false when it isn't allowed
+func writeRateLimit(w http.ResponseWriter, result RateLimitResult, limit RateLimit) bool {
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(limit.Burst))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	w.Header().Set("RateLimit-Reset", ceilSeconds(result.Reset))
+
+	if !result.Allowed {
+		w.Header().Set("Retry-After", ceilSeconds(result.RetryAfter))
+		http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+		return false
+	}
+
+	return true
+}
+
+// ipRateLimitMiddleware goes before authenticationMiddleware
+func ipRateLimitMiddleware(l *rateLimiter, h http.Handler) http.Handler {
+	if !l.config.IPRate.enabled() {
+		return h
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		result := l.take("ip:"+clientIP(r, l.config.TrustedProxies), l.config.IPRate)
+		if result == nil {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		if writeRateLimit(w, *result, l.config.IPRate) {
+			h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ipRateLimitKey{}, *result)))
+		}
+	})
+}
+
+// principalRateLimitMiddleware goes after authenticationMiddleware,
+// its headers replace the client ip ones when the principal bucket is more exhausted
+func principalRateLimitMiddleware(l *rateLimiter, h http.Handler) http.Handler {
+	if !l.config.PrincipalRate.enabled() {
+		return h
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, authenticated := principalFromContext(r.Context())
+		if !authenticated {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		result := l.take("principal:"+principal, l.config.PrincipalRate)
+		ipResult, ipChecked := r.Context().Value(ipRateLimitKey{}).(RateLimitResult)
+		if result == nil || (ipChecked && !isStricter(*result, ipResult)) {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		if writeRateLimit(w, *result, l.config.PrincipalRate) {
+			h.ServeHTTP(w, r)
+		}
+	})
+}
+
+func grpcRateLimitError(ctx context.Context, result RateLimitResult) error {
+	_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", ceilSeconds(result.RetryAfter)))
+	return status.Error(codes.ResourceExhausted, "too many requests")
+}
+
+// grpcIPRateLimitInterceptor uses the peer address, gRPC clients connect without proxies adding headers,
+// it goes before grpcAuthenticationInterceptor
+func grpcIPRateLimitInterceptor(l *rateLimiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		var ip string
+		if p, ok := peer.FromContext(ctx); ok {
+			ip = hostOf(p.Addr.String())
+		}
+
+		if result := l.take("ip:"+ip, l.config.IPRate); result != nil && !result.Allowed {
+			return nil, grpcRateLimitError(ctx, *result)
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// grpcPrincipalRateLimitInterceptor goes after grpcAuthenticationInterceptor
+func grpcPrincipalRateLimitInterceptor(l *rateLimiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		principal, authenticated := principalFromContext(ctx)
+		if !authenticated {
+			return handler(ctx, req)
+		}
+
+		if result := l.take("principal:"+principal, l.config.PrincipalRate); result != nil && !result.Allowed {
+			return nil, grpcRateLimitError(ctx, *result)
+		}
+
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,179 @@
+package transport
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"orderservice/pkg/orderservice/application/data"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	streamHeartbeatInterval = 15 * time.Second
+	streamWriteTimeout      = 10 * time.Second
+	sseRetryMilliseconds    = 3000
+)
+
+var upgrader = websocket.Upgrader{ReadBufferSize: 1024, WriteBufferSize: 1024}
+
+// lastEventID is taken from the header sent by EventSource on reconnect,
+// WebSocket clients can't set headers so they pass it in query
+func lastEventID(r *http.Request) uint64 {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("lastEventId")
+	}
+
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return id
+}
+
+// orderIDFilter accepts "?orderId=a&orderId=b" and "?orderId=a,b", nil means all orders
+func orderIDFilter(r *http.Request) func(data.OrderEvent) bool {
+	ids := map[string]bool{}
+	for _, value := range r.URL.Query()["orderId"] {
+		for _, id := range strings.Split(value, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids[strings.ToLower(id)] = true
+			}
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return func(e data.OrderEvent) bool {
+		return ids[strings.ToLower(e.OrderID)]
+	}
+}
+
+func writeSSE(w http.ResponseWriter, e data.OrderEvent) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, b)
+	return err
+}
+
+func (s *server) streamOrders(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming Unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	sub, replay := s.events.Subscribe(lastEventID(r), orderIDFilter(r))
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", sseRetryMilliseconds); err != nil {
+		return
+	}
+
+	for _, e := range replay {
+		if err := writeSSE(w, e); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if err := writeSSE(w, e); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+func (s *server) streamOrdersWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Debug(err) // upgrader has already replied with an error
+		return
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			log.Debug(err)
+		}
+	}()
+
+	sub, replay := s.events.Subscribe(lastEventID(r), orderIDFilter(r))
+	defer sub.Close()
+
+	// client messages are ignored, reading is needed to process control frames and detect close
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	writeEvent := func(e data.OrderEvent) error {
+		if err := conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout)); err != nil {
+			return err
+		}
+		return conn.WriteJSON(e)
+	}
+
+	for _, e := range replay {
+		if err = writeEvent(e); err != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case e, ok := <-sub.C:
+			if !ok {
+				_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(streamWriteTimeout))
+				return
+			}
+			if err = writeEvent(e); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package transport
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"orderservice/pkg/orderservice/application/data"
+	"orderservice/pkg/orderservice/application/event"
+	"strings"
+	"testing"
+)
+
+func TestStreamOrdersReplaysAndFilters(t *testing.T) {
+	broker := event.NewBroker(10)
+	broker.Publish(data.OrderEvent{Type: data.OrderCreatedEvent, OrderID: "a"})
+	broker.Publish(data.OrderEvent{Type: data.OrderCreatedEvent, OrderID: "b"})
+	broker.Publish(data.OrderEvent{Type: data.OrderUpdatedEvent, OrderID: "a"})
+
+	srv := server{events: broker}
+	ts := httptest.NewServer(http.HandlerFunc(srv.streamOrders))
+	defer ts.Close()
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"?orderId=a", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Last-Event-ID", "1")
+
+	response, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	if contentType := response.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Errorf("Content type is wrong: %s", contentType)
+	}
+
+	broker.Publish(data.OrderEvent{Type: data.OrderDeletedEvent, OrderID: "b"})
+	broker.Publish(data.OrderEvent{Type: data.OrderDeletedEvent, OrderID: "a"})
+
+	ids := make([]string, 0)
+	scanner := bufio.NewScanner(response.Body)
+	for len(ids) < 2 && scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "id: ") {
+			ids = append(ids, strings.TrimPrefix(scanner.Text(), "id: "))
+		}
+	}
+
+	if strings.Join(ids, ",") != "3,5" {
+		t.Errorf("Streamed event ids are wrong: %v", ids)
+	}
+}
@@ -0,0 +1,161 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile enables mutual TLS, clients must present a certificate signed by one of its CAs
+	ClientCAFile string
+}
+
+// CertReloader serves certificates from files and reloads them when the files change,
+// so renewed certificates are used without restart
+type CertReloader struct {
+	c       TLSConfig
+	mu      sync.RWMutex
+	config  *tls.Config
+	modTime time.Time
+}
+
+func NewCertReloader(c TLSConfig) (*CertReloader, error) {
+	r := &CertReloader{c: c}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// ServerConfig returns configuration for http.Server and gRPC, every handshake uses the latest files
+func (r *CertReloader) ServerConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			return r.config, nil
+		},
+		// http.Server requires a certificate source in the top level config
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			return &r.config.Certificates[0], nil
+		},
+	}
+}
+
+// Watch checks files every interval until ctx is done, failed reloads keep the previous certificates
+func (r *CertReloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := r.changed()
+			if err != nil {
+				log.Error(err)
+				continue
+			}
+			if !changed {
+				continue
+			}
+
+			if err = r.reload(); err != nil {
+				log.Error(err)
+				continue
+			}
+			log.Info("tls certificates reloaded")
+		}
+	}
+}
+
+func (r *CertReloader) files() []string {
+	files := []string{r.c.CertFile, r.c.KeyFile}
+	if r.c.ClientCAFile != "" {
+		files = append(files, r.c.ClientCAFile)
+	}
+
+	return files
+}
+
+// latestModTime is compared instead of contents, renewal tools replace files as a whole
+func (r *CertReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+
+	return latest, nil
+}
+
+func (r *CertReloader) changed() (bool, error) {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return !modTime.Equal(r.modTime), nil
+}
+
+func (r *CertReloader) reload() error {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.c.CertFile, r.c.KeyFile)
+	if err != nil {
+		return err
+	}
+
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+
+	if r.c.ClientCAFile != "" {
+		pem, err := ioutil.ReadFile(r.c.ClientCAFile)
+		if err != nil {
+			return err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates in %s", r.c.ClientCAFile)
+		}
+
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.config = config
+	r.modTime = modTime
+
+	return nil
+}
@@ -0,0 +1,263 @@
+package transport
+
+import (
+	"encoding/json"
+	"orderservice/pkg/orderservice/application/data"
+	"orderservice/pkg/orderservice/application/service"
+	"time"
+)
+
+// v2 DTOs are mapped from application data explicitly, so application types can change
+// without breaking v2 clients and v1 quirks aren't carried over
+
+type menuItemV2 struct {
+	ID        string   `json:"id"`
+	Quantity  int      `json:"quantity"`
+	Modifiers []string `json:"modifiers"`
+	Comment   string   `json:"comment"`
+}
+
+type deliveryV2 struct {
+	Type    string `json:"type"`
+	Address string `json:"address"`
+	Phone   string `json:"phone"`
+}
+
+type moneyV2 struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+type pricingV2 struct {
+	Subtotal  moneyV2 `json:"subtotal"`
+	Discount  moneyV2 `json:"discount"`
+	Tax       moneyV2 `json:"tax"`
+	Total     moneyV2 `json:"total"`
+	PromoCode string  `json:"promoCode"`
+}
+
+type orderV2 struct {
+	ID        string       `json:"id"`
+	MenuItems []menuItemV2 `json:"menuItems"`
+	OrderedAt time.Time    `json:"orderedAt"`
+	Notes     string       `json:"notes"`
+	Delivery  deliveryV2   `json:"delivery"`
+	Pricing   pricingV2    `json:"pricing"`
+	Status    string       `json:"status"`
+}
+
+type ordersListV2 struct {
+	Orders []orderV2 `json:"orders"`
+}
+
+type orderCreatedV2 struct {
+	ID string `json:"id"`
+}
+
+type addOrderRequestV2 struct {
+	MenuItems []menuItemV2 `json:"menuItems"`
+	Notes     string       `json:"notes"`
+	Delivery  deliveryV2   `json:"delivery"`
+	PromoCode string       `json:"promoCode"`
+}
+
+type updateOrderRequestV2 struct {
+	MenuItems []menuItemV2 `json:"menuItems"`
+	Notes     string       `json:"notes"`
+	Delivery  deliveryV2   `json:"delivery"`
+}
+
+type jsonPatchOperationV2 struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+type jsonPatchRequestV2 []jsonPatchOperationV2
+
+type mergePatchRequestV2 struct {
+	MenuItems map[string]*int `json:"menuItems"`
+}
+
+type orderSnapshotV2 struct {
+	MenuItems []menuItemV2 `json:"menuItems"`
+	Notes     string       `json:"notes"`
+	Delivery  deliveryV2   `json:"delivery"`
+	Pricing   pricingV2    `json:"pricing"`
+	Status    string       `json:"status"`
+}
+
+type fieldChangeV2 struct {
+	Path   string      `json:"path"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+type orderRevisionV2 struct {
+	Revision  int              `json:"revision"`
+	Action    string           `json:"action"`
+	Actor     string           `json:"actor"`
+	ChangedAt time.Time        `json:"changedAt"`
+	Before    *orderSnapshotV2 `json:"before"`
+	After     *orderSnapshotV2 `json:"after"`
+	Changes   []fieldChangeV2  `json:"changes"`
+}
+
+type orderHistoryV2 struct {
+	OrderID   string            `json:"orderId"`
+	Revisions []orderRevisionV2 `json:"revisions"`
+}
+
+type paymentV2 struct {
+	ID          string    `json:"id"`
+	OrderID     string    `json:"orderId"`
+	Provider    string    `json:"provider"`
+	ProviderRef string    `json:"providerRef"`
+	Amount      moneyV2   `json:"amount"`
+	Status      string    `json:"status"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+func newMoneyV2(m data.Money) moneyV2 {
+	return moneyV2{Amount: m.Amount, Currency: m.Currency}
+}
+
+func newMenuItemsV2(menuItems []data.MenuItem) []menuItemV2 {
+	items := make([]menuItemV2, len(menuItems))
+	for i, item := range menuItems {
+		modifiers := item.Modifiers
+		if modifiers == nil {
+			modifiers = make([]string, 0)
+		}
+		items[i] = menuItemV2{ID: item.ID, Quantity: item.Quantity, Modifiers: modifiers, Comment: item.Comment}
+	}
+
+	return items
+}
+
+func newDeliveryV2(d data.Delivery) deliveryV2 {
+	return deliveryV2{Type: d.Type, Address: d.Address, Phone: d.Phone}
+}
+
+func newPricingV2(p data.Pricing) pricingV2 {
+	return pricingV2{
+		Subtotal:  newMoneyV2(p.Subtotal),
+		Discount:  newMoneyV2(p.Discount),
+		Tax:       newMoneyV2(p.Tax),
+		Total:     newMoneyV2(p.Total),
+		PromoCode: p.PromoCode,
+	}
+}
+
+func newOrderV2(info data.OrderInfo) orderV2 {
+	return orderV2{
+		ID:        info.ID,
+		MenuItems: newMenuItemsV2(info.MenuItems),
+		OrderedAt: info.OrderedAt,
+		Notes:     info.Notes,
+		Delivery:  newDeliveryV2(info.Delivery),
+		Pricing:   newPricingV2(info.Pricing),
+		Status:    info.Status,
+	}
+}
+
+func newOrderSnapshotV2(s *data.OrderSnapshot) *orderSnapshotV2 {
+	if s == nil {
+		return nil
+	}
+
+	return &orderSnapshotV2{
+		MenuItems: newMenuItemsV2(s.MenuItems),
+		Notes:     s.Notes,
+		Delivery:  newDeliveryV2(s.Delivery),
+		Pricing:   newPricingV2(s.Pricing),
+		Status:    s.Status,
+	}
+}
+
+func newOrderHistoryV2(h data.OrderHistory) orderHistoryV2 {
+	revisions := make([]orderRevisionV2, len(h.Revisions))
+	for i, r := range h.Revisions {
+		changes := make([]fieldChangeV2, len(r.Changes))
+		for j, c := range r.Changes {
+			changes[j] = fieldChangeV2{Path: c.Path, Before: c.Before, After: c.After}
+		}
+
+		revisions[i] = orderRevisionV2{
+			Revision:  r.Revision,
+			Action:    r.Action,
+			Actor:     r.Actor,
+			ChangedAt: r.ChangedAt,
+			Before:    newOrderSnapshotV2(r.Before),
+			After:     newOrderSnapshotV2(r.After),
+			Changes:   changes,
+		}
+	}
+
+	return orderHistoryV2{OrderID: h.OrderID, Revisions: revisions}
+}
+
+func newPaymentV2(p data.PaymentInfo) paymentV2 {
+	return paymentV2{
+		ID:          p.ID,
+		OrderID:     p.OrderID,
+		Provider:    p.Provider,
+		ProviderRef: p.ProviderRef,
+		Amount:      newMoneyV2(p.Amount),
+		Status:      p.Status,
+		CreatedAt:   p.CreatedAt,
+	}
+}
+
+func newOrdersListV2(list data.OrdersList) ordersListV2 {
+	orders := make([]orderV2, len(list.Orders))
+	for i, info := range list.Orders {
+		orders[i] = newOrderV2(info)
+	}
+
+	return ordersListV2{Orders: orders}
+}
+
+func menuItemsFromV2(items []menuItemV2) []data.MenuItem {
+	result := make([]data.MenuItem, len(items))
+	for i, item := range items {
+		result[i] = data.MenuItem{ID: item.ID, Quantity: item.Quantity, Modifiers: item.Modifiers, Comment: item.Comment}
+	}
+
+	return result
+}
+
+func deliveryFromV2(d deliveryV2) data.Delivery {
+	return data.Delivery{Type: d.Type, Address: d.Address, Phone: d.Phone}
+}
+
+func (r addOrderRequestV2) toService() service.AddOrderRequest {
+	return service.AddOrderRequest{
+		MenuItems: menuItemsFromV2(r.MenuItems),
+		Notes:     r.Notes,
+		Delivery:  deliveryFromV2(r.Delivery),
+		PromoCode: r.PromoCode,
+	}
+}
+
+func (r updateOrderRequestV2) toService() service.UpdateOrderRequest {
+	return service.UpdateOrderRequest{
+		MenuItems: menuItemsFromV2(r.MenuItems),
+		Notes:     r.Notes,
+		Delivery:  deliveryFromV2(r.Delivery),
+	}
+}
+
+func (r jsonPatchRequestV2) toService() service.JSONPatchRequest {
+	patch := make(service.JSONPatchRequest, len(r))
+	for i, op := range r {
+		patch[i] = service.JSONPatchOperation{Op: op.Op, Path: op.Path, From: op.From, Value: op.Value}
+	}
+
+	return patch
+}
+
+func (r mergePatchRequestV2) toService() service.MergePatchRequest {
+	return service.MergePatchRequest{MenuItems: r.MenuItems}
+}
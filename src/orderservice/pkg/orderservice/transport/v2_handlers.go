@@ -0,0 +1,178 @@
+package transport
+
+import (
+	"github.com/gorilla/mux"
+	"net/http"
+	"orderservice/pkg/orderservice/application/service"
+)
+
+const (
+	apiV1Prefix = "/api/v1"
+	apiV2Prefix = "/api/v2"
+)
+
+// routeV2 fixes v1 naming: collections are plural, creation returns 201 with the new id
+func routeV2(s *mux.Router, srv *server) {
+	s.HandleFunc("/orders", srv.getOrdersListV2).Methods(http.MethodGet)
+	s.HandleFunc("/orders", srv.addOrderV2).Methods(http.MethodPost)
+	s.HandleFunc("/orders/{ID:[0-9a-zA-Z-]+}", srv.getOrderV2).Methods(http.MethodGet)
+	s.HandleFunc("/orders/{ID:[0-9a-zA-Z-]+}", srv.updateOrderV2).Methods(http.MethodPut)
+	s.HandleFunc("/orders/{ID:[0-9a-zA-Z-]+}", srv.patchOrderV2).Methods(http.MethodPatch)
+	s.HandleFunc("/orders/{ID:[0-9a-zA-Z-]+}", srv.deleteOrderV2).Methods(http.MethodDelete)
+	s.HandleFunc("/orders/{ID:[0-9a-zA-Z-]+}/history", srv.getOrderHistoryV2).Methods(http.MethodGet)
+	s.HandleFunc("/orders/{ID:[0-9a-zA-Z-]+}/payments", srv.payOrderV2).Methods(http.MethodPost)
+}
+
+func (s *server) getOrdersListV2(w http.ResponseWriter, r *http.Request) {
+	orders, err := s.orderQueryService.GetOrders()
+	if err != nil {
+		processError(w, err)
+		return
+	}
+
+	render(w, r, newOrdersListV2(*orders))
+}
+
+func (s *server) getOrderV2(w http.ResponseWriter, r *http.Request) {
+	id, found := mux.Vars(r)["ID"]
+	if !found {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	info, err := s.orderQueryService.GetOrderInfo(id)
+	if err != nil {
+		processError(w, err)
+		return
+	}
+
+	if info == nil {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	render(w, r, newOrderV2(*info))
+}
+
+func (s *server) addOrderV2(w http.ResponseWriter, r *http.Request) {
+	var orderRequest addOrderRequestV2
+	err := jsonFromRequest(r, &orderRequest)
+	if err != nil {
+		processRequestError(w, err)
+		return
+	}
+
+	id, err := s.orderService.Add(orderRequest.toService(), actorFromRequest(r))
+	if err != nil {
+		processError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", apiV2Prefix+"/orders/"+id)
+	renderWithStatus(w, r, http.StatusCreated, orderCreatedV2{ID: id})
+}
+
+func (s *server) updateOrderV2(w http.ResponseWriter, r *http.Request) {
+	id, found := mux.Vars(r)["ID"]
+	if !found {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	var orderRequest updateOrderRequestV2
+	err := jsonFromRequest(r, &orderRequest)
+	if err != nil {
+		processRequestError(w, err)
+		return
+	}
+
+	err = s.orderService.Update(id, orderRequest.toService(), actorFromRequest(r))
+	if err != nil {
+		processError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) deleteOrderV2(w http.ResponseWriter, r *http.Request) {
+	id, found := mux.Vars(r)["ID"]
+	if !found {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	err := s.orderService.Delete(id, actorFromRequest(r))
+	if err != nil {
+		processError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) patchOrderV2(w http.ResponseWriter, r *http.Request) {
+	id, found := mux.Vars(r)["ID"]
+	if !found {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	patch, ok := patchFromRequest(w, r, func(r *http.Request) (service.OrderPatch, error) {
+		var jsonPatch jsonPatchRequestV2
+		err := jsonFromRequest(r, &jsonPatch)
+		return jsonPatch.toService(), err
+	}, func(r *http.Request) (service.OrderPatch, error) {
+		var mergePatch mergePatchRequestV2
+		err := jsonFromRequest(r, &mergePatch)
+		return mergePatch.toService(), err
+	})
+	if !ok {
+		return
+	}
+
+	err := s.orderService.Patch(id, patch, actorFromRequest(r))
+	if err != nil {
+		processError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) getOrderHistoryV2(w http.ResponseWriter, r *http.Request) {
+	id, found := mux.Vars(r)["ID"]
+	if !found {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	history, err := s.orderQueryService.GetOrderHistory(id)
+	if err != nil {
+		processError(w, err)
+		return
+	}
+
+	if history == nil {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	render(w, r, newOrderHistoryV2(*history))
+}
+
+func (s *server) payOrderV2(w http.ResponseWriter, r *http.Request) {
+	id, found := mux.Vars(r)["ID"]
+	if !found {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	payment, err := s.paymentService.Pay(id, actorFromRequest(r))
+	if err != nil {
+		processError(w, err)
+		return
+	}
+
+	renderWithStatus(w, r, http.StatusCreated, newPaymentV2(*payment))
+}
@@ -0,0 +1,108 @@
+package transport
+
+import (
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOrdersListV2(t *testing.T) {
+	srv := server{orderQueryService: mocOrderQueryService{}}
+	w := httptest.NewRecorder()
+	srv.getOrdersListV2(w, httptest.NewRequest(http.MethodGet, "/api/v2/orders", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Status code is wrong. Have: %d, want: %d", w.Code, http.StatusOK)
+	}
+
+	body := w.Body.String()
+	if !strings.Contains(body, `"orderedAt"`) || strings.Contains(body, "orderedAtTimestamp") {
+		t.Errorf("Order is not mapped to v2: %s", body)
+	}
+}
+
+func TestAddOrderV2(t *testing.T) {
+	srv := server{orderService: mocOrderService{}}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/api/v2/orders", strings.NewReader(`{"menuItems":[]}`))
+	srv.addOrderV2(w, r)
+	if w.Code != http.StatusCreated {
+		t.Errorf("Status code is wrong. Have: %d, want: %d", w.Code, http.StatusCreated)
+	}
+
+	location := w.Header().Get("Location")
+	if location != "/api/v2/orders/3fa85f64-5717-4562-b3fc-2c963f66afa6" {
+		t.Errorf("Location is wrong. Have: %s", location)
+	}
+}
+
+func TestDeprecationHeaders(t *testing.T) {
+	deprecatedAt := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2027, 4, 1, 0, 0, 0, 0, time.UTC)
+	h := deprecationMiddleware(deprecatedAt, sunset, apiV2Prefix)(http.HandlerFunc(helloWorld))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/orders", nil))
+
+	if have, want := w.Header().Get("Deprecation"), "@1792368000"; have != want {
+		t.Errorf("Deprecation is wrong. Have: %s, want: %s", have, want)
+	}
+	if have, want := w.Header().Get("Sunset"), "Thu, 01 Apr 2027 00:00:00 GMT"; have != want {
+		t.Errorf("Sunset is wrong. Have: %s, want: %s", have, want)
+	}
+	if have, want := w.Header().Get("Link"), `</api/v2>; rel="successor-version"`; have != want {
+		t.Errorf("Link is wrong. Have: %s, want: %s", have, want)
+	}
+}
+
+func TestRequestMetrics(t *testing.T) {
+	metrics := newRequestMetrics()
+	r := mux.NewRouter()
+	r.Handle("/metrics", metrics)
+	v1 := r.PathPrefix(apiV1Prefix).Subrouter()
+	v1.Use(metrics.middleware("v1"))
+	v1.HandleFunc("/hello-world", helloWorld)
+	v2 := r.PathPrefix(apiV2Prefix).Subrouter()
+	v2.Use(metrics.middleware("v2"))
+	v2.HandleFunc("/orders", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}).Methods(http.MethodPost)
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/hello-world", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/hello-world", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/v2/orders", nil))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+	for _, line := range []string{
+		`http_requests_total{version="v1",method="GET",code="200"} 2`,
+		`http_requests_total{version="v2",method="POST",code="201"} 1`,
+		`http_request_duration_seconds_count{version="v2",method="POST",code="201"} 1`,
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("Metrics have no %s in:\n%s", line, body)
+		}
+	}
+}
+
+func TestPatchAndHistoryV2(t *testing.T) {
+	r := mux.NewRouter()
+	routeV2(r.PathPrefix(apiV2Prefix).Subrouter(), &server{orderService: mocOrderService{}, orderQueryService: mocOrderQueryService{}})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPatch, "/api/v2/orders/3fa85f64-5717-4562-b3fc-2c963f66afa6", strings.NewReader(`{"menuItems":{"a":1}}`))
+	req.Header.Set("Content-Type", mergePatchMediaType)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Status code of patch is wrong. Have: %d, want: %d", w.Code, http.StatusNoContent)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v2/orders/3fa85f64-5717-4562-b3fc-2c963f66afa6/history", nil))
+	if body := w.Body.String(); w.Code != http.StatusOK || !strings.Contains(body, `"changes":[]`) || !strings.Contains(body, `"before":null`) {
+		t.Errorf("History is not mapped to v2: %d %s", w.Code, body)
+	}
+}
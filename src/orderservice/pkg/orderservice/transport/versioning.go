@@ -0,0 +1,32 @@
+package transport
+
+import (
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type VersioningConfig struct {
+	// V1DeprecatedAt is announced in Deprecation header, zero omits the header
+	V1DeprecatedAt time.Time
+	// V1Sunset is when v1 stops working, zero omits Sunset header
+	V1Sunset time.Time
+}
+
+// deprecationMiddleware announces deprecation (RFC 9745) and removal (RFC 8594) of an API version
+func deprecationMiddleware(deprecatedAt, sunset time.Time, successor string) mux.MiddlewareFunc {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !deprecatedAt.IsZero() {
+				w.Header().Set("Deprecation", "@"+strconv.FormatInt(deprecatedAt.Unix(), 10))
+				w.Header().Add("Link", "<"+successor+">; rel=\"successor-version\"")
+			}
+			if !sunset.IsZero() {
+				w.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+			}
+
+			h.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package transport
+
+import (
+	"github.com/gorilla/mux"
+	"net/http"
+	"orderservice/pkg/orderservice/application/service"
+)
+
+func (s *server) getWebhooksList(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := s.webhookQueryService.GetWebhooks()
+	if err != nil {
+		processError(w, err)
+		return
+	}
+
+	render(w, r, webhooks)
+}
+
+func (s *server) getWebhook(w http.ResponseWriter, r *http.Request) {
+	id, found := mux.Vars(r)["ID"]
+	if !found {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	webhook, err := s.webhookQueryService.GetWebhook(id)
+	if err != nil {
+		processError(w, err)
+		return
+	}
+
+	if webhook == nil {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	render(w, r, webhook)
+}
+
+func (s *server) addWebhook(w http.ResponseWriter, r *http.Request) {
+	var webhookRequest service.WebhookRequest
+	err := jsonFromRequest(r, &webhookRequest)
+	if err != nil {
+		processRequestError(w, err)
+		return
+	}
+
+	webhook, err := s.webhookService.Add(webhookRequest)
+	if err != nil {
+		processError(w, err)
+		return
+	}
+
+	render(w, r, webhook)
+}
+
+func (s *server) updateWebhook(w http.ResponseWriter, r *http.Request) {
+	id, found := mux.Vars(r)["ID"]
+	if !found {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	var webhookRequest service.WebhookRequest
+	err := jsonFromRequest(r, &webhookRequest)
+	if err != nil {
+		processRequestError(w, err)
+		return
+	}
+
+	err = s.webhookService.Update(id, webhookRequest)
+	if err != nil {
+		processError(w, err)
+	}
+}
+
+func (s *server) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, found := mux.Vars(r)["ID"]
+	if !found {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	err := s.webhookService.Delete(id)
+	if err != nil {
+		processError(w, err)
+	}
+}
+
+// getWebhookDeliveries lists deliveries filtered by "?status=dead" for the dead-letter list
+func (s *server) getWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	deliveries, err := s.webhookQueryService.GetDeliveries(r.URL.Query().Get("status"))
+	if err != nil {
+		processError(w, err)
+		return
+	}
+
+	render(w, r, deliveries)
+}
+
+func (s *server) redeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	id, found := mux.Vars(r)["ID"]
+	if !found {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	err := s.webhookService.Redeliver(id)
+	if err != nil {
+		processError(w, err)
+	}
+}
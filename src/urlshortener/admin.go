@@ -0,0 +1,114 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"embed"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const AdminPath = "/admin/"
+
+const authRealm = "urlshortener"
+
+//go:embed admin
+var adminFiles embed.FS
+
+type AdminConfig struct {
+	// Username and Password are checked with basic auth, the admin UI is disabled without them
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// Token is accepted as Authorization: Bearer token by management API
+	Token string `json:"token"`
+}
+
+func (c AdminConfig) IsEnabled() bool {
+	return c.Username != "" && c.Password != ""
+}
+
+func (c AdminConfig) hasCredentials() bool {
+	return c.IsEnabled() || c.Token != ""
+}
+
+func AdminHttpHandler() http.Handler {
+	files, err := fs.Sub(adminFiles, "admin")
+	if err != nil {
+		panic(err)
+	}
+
+	fileServer := http.StripPrefix(AdminPath, http.FileServer(http.FS(files)))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Security-Policy", "default-src 'self'; frame-ancestors 'none'")
+		w.Header().Set("X-Frame-Options", "DENY")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Cache-Control", "no-store")
+		fileServer.ServeHTTP(w, r)
+	})
+}
+
+// RequireAuth protects handler with basic auth or bearer token, all requests are forbidden
+// without credentials configured
+func RequireAuth(config AdminConfig, h http.Handler) http.Handler {
+	if !config.hasCredentials() {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Admin credentials are not configured", http.StatusForbidden)
+		})
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isAuthorized(config, r) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="`+authRealm+`", charset="UTF-8"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		// browsers resend basic auth credentials to cross site requests, so changes must come from our own pages
+		if !isSafeMethod(r.Method) && !isSameOrigin(r) {
+			http.Error(w, "Cross origin request is forbidden", http.StatusForbidden)
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+func isAuthorized(config AdminConfig, r *http.Request) bool {
+	if authorization := r.Header.Get("Authorization"); config.Token != "" && strings.HasPrefix(authorization, "Bearer ") {
+		return secureEqual(strings.TrimPrefix(authorization, "Bearer "), config.Token)
+	}
+
+	username, password, ok := r.BasicAuth()
+	if !ok || !config.IsEnabled() {
+		return false
+	}
+
+	// both are compared to spend the same time whichever is wrong
+	usernameOk := secureEqual(username, config.Username)
+	passwordOk := secureEqual(password, config.Password)
+	return usernameOk && passwordOk
+}
+
+// secureEqual compares hashes so the time does not depend on length of the secret
+func secureEqual(a, b string) bool {
+	hashA := sha256.Sum256([]byte(a))
+	hashB := sha256.Sum256([]byte(b))
+	return subtle.ConstantTimeCompare(hashA[:], hashB[:]) == 1
+}
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+func isSameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		// non browser clients and same origin requests of old browsers send no origin
+		return r.Header.Get("Sec-Fetch-Site") == "" || r.Header.Get("Sec-Fetch-Site") == "same-origin"
+	}
+
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
@@ -0,0 +1,295 @@
+'use strict';
+
+const api = '/api/links';
+
+const form = document.getElementById('link-form');
+const linksBody = document.getElementById('links');
+const search = document.getElementById('search');
+const message = document.getElementById('message');
+
+let editing = null;
+
+async function request(method, url, body) {
+    const options = {method, headers: {'Accept': 'application/json'}, credentials: 'same-origin'};
+    if (body !== undefined) {
+        options.headers['Content-Type'] = 'application/json';
+        options.body = JSON.stringify(body);
+    }
+
+    const response = await fetch(url, options);
+    if (response.status === 204) {
+        return null;
+    }
+
+    const data = await response.json().catch(() => ({}));
+    if (!response.ok) {
+        throw new Error(data.error || response.statusText);
+    }
+    return data;
+}
+
+function showMessage(text, info) {
+    message.textContent = text;
+    message.classList.toggle('info', Boolean(info));
+    message.hidden = false;
+}
+
+function element(tag, text, className) {
+    const el = document.createElement(tag);
+    if (text !== undefined) {
+        el.textContent = text;
+    }
+    if (className) {
+        el.className = className;
+    }
+    return el;
+}
+
+function linkUrl(link, suffix) {
+    let url = api + '/' + encodeURIComponent(link.code) + (suffix || '');
+    if (link.domain) {
+        url += (url.includes('?') ? '&' : '?') + 'domain=' + encodeURIComponent(link.domain);
+    }
+    return url;
+}
+
+function isEnabled(link) {
+    return link.enabled === undefined || link.enabled;
+}
+
+function optionTags(link) {
+    const tags = [];
+    if (link.read_only) {
+        tags.push('config');
+    }
+    if (!isEnabled(link)) {
+        tags.push('disabled');
+    }
+    if (link.redirect_code) {
+        tags.push(String(link.redirect_code));
+    }
+    if (link.expires_at) {
+        tags.push('expires ' + new Date(link.expires_at).toLocaleString());
+    }
+    if (link.max_clicks) {
+        tags.push('max ' + link.max_clicks + ' clicks');
+    }
+    if (link.query_passthrough) {
+        tags.push('query passthrough');
+    }
+    return tags;
+}
+
+function button(text, handler) {
+    const b = element('button', text);
+    b.type = 'button';
+    b.addEventListener('click', handler);
+    return b;
+}
+
+function renderLinks(links) {
+    linksBody.replaceChildren();
+    for (const link of links) {
+        const row = element('tr');
+        if (!isEnabled(link)) {
+            row.className = 'disabled';
+        }
+
+        const shortCell = element('td');
+        const shortLink = element('a', link.short_url);
+        shortLink.href = link.short_url;
+        shortCell.append(shortLink);
+        row.append(shortCell);
+
+        row.append(element('td', link.url));
+
+        const optionsCell = element('td');
+        for (const tag of optionTags(link)) {
+            optionsCell.append(element('span', tag, 'tag'));
+        }
+        row.append(optionsCell);
+
+        const actions = element('td', undefined, 'actions');
+        actions.append(button('Stats', () => showStats(link)));
+        if (!link.read_only) {
+            actions.append(button('Edit', () => startEdit(link)));
+            actions.append(button(isEnabled(link) ? 'Disable' : 'Enable', () => toggleLink(link)));
+            actions.append(button('Delete', () => deleteLink(link)));
+        }
+        row.append(actions);
+
+        linksBody.append(row);
+    }
+}
+
+async function loadLinks() {
+    try {
+        const data = await request('GET', api + '?q=' + encodeURIComponent(search.value.trim()));
+        renderLinks(data.links);
+    } catch (e) {
+        showMessage('Loading links failed: ' + e.message);
+    }
+}
+
+function linkBody(link) {
+    const body = {url: link.url};
+    for (const option of ['redirect_code', 'expires_at', 'max_clicks', 'enabled', 'query_passthrough']) {
+        if (link[option] !== undefined) {
+            body[option] = link[option];
+        }
+    }
+    return body;
+}
+
+function formBody() {
+    const data = new FormData(form);
+    const body = {url: data.get('url')};
+    if (data.get('redirect_code')) {
+        body.redirect_code = Number(data.get('redirect_code'));
+    }
+    if (data.get('expires_at')) {
+        body.expires_at = new Date(data.get('expires_at')).toISOString();
+    }
+    if (data.get('max_clicks')) {
+        body.max_clicks = Number(data.get('max_clicks'));
+    }
+    if (!data.get('enabled')) {
+        body.enabled = false;
+    }
+    if (data.get('query_passthrough')) {
+        body.query_passthrough = true;
+    }
+    return body;
+}
+
+function localDateTime(value) {
+    const date = new Date(value);
+    date.setMinutes(date.getMinutes() - date.getTimezoneOffset());
+    return date.toISOString().slice(0, 16);
+}
+
+function startEdit(link) {
+    editing = link;
+    form.reset();
+    form.elements.url.value = link.url;
+    form.elements.alias.value = link.code;
+    form.elements.alias.disabled = true;
+    form.elements.domain.value = link.domain || '';
+    form.elements.domain.disabled = true;
+    form.elements.redirect_code.value = link.redirect_code ? String(link.redirect_code) : '';
+    form.elements.expires_at.value = link.expires_at ? localDateTime(link.expires_at) : '';
+    form.elements.max_clicks.value = link.max_clicks || '';
+    form.elements.enabled.checked = isEnabled(link);
+    form.elements.query_passthrough.checked = Boolean(link.query_passthrough);
+    document.getElementById('form-title').textContent = 'Edit ' + link.code;
+    document.getElementById('submit').textContent = 'Save';
+    document.getElementById('cancel').hidden = false;
+    form.scrollIntoView();
+}
+
+function stopEdit() {
+    editing = null;
+    form.reset();
+    form.elements.alias.disabled = false;
+    form.elements.domain.disabled = false;
+    document.getElementById('form-title').textContent = 'New link';
+    document.getElementById('submit').textContent = 'Create';
+    document.getElementById('cancel').hidden = true;
+}
+
+async function toggleLink(link) {
+    const body = linkBody(link);
+    body.enabled = !isEnabled(link);
+    try {
+        await request('PUT', linkUrl(link), body);
+        await loadLinks();
+    } catch (e) {
+        showMessage('Saving link failed: ' + e.message);
+    }
+}
+
+async function deleteLink(link) {
+    if (!confirm('Delete ' + link.short_url + '?')) {
+        return;
+    }
+    try {
+        await request('DELETE', linkUrl(link));
+        await loadLinks();
+    } catch (e) {
+        showMessage('Deleting link failed: ' + e.message);
+    }
+}
+
+function fillCounts(list, counts) {
+    list.replaceChildren();
+    const entries = Object.entries(counts).sort((a, b) => b[1] - a[1]);
+    for (const [name, count] of entries) {
+        list.append(element('li', name + ': ' + count));
+    }
+}
+
+async function showStats(link) {
+    try {
+        const stats = await request('GET', linkUrl(link, '/stats?bucket=day'));
+        document.getElementById('stats-title').textContent = 'Stats of ' + link.short_url;
+        document.getElementById('stats-summary').textContent =
+            stats.clicks + ' clicks, ' + stats.daily_unique_visitors + ' daily unique visitors since ' + new Date(stats.since).toLocaleDateString();
+        fillCounts(document.getElementById('stats-referrers'), stats.referrers);
+        fillCounts(document.getElementById('stats-devices'), stats.devices);
+
+        const series = document.getElementById('stats-series');
+        series.replaceChildren();
+        const max = Math.max(1, ...stats.series.map(point => point.clicks));
+        for (const point of stats.series) {
+            const item = element('li');
+            item.append(element('span', new Date(point.time).toLocaleDateString()));
+            const bar = element('span', undefined, 'bar');
+            bar.style.width = (point.clicks / max * 300) + 'px';
+            item.append(bar);
+            item.append(element('span', String(point.clicks)));
+            series.append(item);
+        }
+
+        const section = document.getElementById('stats');
+        section.hidden = false;
+        section.scrollIntoView();
+    } catch (e) {
+        showMessage('Loading stats failed: ' + e.message);
+    }
+}
+
+form.addEventListener('submit', async event => {
+    event.preventDefault();
+    const body = formBody();
+    try {
+        if (editing) {
+            await request('PUT', linkUrl(editing), body);
+            showMessage('Link ' + editing.code + ' is saved', true);
+        } else {
+            const alias = form.elements.alias.value.trim();
+            if (alias) {
+                body.alias = alias;
+            }
+            const domain = form.elements.domain.value.trim();
+            if (domain) {
+                body.domain = domain;
+            }
+            const link = await request('POST', api, body);
+            showMessage('Link ' + link.short_url + ' is created', true);
+        }
+        stopEdit();
+        await loadLinks();
+    } catch (e) {
+        showMessage('Saving link failed: ' + e.message);
+    }
+});
+
+document.getElementById('cancel').addEventListener('click', stopEdit);
+
+let searchTimer = null;
+search.addEventListener('input', () => {
+    clearTimeout(searchTimer);
+    searchTimer = setTimeout(loadLinks, 250);
+});
+
+loadLinks();
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireAuth(t *testing.T) {
+	config := AdminConfig{Username: "admin", Password: "secret", Token: "token"}
+	h := RequireAuth(config, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	cases := []struct {
+		name   string
+		method string
+		setup  func(r *http.Request)
+		status int
+	}{
+		{"no credentials", http.MethodGet, func(r *http.Request) {}, http.StatusUnauthorized},
+		{"wrong password", http.MethodGet, func(r *http.Request) { r.SetBasicAuth("admin", "wrong") }, http.StatusUnauthorized},
+		{"basic auth", http.MethodGet, func(r *http.Request) { r.SetBasicAuth("admin", "secret") }, http.StatusNoContent},
+		{"wrong token", http.MethodGet, func(r *http.Request) { r.Header.Set("Authorization", "Bearer wrong") }, http.StatusUnauthorized},
+		{"token", http.MethodDelete, func(r *http.Request) { r.Header.Set("Authorization", "Bearer token") }, http.StatusNoContent},
+		{"cross origin change", http.MethodDelete, func(r *http.Request) {
+			r.SetBasicAuth("admin", "secret")
+			r.Header.Set("Origin", "https://evil.example")
+		}, http.StatusForbidden},
+		{"same origin change", http.MethodDelete, func(r *http.Request) {
+			r.SetBasicAuth("admin", "secret")
+			r.Header.Set("Origin", "http://example.com")
+		}, http.StatusNoContent},
+	}
+
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(c.method, "http://example.com/api/links/x", nil)
+		c.setup(r)
+		h.ServeHTTP(w, r)
+		if w.Code != c.status {
+			t.Errorf("Status code is wrong for %s. Have: %d, want: %d", c.name, w.Code, c.status)
+		}
+	}
+}
+
+func TestRequireAuthWithoutCredentials(t *testing.T) {
+	h := RequireAuth(AdminConfig{}, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, "http://example.com/api/links/x", nil))
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Status code of %s is wrong. Have: %d, want: %d", method, w.Code, http.StatusForbidden)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	ClickSinkMemory = "memory"
+	ClickSinkFile   = "file"
+	ClickSinkSQL    = "sql"
+)
+
+const (
+	DefaultClickBufferSize = 1024
+	DefaultClickRingSize   = 100000
+	DefaultClickLogPath    = "data/clicks.log"
+)
+
+const (
+	clickBatchSize     = 100
+	clickFlushInterval = time.Second
+	// visitorSaltLayout rotates the salt of visitor ids daily, a visitor is unique within a day
+	visitorSaltLayout = "2006-01-02"
+	visitorSecretSize = 32
+)
+
+const (
+	DeviceBot     = "bot"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceDesktop = "desktop"
+)
+
+const directReferrer = "direct"
+
+type Click struct {
+	Host      string    `json:"host,omitempty"`
+	Path      string    `json:"path"`
+	Time      time.Time `json:"time"`
+	Referrer  string    `json:"referrer,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Device    string    `json:"device"`
+	// VisitorID is a HMAC of client address and user agent with a daily salt, addresses are not stored
+	VisitorID string `json:"visitor_id"`
+}
+
+// ClickSink stores recorded clicks, Record is called from a single goroutine
+type ClickSink interface {
+	Record(clicks []Click) error
+	// Clicks returns clicks of the link since the time in chronological order
+	Clicks(host, path string, since time.Time) ([]Click, error)
+	Close() error
+}
+
+type AnalyticsConfig struct {
+	Sink string `json:"sink"`
+	// BufferSize is the number of clicks waiting for the sink, clicks are dropped when it is full
+	BufferSize int `json:"buffer_size"`
+	// RingSize is the number of last clicks kept by memory sink
+	RingSize int `json:"ring_size"`
+	// Path is the log file of file sink
+	Path string `json:"path"`
+	// Driver and DSN are passed to sql.Open for sql sink
+	Driver string `json:"driver"`
+	DSN    string `json:"dsn"`
+	// VisitorSecret keys visitor ids, a random secret is used without it so ids change on restart
+	VisitorSecret string `json:"visitor_secret"`
+}
+
+func EnsureAnalyticsValid(config *Config) {
+	if config.Analytics.Sink == "" {
+		config.Analytics.Sink = ClickSinkMemory
+	}
+	if config.Analytics.BufferSize <= 0 {
+		config.Analytics.BufferSize = DefaultClickBufferSize
+	}
+	if config.Analytics.RingSize <= 0 {
+		config.Analytics.RingSize = DefaultClickRingSize
+	}
+	if config.Analytics.Sink == ClickSinkFile && config.Analytics.Path == "" {
+		config.Analytics.Path = DefaultClickLogPath
+	}
+}
+
+func OpenClickSink(config AnalyticsConfig) (ClickSink, error) {
+	switch config.Sink {
+	case ClickSinkMemory:
+		return NewMemoryClickSink(config.RingSize), nil
+	case ClickSinkFile:
+		return OpenFileClickSink(config.Path)
+	case ClickSinkSQL:
+		return OpenSQLClickSink(config.Driver, config.DSN)
+	default:
+		return nil, errors.New(fmt.Sprintf("Unknown click sink %s", config.Sink))
+	}
+}
+
+// ClickRecorder passes clicks to the sink in background so redirects never wait for it
+type ClickRecorder struct {
+	// clicks is never closed, handlers still running after Close may send to it
+	clicks   chan Click
+	sink     ClickSink
+	visitors *visitorHasher
+	dropped  uint64
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+func NewClickRecorder(sink ClickSink, bufferSize int, visitorSecret string) (*ClickRecorder, error) {
+	visitors, err := newVisitorHasher(visitorSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	cr := &ClickRecorder{
+		clicks:   make(chan Click, bufferSize),
+		sink:     sink,
+		visitors: visitors,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go cr.run()
+
+	return cr, nil
+}
+
+func (cr *ClickRecorder) Record(r *http.Request, host, path string) {
+	now := time.Now().UTC()
+	click := Click{
+		Host:      host,
+		Path:      path,
+		Time:      now,
+		Referrer:  r.Referer(),
+		UserAgent: r.UserAgent(),
+		Device:    deviceClass(r.UserAgent()),
+		VisitorID: cr.visitors.ID(r, now),
+	}
+
+	select {
+	case <-cr.stop:
+		return // clicks after Close aren't recorded
+	default:
+	}
+
+	select {
+	case cr.clicks <- click:
+	default:
+		if dropped := atomic.AddUint64(&cr.dropped, 1); dropped%1000 == 1 {
+			fmt.Printf("Click buffer is full, %d clicks dropped\n", dropped)
+		}
+	}
+}
+
+func (cr *ClickRecorder) Sink() ClickSink {
+	return cr.sink
+}
+
+// Close records buffered clicks and closes the sink
+func (cr *ClickRecorder) Close() error {
+	cr.stopOnce.Do(func() { close(cr.stop) })
+	<-cr.done
+
+	return cr.sink.Close()
+}
+
+func (cr *ClickRecorder) run() {
+	defer close(cr.done)
+
+	ticker := time.NewTicker(clickFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Click, 0, clickBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		err := cr.sink.Record(batch)
+		if err != nil {
+			fmt.Printf("Record %d clicks error: %s\n", len(batch), err)
+		}
+		batch = make([]Click, 0, clickBatchSize)
+	}
+
+	for {
+		select {
+		case click := <-cr.clicks:
+			batch = append(batch, click)
+			if len(batch) >= clickBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-cr.stop:
+			// clicks buffered before Close are recorded, later sends only fill the buffer
+			for {
+				select {
+				case click := <-cr.clicks:
+					batch = append(batch, click)
+					if len(batch) >= clickBatchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+func deviceClass(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	switch {
+	case ua == "" || containsAny(ua, "bot", "crawler", "spider", "curl", "wget", "python-requests", "go-http-client"):
+		return DeviceBot
+	case containsAny(ua, "ipad", "tablet"):
+		return DeviceTablet
+	case containsAny(ua, "mobi", "iphone", "android"):
+		return DeviceMobile
+	default:
+		return DeviceDesktop
+	}
+}
+
+func containsAny(s string, substrings ...string) bool {
+	for _, substring := range substrings {
+		if strings.Contains(s, substring) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// visitorHasher derives visitor ids with a salt rotated daily, ids of different days can not be linked
+type visitorHasher struct {
+	secret []byte
+	mutex  sync.Mutex
+	day    string
+	salt   []byte
+}
+
+func newVisitorHasher(secret string) (*visitorHasher, error) {
+	if secret != "" {
+		return &visitorHasher{secret: []byte(secret)}, nil
+	}
+
+	random := make([]byte, visitorSecretSize)
+	_, err := rand.Read(random)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Generate visitor secret error: %s", err))
+	}
+
+	return &visitorHasher{secret: random}, nil
+}
+
+func (v *visitorHasher) ID(r *http.Request, now time.Time) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	mac := hmac.New(sha256.New, v.daySalt(now))
+	mac.Write([]byte(host + "\x00" + r.UserAgent()))
+	return hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+func (v *visitorHasher) daySalt(now time.Time) []byte {
+	day := now.UTC().Format(visitorSaltLayout)
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	if day != v.day {
+		mac := hmac.New(sha256.New, v.secret)
+		mac.Write([]byte(day))
+		v.day, v.salt = day, mac.Sum(nil)
+	}
+
+	return v.salt
+}
+
+func referrerHost(referrer string) string {
+	if referrer == "" {
+		return directReferrer
+	}
+
+	u, err := url.Parse(referrer)
+	if err != nil || u.Hostname() == "" {
+		return directReferrer
+	}
+
+	return u.Hostname()
+}
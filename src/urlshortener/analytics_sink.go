@@ -0,0 +1,328 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// MemoryClickSink keeps last clicks in a ring buffer, older clicks are overwritten
+type MemoryClickSink struct {
+	mutex sync.RWMutex
+	ring  []Click
+	next  int
+	full  bool
+}
+
+func NewMemoryClickSink(size int) *MemoryClickSink {
+	return &MemoryClickSink{ring: make([]Click, size)}
+}
+
+func (s *MemoryClickSink) Record(clicks []Click) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, click := range clicks {
+		s.ring[s.next] = click
+		s.next = (s.next + 1) % len(s.ring)
+		if s.next == 0 {
+			s.full = true
+		}
+	}
+
+	return nil
+}
+
+func (s *MemoryClickSink) Clicks(host, path string, since time.Time) ([]Click, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	start, count := 0, s.next
+	if s.full {
+		start, count = s.next, len(s.ring)
+	}
+
+	var clicks []Click
+	for i := 0; i < count; i++ {
+		click := s.ring[(start+i)%len(s.ring)]
+		if click.Host == host && click.Path == path && !click.Time.Before(since) {
+			clicks = append(clicks, click)
+		}
+	}
+
+	return clicks, nil
+}
+
+func (s *MemoryClickSink) Close() error {
+	return nil
+}
+
+// FileClickSink appends clicks to a log file, offsets of the records are indexed by link
+// so stats read only the clicks of the requested link
+type FileClickSink struct {
+	mutex sync.Mutex
+	path  string
+	file  *os.File
+	size  int64
+	index map[string][]clickRecord
+}
+
+type clickRecord struct {
+	time   time.Time
+	offset int64
+	length int
+}
+
+func OpenFileClickSink(path string) (*FileClickSink, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		err := os.MkdirAll(dir, 0755)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Create directory %s error: %s", dir, err))
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Open file %s error: %s", path, err))
+	}
+
+	s := &FileClickSink{path: path, file: file, index: map[string][]clickRecord{}}
+	err = s.buildIndex()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// buildIndex reads the log once, broken records are skipped
+func (s *FileClickSink) buildIndex() error {
+	reader := bufio.NewReader(s.file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			var click Click
+			if json.Unmarshal(line, &click) == nil {
+				s.addToIndex(click, s.size, len(line))
+			}
+			s.size += int64(len(line))
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.New(fmt.Sprintf("Read file %s error: %s", s.path, err))
+		}
+	}
+
+	// a torn last record must not be glued to the next one
+	if s.size > 0 {
+		last := make([]byte, 1)
+		_, err := s.file.ReadAt(last, s.size-1)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Read file %s error: %s", s.path, err))
+		}
+		if last[0] != '\n' {
+			_, err = s.file.Write([]byte{'\n'})
+			if err != nil {
+				return errors.New(fmt.Sprintf("Write file %s error: %s", s.path, err))
+			}
+			s.size++
+		}
+	}
+
+	return nil
+}
+
+func (s *FileClickSink) addToIndex(click Click, offset int64, length int) {
+	key := click.Host + click.Path
+	s.index[key] = append(s.index[key], clickRecord{time: click.Time, offset: offset, length: length})
+}
+
+func (s *FileClickSink) Record(clicks []Click) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var buffer bytes.Buffer
+	records := make([]clickRecord, 0, len(clicks))
+	for _, click := range clicks {
+		line, err := json.Marshal(click)
+		if err != nil {
+			return err
+		}
+		records = append(records, clickRecord{offset: s.size + int64(buffer.Len()), length: len(line) + 1})
+		buffer.Write(line)
+		buffer.WriteByte('\n')
+	}
+
+	_, err := s.file.Write(buffer.Bytes())
+	if err != nil {
+		return errors.New(fmt.Sprintf("Write file %s error: %s", s.path, err))
+	}
+
+	s.size += int64(buffer.Len())
+	for i, click := range clicks {
+		s.addToIndex(click, records[i].offset, records[i].length)
+	}
+
+	return nil
+}
+
+func (s *FileClickSink) Clicks(host, path string, since time.Time) ([]Click, error) {
+	s.mutex.Lock()
+	var records []clickRecord
+	for _, record := range s.index[host+path] {
+		if !record.time.Before(since) {
+			records = append(records, record)
+		}
+	}
+	s.mutex.Unlock()
+
+	clicks := make([]Click, 0, len(records))
+	for _, record := range records {
+		line := make([]byte, record.length)
+		_, err := s.file.ReadAt(line, record.offset)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Read file %s error: %s", s.path, err))
+		}
+
+		var click Click
+		err = json.Unmarshal(line, &click)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Broken record at %s:%d: %s", s.path, record.offset, err))
+		}
+		clicks = append(clicks, click)
+	}
+
+	return clicks, nil
+}
+
+func (s *FileClickSink) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.file.Close()
+}
+
+// sqlTimeLayout is the text form of DATETIME, fractional seconds are optional
+const sqlTimeLayout = "2006-01-02 15:04:05.999999"
+
+// SQLClickSink keeps clicks in the click table of MySQL database
+type SQLClickSink struct {
+	db *sql.DB
+}
+
+func OpenSQLClickSink(driver, dsn string) (*SQLClickSink, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Open %s database error: %s", driver, err))
+	}
+
+	_, err = db.Exec("" +
+		"CREATE TABLE IF NOT EXISTS click (" +
+		"  host VARCHAR(255) NOT NULL DEFAULT ''," +
+		"  path VARCHAR(255) NOT NULL," +
+		"  clicked_at DATETIME(3) NOT NULL," +
+		"  referrer TEXT NOT NULL," +
+		"  user_agent TEXT NOT NULL," +
+		"  device VARCHAR(16) NOT NULL," +
+		"  visitor_id CHAR(16) NOT NULL," +
+		"  INDEX click_host_path_clicked_at (host, path, clicked_at)" +
+		")")
+	if err != nil {
+		_ = db.Close()
+		return nil, errors.New(fmt.Sprintf("Create click table error: %s", err))
+	}
+
+	err = ensureColumn(db, "click", "host", ""+
+		"ADD COLUMN host VARCHAR(255) NOT NULL DEFAULT '' FIRST, "+
+		"DROP INDEX click_path_clicked_at, ADD INDEX click_host_path_clicked_at (host, path, clicked_at)")
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &SQLClickSink{db: db}, nil
+}
+
+func (s *SQLClickSink) Record(clicks []Click) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare("" +
+		"INSERT INTO click (host, path, clicked_at, referrer, user_agent, device, visitor_id) " +
+		"VALUES (?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, click := range clicks {
+		_, err = stmt.Exec(click.Host, click.Path, click.Time, click.Referrer, click.UserAgent, click.Device, click.VisitorID)
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *SQLClickSink) Clicks(host, path string, since time.Time) ([]Click, error) {
+	rows, err := s.db.Query(""+
+		"SELECT host, path, clicked_at, referrer, user_agent, device, visitor_id "+
+		"FROM click WHERE host = ? AND path = ? AND clicked_at >= ? ORDER BY clicked_at", host, path, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var clicks []Click
+	for rows.Next() {
+		var click Click
+		var clickedAt []byte
+		err = rows.Scan(&click.Host, &click.Path, &clickedAt, &click.Referrer, &click.UserAgent, &click.Device, &click.VisitorID)
+		if err != nil {
+			return nil, err
+		}
+		click.Time, err = parseSQLTime(clickedAt)
+		if err != nil {
+			return nil, err
+		}
+		clicks = append(clicks, click)
+	}
+
+	return clicks, rows.Err()
+}
+
+// parseSQLTime reads DATETIME values in UTC, they come as text unless the dsn has parseTime=true
+// and database/sql formats time.Time values as RFC 3339 otherwise
+func parseSQLTime(value []byte) (time.Time, error) {
+	t, err := time.ParseInLocation(sqlTimeLayout, string(value), time.UTC)
+	if err == nil {
+		return t, nil
+	}
+
+	t, rfcErr := time.Parse(time.RFC3339Nano, string(value))
+	if rfcErr != nil {
+		return time.Time{}, errors.New(fmt.Sprintf("Parse time %q error: %s", value, err))
+	}
+
+	return t.UTC(), nil
+}
+
+func (s *SQLClickSink) Close() error {
+	return s.db.Close()
+}
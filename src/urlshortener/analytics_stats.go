@@ -0,0 +1,84 @@
+package main
+
+import (
+	"time"
+)
+
+const (
+	StatsBucketHour = "hour"
+	StatsBucketDay  = "day"
+)
+
+type StatsPoint struct {
+	Time           time.Time `json:"time"`
+	Clicks         int       `json:"clicks"`
+	UniqueVisitors int       `json:"unique_visitors"`
+}
+
+type LinkStats struct {
+	Code   string    `json:"code"`
+	Since  time.Time `json:"since"`
+	Bucket string    `json:"bucket"`
+	Clicks int       `json:"clicks"`
+	// DailyUniqueVisitors sums unique visitors of every day, visitor ids can't be linked between days
+	// so a visitor coming back on another day is counted again
+	DailyUniqueVisitors int            `json:"daily_unique_visitors"`
+	Referrers           map[string]int `json:"referrers"`
+	Devices             map[string]int `json:"devices"`
+	Series              []StatsPoint   `json:"series"`
+}
+
+func bucketDuration(bucket string) time.Duration {
+	if bucket == StatsBucketHour {
+		return time.Hour
+	}
+
+	return 24 * time.Hour
+}
+
+// BuildLinkStats aggregates clicks into buckets from since till now, empty buckets are included
+func BuildLinkStats(code string, clicks []Click, bucket string, since, now time.Time) LinkStats {
+	step := bucketDuration(bucket)
+	since = since.UTC().Truncate(step)
+
+	stats := LinkStats{
+		Code:      code,
+		Since:     since,
+		Bucket:    bucket,
+		Referrers: map[string]int{},
+		Devices:   map[string]int{},
+		Series:    []StatsPoint{},
+	}
+
+	dayVisitors := map[string]bool{}
+	bucketVisitors := map[time.Time]map[string]bool{}
+	bucketClicks := map[time.Time]int{}
+	for _, click := range clicks {
+		if click.Time.Before(since) {
+			continue
+		}
+
+		stats.Clicks++
+		dayVisitors[click.Time.UTC().Format(visitorSaltLayout)+" "+click.VisitorID] = true
+		stats.Referrers[referrerHost(click.Referrer)]++
+		stats.Devices[click.Device]++
+
+		t := click.Time.UTC().Truncate(step)
+		bucketClicks[t]++
+		if bucketVisitors[t] == nil {
+			bucketVisitors[t] = map[string]bool{}
+		}
+		bucketVisitors[t][click.VisitorID] = true
+	}
+	stats.DailyUniqueVisitors = len(dayVisitors)
+
+	for t := since; !t.After(now); t = t.Add(step) {
+		stats.Series = append(stats.Series, StatsPoint{
+			Time:           t,
+			Clicks:         bucketClicks[t],
+			UniqueVisitors: len(bucketVisitors[t]),
+		})
+	}
+
+	return stats
+}
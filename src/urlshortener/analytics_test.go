@@ -0,0 +1,265 @@
+package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDeviceClass(t *testing.T) {
+	cases := map[string]string{
+		"":                                   DeviceBot,
+		"Googlebot/2.1":                      DeviceBot,
+		"Mozilla/5.0 (iPad; CPU OS 14_0)":    DeviceTablet,
+		"Mozilla/5.0 (iPhone) Mobile/15E148": DeviceMobile,
+		"Mozilla/5.0 (Windows NT 10.0)":      DeviceDesktop,
+	}
+
+	for userAgent, want := range cases {
+		if have := deviceClass(userAgent); have != want {
+			t.Errorf("Device class of %q is wrong. Have: %s, want: %s", userAgent, have, want)
+		}
+	}
+}
+
+func TestBuildLinkStats(t *testing.T) {
+	now := time.Date(2021, 3, 10, 12, 30, 0, 0, time.UTC)
+	clicks := []Click{
+		{Path: "/ex", Time: now.Add(-25 * time.Hour), Device: DeviceDesktop, VisitorID: "a"},
+		{Path: "/ex", Time: now.Add(-time.Hour), Referrer: "https://t.co/abc", Device: DeviceMobile, VisitorID: "b"},
+		{Path: "/ex", Time: now, Device: DeviceMobile, VisitorID: "b"},
+	}
+
+	stats := BuildLinkStats("ex", clicks, StatsBucketDay, now.Add(-48*time.Hour), now)
+	if stats.Clicks != 3 || stats.DailyUniqueVisitors != 2 {
+		t.Errorf("Totals are wrong. Have: %d clicks of %d visitors, want: 3 clicks of 2 visitors", stats.Clicks, stats.DailyUniqueVisitors)
+	}
+	if stats.Referrers["t.co"] != 1 || stats.Referrers[directReferrer] != 2 {
+		t.Errorf("Referrers are wrong: %v", stats.Referrers)
+	}
+	if stats.Devices[DeviceMobile] != 2 {
+		t.Errorf("Devices are wrong: %v", stats.Devices)
+	}
+
+	wantSeries := []int{0, 1, 2}
+	if len(stats.Series) != len(wantSeries) {
+		t.Fatalf("Series length is wrong. Have: %d, want: %d", len(stats.Series), len(wantSeries))
+	}
+	for i, point := range stats.Series {
+		if point.Clicks != wantSeries[i] {
+			t.Errorf("Clicks of %s are wrong. Have: %d, want: %d", point.Time, point.Clicks, wantSeries[i])
+		}
+	}
+	if stats.Series[2].UniqueVisitors != 1 {
+		t.Errorf("Unique visitors of %s are wrong. Have: %d, want: 1", stats.Series[2].Time, stats.Series[2].UniqueVisitors)
+	}
+}
+
+func TestMemoryClickSinkOverwritesOldest(t *testing.T) {
+	sink := NewMemoryClickSink(2)
+	now := time.Now()
+	_ = sink.Record([]Click{{Path: "/a", Time: now}, {Path: "/a", Time: now.Add(time.Second)}, {Path: "/a", Time: now.Add(2 * time.Second)}})
+
+	clicks, _ := sink.Clicks(DefaultDomain, "/a", now)
+	if len(clicks) != 2 || !clicks[0].Time.Equal(now.Add(time.Second)) {
+		t.Errorf("Ring keeps wrong clicks: %v", clicks)
+	}
+}
+
+func TestVisitorID(t *testing.T) {
+	visitors, err := newVisitorHasher("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := newVisitorHasher("other")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest("GET", "/a", nil)
+	r.Header.Set("User-Agent", "Mozilla/5.0")
+	now := time.Date(2021, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	id := visitors.ID(r, now)
+	if have := visitors.ID(r, now.Add(time.Hour)); have != id {
+		t.Errorf("Visitor id changes within a day. Have: %s, want: %s", have, id)
+	}
+	if have := visitors.ID(r, now.Add(24*time.Hour)); have == id {
+		t.Errorf("Visitor id is not changed on the next day: %s", have)
+	}
+	if have := other.ID(r, now); have == id {
+		t.Errorf("Visitor id does not depend on the secret: %s", have)
+	}
+}
+
+func TestFileClickSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clicks.log")
+	sink, err := OpenFileClickSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2021, 3, 10, 12, 0, 0, 0, time.UTC)
+	err = sink.Record([]Click{
+		{Path: "/a", Time: now.Add(-time.Hour), VisitorID: "old"},
+		{Path: "/a", Time: now, VisitorID: "a"},
+		{Path: "/b", Time: now, VisitorID: "b"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// torn record of a crash is skipped and does not break the next one
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = file.WriteString(`{"path": "/a", "ti`)
+	_ = file.Close()
+
+	sink, err = OpenFileClickSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	if err = sink.Record([]Click{{Path: "/a", Time: now.Add(time.Hour), VisitorID: "new"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	clicks, err := sink.Clicks(DefaultDomain, "/a", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clicks) != 2 || clicks[0].VisitorID != "a" || clicks[1].VisitorID != "new" {
+		t.Errorf("Clicks are wrong: %v", clicks)
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content[len(content)-1] != '\n' {
+		t.Errorf("Log does not end with a record: %q", content)
+	}
+}
+
+// clickRowsDriver answers every query with one click row, clicked_at is returned as given
+type clickRowsDriver struct {
+	clickedAt driver.Value
+}
+
+func (d clickRowsDriver) Open(string) (driver.Conn, error) {
+	return clickRowsConn(d), nil
+}
+
+type clickRowsConn clickRowsDriver
+
+func (c clickRowsConn) Prepare(string) (driver.Stmt, error) {
+	return clickRowsStmt(c), nil
+}
+
+func (clickRowsConn) Close() error {
+	return nil
+}
+
+func (clickRowsConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not supported")
+}
+
+type clickRowsStmt clickRowsDriver
+
+func (clickRowsStmt) Close() error {
+	return nil
+}
+
+func (clickRowsStmt) NumInput() int {
+	return -1
+}
+
+func (clickRowsStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+
+func (s clickRowsStmt) Query([]driver.Value) (driver.Rows, error) {
+	return &clickRows{clickedAt: s.clickedAt}, nil
+}
+
+type clickRows struct {
+	clickedAt driver.Value
+	read      bool
+}
+
+func (*clickRows) Columns() []string {
+	return []string{"host", "path", "clicked_at", "referrer", "user_agent", "device", "visitor_id"}
+}
+
+func (*clickRows) Close() error {
+	return nil
+}
+
+func (r *clickRows) Next(dest []driver.Value) error {
+	if r.read {
+		return io.EOF
+	}
+	r.read = true
+
+	values := []driver.Value{[]byte(""), []byte("/a"), r.clickedAt, []byte(""), []byte(""), []byte(DeviceDesktop), []byte("a")}
+	copy(dest, values)
+	return nil
+}
+
+func TestSQLClickSinkReadsTime(t *testing.T) {
+	want := time.Date(2021, 3, 10, 12, 30, 0, 123000000, time.UTC)
+	values := map[string]driver.Value{
+		"text":       []byte("2021-03-10 12:30:00.123"),
+		"parse time": want,
+	}
+
+	for name, value := range values {
+		sql.Register("clicks "+name, clickRowsDriver{clickedAt: value})
+		db, err := sql.Open("clicks "+name, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sink := &SQLClickSink{db: db}
+		clicks, err := sink.Clicks(DefaultDomain, "/a", want.Add(-time.Hour))
+		if err != nil {
+			t.Errorf("Clicks of %s time are not read: %s", name, err)
+		} else if len(clicks) != 1 || !clicks[0].Time.Equal(want) {
+			t.Errorf("Clicks of %s time are wrong: %v", name, clicks)
+		}
+		_ = sink.Close()
+	}
+}
+
+func TestRecordAfterClose(t *testing.T) {
+	sink := NewMemoryClickSink(10)
+	clicks, err := NewClickRecorder(sink, 10, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest("GET", "/a", nil)
+	clicks.Record(r, DefaultDomain, "/a")
+	if err = clicks.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// a handler outliving the shutdown timeout must not panic
+	clicks.Record(r, DefaultDomain, "/a")
+	_ = clicks.Close()
+
+	if recorded, _ := sink.Clicks(DefaultDomain, "/a", time.Time{}); len(recorded) != 1 {
+		t.Errorf("Buffered clicks are not recorded on close: %d", len(recorded))
+	}
+}
@@ -0,0 +1,475 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const ApiLinksPath = "/api/links"
+
+const statsPathSuffix = "/stats"
+
+const (
+	defaultHourStatsPeriod = 48 * time.Hour
+	defaultDayStatsPeriod  = 30 * 24 * time.Hour
+	maxStatsPoints         = 24 * 366
+)
+
+const (
+	codeAlphabet      = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	codeLength        = 7
+	codeMaxAttempts   = 10
+	maxApiRequestSize = 64 * 1024
+)
+
+var aliasPattern = regexp.MustCompile(`^[0-9A-Za-z_-]{1,64}$`)
+
+// reservedAliases are paths served by the shortener itself on every domain
+var reservedAliases = map[string]bool{
+	strings.Trim(AdminPath, "/"): true,
+}
+
+type updateLinkRequest struct {
+	URL string `json:"url"`
+	LinkOptions
+}
+
+func (r updateLinkRequest) target() LinkTarget {
+	return LinkTarget{URL: r.URL, LinkOptions: r.LinkOptions}
+}
+
+type createLinkRequest struct {
+	updateLinkRequest
+	Alias  string `json:"alias"`
+	Domain string `json:"domain"`
+}
+
+type linkRequest interface {
+	target() LinkTarget
+}
+
+type linkResponse struct {
+	Domain   string `json:"domain,omitempty"`
+	Code     string `json:"code"`
+	ShortURL string `json:"short_url"`
+	URL      string `json:"url"`
+	LinkOptions
+	// ReadOnly links come from config file and are changed there
+	ReadOnly bool `json:"read_only,omitempty"`
+}
+
+type linkListResponse struct {
+	Links []linkResponse `json:"links"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type apiDomain struct {
+	staticPaths map[string]LinkTarget
+	baseURL     string
+}
+
+// LinkApiHttpHandler manages links of the storage, codes are link paths without leading slash,
+// links of other than default domain are selected by domain query parameter
+type LinkApiHttpHandler struct {
+	storage LinkStorage
+	domains map[string]apiDomain
+	clicks  ClickSink
+	targets TargetValidator
+}
+
+func (h LinkApiHttpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == ApiLinksPath {
+		switch r.Method {
+		case http.MethodGet:
+			h.listLinks(w, r)
+		case http.MethodPost:
+			h.createLink(w, r)
+		default:
+			methodNotAllowed(w, http.MethodGet, http.MethodPost)
+		}
+		return
+	}
+
+	host := NormalizeHost(r.URL.Query().Get("domain"))
+	if _, found := h.domains[host]; !found {
+		writeApiError(w, http.StatusBadRequest, fmt.Sprintf("Unknown domain %s", host))
+		return
+	}
+
+	code := strings.TrimPrefix(r.URL.Path, ApiLinksPath+"/")
+	if strings.HasSuffix(code, statsPathSuffix) {
+		code = strings.TrimSuffix(code, statsPathSuffix)
+		if !aliasPattern.MatchString(code) {
+			writeApiError(w, http.StatusNotFound, ErrLinkNotFound.Error())
+			return
+		}
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, http.MethodGet)
+			return
+		}
+		h.getLinkStats(w, r, host, code)
+		return
+	}
+
+	if !aliasPattern.MatchString(code) {
+		writeApiError(w, http.StatusNotFound, ErrLinkNotFound.Error())
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.getLink(w, r, host, code)
+	case http.MethodPut:
+		h.updateLink(w, r, host, code)
+	case http.MethodDelete:
+		h.deleteLink(w, host, code)
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPut, http.MethodDelete)
+	}
+}
+
+func (h LinkApiHttpHandler) listLinks(w http.ResponseWriter, r *http.Request) {
+	links, err := h.storage.List()
+	if err != nil {
+		fmt.Printf("List links error: %s\n", err)
+		writeApiError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+
+	query := strings.ToLower(r.URL.Query().Get("q"))
+	_, filterDomain := r.URL.Query()["domain"]
+	domainFilter := NormalizeHost(r.URL.Query().Get("domain"))
+	matches := func(link Link) bool {
+		if filterDomain && link.Host != domainFilter {
+			return false
+		}
+		return query == "" ||
+			strings.Contains(strings.ToLower(link.Host+link.Path), query) ||
+			strings.Contains(strings.ToLower(link.URL), query)
+	}
+
+	response := linkListResponse{Links: []linkResponse{}}
+	for host, domain := range h.domains {
+		for short, long := range domain.staticPaths {
+			link := Link{Host: host, Path: short, URL: long.URL, LinkOptions: long.LinkOptions}
+			if matches(link) {
+				item := h.linkResponse(r, link)
+				item.ReadOnly = true
+				response.Links = append(response.Links, item)
+			}
+		}
+	}
+	for _, link := range links {
+		if matches(link) {
+			response.Links = append(response.Links, h.linkResponse(r, link))
+		}
+	}
+
+	sort.Slice(response.Links, func(i, j int) bool {
+		a, b := response.Links[i], response.Links[j]
+		if a.Domain != b.Domain {
+			return a.Domain < b.Domain
+		}
+		return a.Code < b.Code
+	})
+	writeApiJson(w, http.StatusOK, response)
+}
+
+// decodeLinkRequest reads json body into request and validates url and options of the target
+func (h LinkApiHttpHandler) decodeLinkRequest(w http.ResponseWriter, r *http.Request, request linkRequest) (LinkTarget, bool) {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxApiRequestSize))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(request)
+	if err != nil {
+		writeApiError(w, http.StatusBadRequest, fmt.Sprintf("Invalid json: %s", err))
+		return LinkTarget{}, false
+	}
+
+	target := request.target()
+	// links to the host the api is called on would redirect to the shortener itself
+	err = h.targets.ValidateTargetURL(target.URL, r.Host)
+	if err == nil {
+		err = ValidateLinkOptions(target.LinkOptions)
+	}
+	if err != nil {
+		writeApiError(w, http.StatusBadRequest, err.Error())
+		return LinkTarget{}, false
+	}
+
+	return target, true
+}
+
+func (h LinkApiHttpHandler) createLink(w http.ResponseWriter, r *http.Request) {
+	var request createLinkRequest
+	target, ok := h.decodeLinkRequest(w, r, &request)
+	if !ok {
+		return
+	}
+
+	host := NormalizeHost(request.Domain)
+	if _, found := h.domains[host]; !found {
+		writeApiError(w, http.StatusBadRequest, fmt.Sprintf("Unknown domain %s", host))
+		return
+	}
+
+	var err error
+	var link Link
+	if request.Alias != "" {
+		link, err = h.addAlias(host, request.Alias, target)
+	} else {
+		link, err = h.addGenerated(host, target)
+	}
+	if err == ErrLinkExists {
+		writeApiError(w, http.StatusConflict, fmt.Sprintf("Alias %s is already taken", request.Alias))
+		return
+	}
+	if err != nil {
+		var validationErr validationError
+		if errors.As(err, &validationErr) {
+			writeApiError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		fmt.Printf("Add link error: %s\n", err)
+		writeApiError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+
+	response := h.linkResponse(r, link)
+	w.Header().Set("Location", linkLocation(host, response.Code))
+	writeApiJson(w, http.StatusCreated, response)
+}
+
+func linkLocation(host, code string) string {
+	location := ApiLinksPath + "/" + code
+	if host != DefaultDomain {
+		location += "?domain=" + url.QueryEscape(host)
+	}
+
+	return location
+}
+
+func (h LinkApiHttpHandler) addAlias(host, alias string, target LinkTarget) (Link, error) {
+	if !aliasPattern.MatchString(alias) {
+		return Link{}, validationError("Alias may contain only latin letters, digits, '_' and '-', up to 64 characters")
+	}
+	if reservedAliases[alias] {
+		return Link{}, validationError(fmt.Sprintf("Alias %s is reserved", alias))
+	}
+
+	link := Link{Host: host, Path: "/" + alias, URL: target.URL, LinkOptions: target.LinkOptions}
+	if _, found := h.domains[host].staticPaths[link.Path]; found {
+		return Link{}, ErrLinkExists
+	}
+
+	return link, h.storage.Add(link)
+}
+
+func (h LinkApiHttpHandler) addGenerated(host string, target LinkTarget) (Link, error) {
+	for i := 0; i < codeMaxAttempts; i++ {
+		code, err := generateCode()
+		if err != nil {
+			return Link{}, err
+		}
+
+		link := Link{Host: host, Path: "/" + code, URL: target.URL, LinkOptions: target.LinkOptions}
+		if _, found := h.domains[host].staticPaths[link.Path]; found {
+			continue
+		}
+
+		err = h.storage.Add(link)
+		if err == ErrLinkExists {
+			continue
+		}
+
+		return link, err
+	}
+
+	return Link{}, errors.New(fmt.Sprintf("No free code after %d attempts", codeMaxAttempts))
+}
+
+func generateCode() (string, error) {
+	code := make([]byte, codeLength)
+	max := big.NewInt(int64(len(codeAlphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = codeAlphabet[n.Int64()]
+	}
+
+	return string(code), nil
+}
+
+func (h LinkApiHttpHandler) getLink(w http.ResponseWriter, r *http.Request, host, code string) {
+	link, err := h.storage.Get(host, "/"+code)
+	if err != nil {
+		fmt.Printf("Get link %s error: %s\n", code, err)
+		writeApiError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+	if link == nil {
+		writeApiError(w, http.StatusNotFound, ErrLinkNotFound.Error())
+		return
+	}
+
+	writeApiJson(w, http.StatusOK, h.linkResponse(r, *link))
+}
+
+func (h LinkApiHttpHandler) updateLink(w http.ResponseWriter, r *http.Request, host, code string) {
+	path := "/" + code
+	if _, found := h.domains[host].staticPaths[path]; found {
+		writeApiError(w, http.StatusConflict, fmt.Sprintf("Link %s is defined in config file", code))
+		return
+	}
+
+	var request updateLinkRequest
+	target, ok := h.decodeLinkRequest(w, r, &request)
+	if !ok {
+		return
+	}
+
+	link := Link{Host: host, Path: path, URL: target.URL, LinkOptions: target.LinkOptions}
+	err := h.storage.Update(link)
+	if err == ErrLinkNotFound {
+		writeApiError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		fmt.Printf("Update link %s error: %s\n", code, err)
+		writeApiError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+
+	writeApiJson(w, http.StatusOK, h.linkResponse(r, link))
+}
+
+func (h LinkApiHttpHandler) deleteLink(w http.ResponseWriter, host, code string) {
+	err := h.storage.Delete(host, "/"+code)
+	if err == ErrLinkNotFound {
+		writeApiError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		fmt.Printf("Delete link %s error: %s\n", code, err)
+		writeApiError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h LinkApiHttpHandler) getLinkStats(w http.ResponseWriter, r *http.Request, host, code string) {
+	path := "/" + code
+	if _, found := h.domains[host].staticPaths[path]; !found {
+		link, err := h.storage.Get(host, path)
+		if err != nil {
+			fmt.Printf("Get link %s error: %s\n", code, err)
+			writeApiError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+			return
+		}
+		if link == nil {
+			writeApiError(w, http.StatusNotFound, ErrLinkNotFound.Error())
+			return
+		}
+	}
+
+	query := r.URL.Query()
+	bucket := query.Get("bucket")
+	period := defaultDayStatsPeriod
+	switch bucket {
+	case "", StatsBucketDay:
+		bucket = StatsBucketDay
+	case StatsBucketHour:
+		period = defaultHourStatsPeriod
+	default:
+		writeApiError(w, http.StatusBadRequest, "Bucket must be hour or day")
+		return
+	}
+
+	now := time.Now().UTC()
+	since := now.Add(-period)
+	if value := query.Get("since"); value != "" {
+		var err error
+		since, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			writeApiError(w, http.StatusBadRequest, "Since must be RFC 3339 time")
+			return
+		}
+	}
+	if now.Sub(since)/bucketDuration(bucket) > maxStatsPoints {
+		writeApiError(w, http.StatusBadRequest, fmt.Sprintf("Period is too long, at most %d buckets", maxStatsPoints))
+		return
+	}
+
+	clicks, err := h.clicks.Clicks(host, path, since.Truncate(bucketDuration(bucket)))
+	if err != nil {
+		fmt.Printf("Get clicks of %s error: %s\n", code, err)
+		writeApiError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+
+	writeApiJson(w, http.StatusOK, BuildLinkStats(code, clicks, bucket, since, now))
+}
+
+func (h LinkApiHttpHandler) linkResponse(r *http.Request, link Link) linkResponse {
+	return linkResponse{
+		Domain:      link.Host,
+		Code:        strings.TrimPrefix(link.Path, "/"),
+		ShortURL:    shortURLBase(r, link.Host, h.domains[link.Host].baseURL) + link.Path,
+		URL:         link.URL,
+		LinkOptions: link.LinkOptions,
+	}
+}
+
+// shortURLBase prefers configured base url, the request host is used for default domain behind no proxy only
+func shortURLBase(r *http.Request, host, baseURL string) string {
+	if baseURL != "" {
+		return strings.TrimSuffix(baseURL, "/")
+	}
+	if host != DefaultDomain {
+		return "https://" + host
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+type validationError string
+
+func (e validationError) Error() string {
+	return string(e)
+}
+
+func methodNotAllowed(w http.ResponseWriter, methods ...string) {
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeApiError(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+}
+
+func writeApiError(w http.ResponseWriter, status int, message string) {
+	writeApiJson(w, status, errorResponse{Error: message})
+}
+
+func writeApiJson(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		fmt.Printf("Write http response error: %s\n", err)
+	}
+}
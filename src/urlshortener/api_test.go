@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testApiToken = "token"
+
+func prepareApiHandler(t *testing.T, config Config, sink ClickSink) http.Handler {
+	storage, err := OpenFileLinkStorage(filepath.Join(t.TempDir(), "links.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { storage.Close() })
+
+	EnsureConfigValid(&config)
+	if err = ValidateConfig(config); err != nil {
+		t.Fatal(err)
+	}
+
+	clicks, err := NewClickRecorder(sink, 10, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { clicks.Close() })
+	hh, err := PrepareHttpHandler(config, storage, clicks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return hh
+}
+
+func apiRequest(hh http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer "+testApiToken)
+	w := httptest.NewRecorder()
+	hh.ServeHTTP(w, r)
+	return w
+}
+
+func TestCreateLinkAlias(t *testing.T) {
+	config := Config{
+		Paths: map[string]LinkTarget{"/static": {URL: "https://golang.org"}},
+		Admin: AdminConfig{Token: testApiToken},
+	}
+	hh := prepareApiHandler(t, config, NewMemoryClickSink(10))
+
+	cases := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"alias", `{"url": "https://golang.org/doc", "alias": "doc"}`, http.StatusCreated},
+		{"taken alias", `{"url": "https://golang.org", "alias": "doc"}`, http.StatusConflict},
+		{"config path", `{"url": "https://golang.org", "alias": "static"}`, http.StatusConflict},
+		{"reserved alias", `{"url": "https://golang.org", "alias": "admin"}`, http.StatusBadRequest},
+		{"slash in alias", `{"url": "https://golang.org", "alias": "a/b"}`, http.StatusBadRequest},
+		{"long alias", `{"url": "https://golang.org", "alias": "` + strings.Repeat("a", 65) + `"}`, http.StatusBadRequest},
+		{"generated code", `{"url": "https://golang.org"}`, http.StatusCreated},
+		{"not http url", `{"url": "ftp://golang.org"}`, http.StatusBadRequest},
+		{"unknown field", `{"url": "https://golang.org", "code": "x"}`, http.StatusBadRequest},
+	}
+
+	for _, c := range cases {
+		w := apiRequest(hh, http.MethodPost, ApiLinksPath, c.body)
+		if w.Code != c.status {
+			t.Errorf("Status code is wrong for %s. Have: %d, want: %d, body: %s", c.name, w.Code, c.status, w.Body.String())
+		}
+	}
+
+	w := apiRequest(hh, http.MethodGet, ApiLinksPath+"/doc", "")
+	var link linkResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &link); err != nil {
+		t.Fatal(err)
+	}
+	if link.URL != "https://golang.org/doc" || link.ShortURL != "http://example.com/doc" {
+		t.Errorf("Link is wrong. Have: %+v", link)
+	}
+}
+
+func TestDeleteLink(t *testing.T) {
+	config := Config{Admin: AdminConfig{Token: testApiToken}}
+	hh := prepareApiHandler(t, config, NewMemoryClickSink(10))
+
+	w := apiRequest(hh, http.MethodPost, ApiLinksPath, `{"url": "https://golang.org", "alias": "go"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Status code is wrong. Have: %d, want: %d", w.Code, http.StatusCreated)
+	}
+
+	cases := []struct {
+		method string
+		status int
+	}{
+		{http.MethodDelete, http.StatusNoContent},
+		{http.MethodGet, http.StatusNotFound},
+		{http.MethodDelete, http.StatusNotFound},
+	}
+	for _, c := range cases {
+		w = apiRequest(hh, c.method, ApiLinksPath+"/go", "")
+		if w.Code != c.status {
+			t.Errorf("Status code of %s is wrong. Have: %d, want: %d", c.method, w.Code, c.status)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	hh.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/go", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Deleted link is served. Have: %d, want: %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestLinkMutationsNeedCredentials(t *testing.T) {
+	hh := prepareApiHandler(t, Config{}, NewMemoryClickSink(10))
+
+	w := httptest.NewRecorder()
+	hh.ServeHTTP(w, httptest.NewRequest(http.MethodPost, ApiLinksPath, strings.NewReader(`{"url": "https://golang.org"}`)))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Status code is wrong. Have: %d, want: %d", w.Code, http.StatusForbidden)
+	}
+}
+
+func TestLinkStats(t *testing.T) {
+	config := Config{Admin: AdminConfig{Token: testApiToken}}
+	sink := NewMemoryClickSink(10)
+	hh := prepareApiHandler(t, config, sink)
+
+	w := apiRequest(hh, http.MethodPost, ApiLinksPath, `{"url": "https://golang.org", "alias": "go"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Status code is wrong. Have: %d, want: %d", w.Code, http.StatusCreated)
+	}
+
+	now := time.Now().UTC()
+	_ = sink.Record([]Click{
+		{Host: DefaultDomain, Path: "/go", Time: now.Add(-time.Hour), Device: DeviceDesktop, VisitorID: "a"},
+		{Host: DefaultDomain, Path: "/go", Time: now, Device: DeviceMobile, VisitorID: "a"},
+		{Host: DefaultDomain, Path: "/other", Time: now, Device: DeviceMobile, VisitorID: "b"},
+	})
+
+	w = apiRequest(hh, http.MethodGet, ApiLinksPath+"/go/stats?bucket=hour", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Status code is wrong. Have: %d, want: %d", w.Code, http.StatusOK)
+	}
+	var stats LinkStats
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Clicks != 2 || stats.DailyUniqueVisitors != 1 {
+		t.Errorf("Totals are wrong. Have: %d clicks of %d visitors, want: 2 clicks of 1 visitor", stats.Clicks, stats.DailyUniqueVisitors)
+	}
+
+	cases := map[string]int{
+		ApiLinksPath + "/go/stats?bucket=week":                http.StatusBadRequest,
+		ApiLinksPath + "/go/stats?since=yesterday":            http.StatusBadRequest,
+		ApiLinksPath + "/go/stats?since=2000-01-01T00:00:00Z": http.StatusBadRequest,
+		ApiLinksPath + "/missing/stats":                       http.StatusNotFound,
+	}
+	for path, status := range cases {
+		w = apiRequest(hh, http.MethodGet, path, "")
+		if w.Code != status {
+			t.Errorf("Status code of %s is wrong. Have: %d, want: %d", path, w.Code, status)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// DefaultDomain serves top level config paths and requests of hosts without own domain config
+const DefaultDomain = ""
+
+// DomainConfig is a namespace of links served on its own host name
+type DomainConfig struct {
+	NotFoundMessage string                `json:"not_found_message"`
+	Paths           map[string]LinkTarget `json:"paths"`
+	// BaseURL is prepended to codes of created links, https://domain is used when empty
+	BaseURL string `json:"base_url"`
+}
+
+// DomainConfigs returns configs of all domains, DefaultDomain is made of top level fields
+func (c Config) DomainConfigs() map[string]DomainConfig {
+	domains := map[string]DomainConfig{
+		DefaultDomain: {NotFoundMessage: c.NotFoundMessage, Paths: c.Paths, BaseURL: c.BaseURL},
+	}
+	for host, domain := range c.Domains {
+		domains[host] = domain
+	}
+
+	return domains
+}
+
+func EnsureDomainsValid(config *Config) {
+	for host, domain := range config.Domains {
+		if domain.NotFoundMessage == "" {
+			domain.NotFoundMessage = config.NotFoundMessage
+		}
+		if domain.Paths == nil {
+			domain.Paths = map[string]LinkTarget{}
+		}
+		config.Domains[host] = domain
+	}
+}
+
+func validateDomains(config Config) error {
+	for host := range config.Domains {
+		if host == DefaultDomain || host != NormalizeHost(host) || strings.ContainsAny(host, "/?#@") {
+			return errors.New(fmt.Sprintf("Domain %q must be a lower case host name without port", host))
+		}
+	}
+
+	return nil
+}
+
+// NormalizeHost removes port and trailing dot from Host header
+func NormalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+func domainName(host string) string {
+	if host == DefaultDomain {
+		return "default domain"
+	}
+
+	return host
+}
+
+// HostHttpHandler passes requests to the handler of their host, DefaultDomain handles unknown hosts
+type HostHttpHandler struct {
+	domains map[string]http.Handler
+}
+
+func (h HostHttpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	hh, found := h.domains[NormalizeHost(r.Host)]
+	if !found {
+		hh = h.domains[DefaultDomain]
+	}
+
+	hh.ServeHTTP(w, r)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestHostHttpHandler(t *testing.T) {
+	storage, err := OpenFileLinkStorage(filepath.Join(t.TempDir(), "links.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+	_ = storage.Add(Link{Host: "b.link", Path: "/x", URL: "https://b.example/x"})
+	_ = storage.Add(Link{Path: "/x", URL: "https://a.example/x"})
+
+	config := Config{
+		Paths: map[string]LinkTarget{"/docs": {URL: "https://a.example/docs"}},
+		Domains: map[string]DomainConfig{
+			"b.link": {Paths: map[string]LinkTarget{"/docs": {URL: "https://b.example/docs"}}},
+		},
+	}
+	EnsureConfigValid(&config)
+	if err = ValidateConfig(config); err != nil {
+		t.Fatal(err)
+	}
+
+	clicks, err := NewClickRecorder(NewMemoryClickSink(10), 10, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clicks.Close()
+	hh, err := PrepareHttpHandler(config, storage, clicks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		host     string
+		path     string
+		location string
+	}{
+		{"a.link", "/docs", "https://a.example/docs"},
+		{"B.link:8080", "/docs", "https://b.example/docs"},
+		{"a.link", "/x", "https://a.example/x"},
+		{"b.link", "/x", "https://b.example/x"},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, c.path, nil)
+		r.Host = c.host
+		hh.ServeHTTP(w, r)
+		if have := w.Header().Get("Location"); have != c.location {
+			t.Errorf("Location is wrong for %s%s. Have: %s, want: %s", c.host, c.path, have, c.location)
+		}
+	}
+}
+
+func TestValidateDomains(t *testing.T) {
+	cases := map[string]bool{
+		"b.link":      true,
+		"B.link":      false,
+		"b.link:8080": false,
+		"b.link/x":    false,
+	}
+
+	for host, valid := range cases {
+		config := Config{Domains: map[string]DomainConfig{host: {}}}
+		if err := validateDomains(config); (err == nil) != valid {
+			t.Errorf("Validation of %q is wrong. Have: %v, want valid: %v", host, err, valid)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+module urlshortener
+
+go 1.16
+
+require (
+	github.com/fsnotify/fsnotify v1.5.1
+	github.com/go-sql-driver/mysql v1.5.0
+)
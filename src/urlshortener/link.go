@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const DefaultRedirectCode = http.StatusSeeOther
+
+var redirectCodes = map[int]bool{
+	http.StatusMovedPermanently:  true,
+	http.StatusFound:             true,
+	http.StatusSeeOther:          true,
+	http.StatusTemporaryRedirect: true,
+	http.StatusPermanentRedirect: true,
+}
+
+// LinkOptions are optional per link settings, zero value keeps the plain string link behaviour
+type LinkOptions struct {
+	RedirectCode int        `json:"redirect_code,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	// MaxClicks is the number of redirects before the link is gone, 0 is unlimited
+	MaxClicks int64 `json:"max_clicks,omitempty"`
+	// Enabled is true when omitted, disabled links are not found
+	Enabled *bool `json:"enabled,omitempty"`
+	// QueryPassthrough appends query string of the short link to the target url
+	QueryPassthrough bool `json:"query_passthrough,omitempty"`
+}
+
+func (o LinkOptions) IsEnabled() bool {
+	return o.Enabled == nil || *o.Enabled
+}
+
+func (o LinkOptions) IsExpired(now time.Time) bool {
+	return o.ExpiresAt != nil && !now.Before(*o.ExpiresAt)
+}
+
+func (o LinkOptions) StatusCode() int {
+	if o.RedirectCode == 0 {
+		return DefaultRedirectCode
+	}
+
+	return o.RedirectCode
+}
+
+func ValidateLinkOptions(o LinkOptions) error {
+	if o.RedirectCode != 0 && !redirectCodes[o.RedirectCode] {
+		return validationError(fmt.Sprintf("Redirect code %d is not one of 301, 302, 303, 307, 308", o.RedirectCode))
+	}
+	if o.MaxClicks < 0 {
+		return validationError("Max clicks must not be negative")
+	}
+
+	return nil
+}
+
+// LinkTarget is a Config.Paths value, either a plain url string or an object with url and options
+type LinkTarget struct {
+	URL string `json:"url"`
+	LinkOptions
+}
+
+func (t *LinkTarget) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(`"`)) {
+		*t = LinkTarget{}
+		return json.Unmarshal(data, &t.URL)
+	}
+
+	// alias type drops UnmarshalJSON to decode the object form with the default decoder
+	type linkTarget LinkTarget
+	var target linkTarget
+	err := json.Unmarshal(data, &target)
+	if err != nil {
+		return err
+	}
+	if target.URL == "" {
+		return errors.New("link object must have url")
+	}
+
+	*t = LinkTarget(target)
+	return nil
+}
+
+func (t LinkTarget) MarshalJSON() ([]byte, error) {
+	if t.LinkOptions == (LinkOptions{}) {
+		return json.Marshal(t.URL)
+	}
+
+	type linkTarget LinkTarget
+	return json.Marshal(linkTarget(t))
+}
+
+func (t LinkTarget) String() string {
+	if t.LinkOptions == (LinkOptions{}) {
+		return t.URL
+	}
+
+	options, err := json.Marshal(t.LinkOptions)
+	if err != nil {
+		return t.URL
+	}
+	return fmt.Sprintf("%s %s", t.URL, options)
+}
+
+// RedirectURL is the target url with query string of the request when passthrough is enabled
+func (t LinkTarget) RedirectURL(r *http.Request) string {
+	if !t.QueryPassthrough || r.URL.RawQuery == "" {
+		return t.URL
+	}
+
+	u, err := url.Parse(t.URL)
+	if err != nil {
+		return t.URL
+	}
+	if u.RawQuery == "" {
+		u.RawQuery = r.URL.RawQuery
+	} else {
+		u.RawQuery = u.RawQuery + "&" + r.URL.RawQuery
+	}
+
+	return u.String()
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLinkTargetJson(t *testing.T) {
+	var paths map[string]LinkTarget
+	err := json.Unmarshal([]byte(`{
+		"/plain": "https://plain.example",
+		"/perm": {"url": "https://perm.example", "redirect_code": 308, "max_clicks": 5, "enabled": false}
+	}`), &paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if paths["/plain"].URL != "https://plain.example" || paths["/plain"].StatusCode() != DefaultRedirectCode {
+		t.Errorf("Plain link is parsed wrong: %v", paths["/plain"])
+	}
+	perm := paths["/perm"]
+	if perm.URL != "https://perm.example" || perm.StatusCode() != 308 || perm.MaxClicks != 5 || perm.IsEnabled() {
+		t.Errorf("Link object is parsed wrong: %v", perm)
+	}
+
+	plain, _ := json.Marshal(paths["/plain"])
+	if string(plain) != `"https://plain.example"` {
+		t.Errorf("Plain link is marshaled as %s", plain)
+	}
+
+	if err = json.Unmarshal([]byte(`{"redirect_code": 301}`), &perm); err == nil {
+		t.Errorf("Link object without url is accepted")
+	}
+}
+
+func TestRedirectURLQueryPassthrough(t *testing.T) {
+	cases := []struct {
+		target LinkTarget
+		want   string
+	}{
+		{LinkTarget{URL: "https://a.example/x"}, "https://a.example/x"},
+		{LinkTarget{URL: "https://a.example/x", LinkOptions: LinkOptions{QueryPassthrough: true}}, "https://a.example/x?utm=1"},
+		{LinkTarget{URL: "https://a.example/x?id=2", LinkOptions: LinkOptions{QueryPassthrough: true}}, "https://a.example/x?id=2&utm=1"},
+	}
+
+	r := httptest.NewRequest("GET", "/short?utm=1", nil)
+	for _, c := range cases {
+		if have := c.target.RedirectURL(r); have != c.want {
+			t.Errorf("Redirect url is wrong. Have: %s, want: %s", have, c.want)
+		}
+	}
+}
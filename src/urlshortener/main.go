@@ -1,22 +1,41 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
 )
 
 const DefaultPort = 8080
 const DefaultNotFoundMessage = "Page %s is not found :("
+const ShutdownTimeout = 10 * time.Second
 
 type Config struct {
-	NotFoundMessage string            `json:"not_found_message"`
-	Port            int               `json:"server_port"`
-	Paths           map[string]string `json:"paths"`
+	NotFoundMessage string `json:"not_found_message"`
+	// NotFoundTemplate is a html/template file of 404 page, HidePaths removes the listing of paths from it
+	NotFoundTemplate string                `json:"not_found_template"`
+	HidePaths        bool                  `json:"hide_paths"`
+	Port             int                   `json:"server_port"`
+	Paths            map[string]LinkTarget `json:"paths"`
+	Storage          StorageConfig         `json:"storage"`
+	// BaseURL is prepended to codes of created links, request host is used when empty
+	BaseURL   string          `json:"base_url"`
+	Analytics AnalyticsConfig `json:"analytics"`
+	Admin     AdminConfig     `json:"admin"`
+	// Domains have own paths served on their host names, top level paths are the default domain
+	Domains map[string]DomainConfig `json:"domains"`
+	Safety  SafetyConfig            `json:"url_safety"`
 }
 
 func ReadConfig(path string) (*Config, error) {
@@ -36,7 +55,7 @@ func ReadConfig(path string) (*Config, error) {
 		return nil, errors.New(fmt.Sprintf("Read file %s error: %s\n", path, err))
 	}
 
-	config := Config{"", 0, map[string]string{}}
+	config := Config{Paths: map[string]LinkTarget{}}
 	err = json.Unmarshal(rawConfig, &config)
 	if err != nil {
 		return nil, errors.New(fmt.Sprintf("Parse json %s error: %s\n", string(rawConfig), err))
@@ -60,6 +79,10 @@ func EnsureNotFoundMessageValid(config *Config) {
 func EnsureConfigValid(config *Config) {
 	EnsurePortValid(config)
 	EnsureNotFoundMessageValid(config)
+	EnsureStorageValid(config)
+	EnsureAnalyticsValid(config)
+	EnsureDomainsValid(config)
+	EnsureSafetyValid(config)
 }
 
 func GetConfigPath() string {
@@ -68,60 +91,173 @@ func GetConfigPath() string {
 	return *configPathPtr
 }
 
-func RedirectHandler(url string) http.HandlerFunc {
+func RedirectHandler(url string, code int) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		http.Redirect(w, r, url, http.StatusSeeOther)
+		http.Redirect(w, r, url, code)
 	}
 }
 
+// ProxyHttpHandler serves links of a single domain
 type ProxyHttpHandler struct {
-	notFoundMessage       string
-	handlers              map[string]http.HandlerFunc
-	availablePathsMessage string
+	host         string
+	notFoundPage *NotFoundPage
+	handlers     map[string]http.HandlerFunc
+	routes       RouteTable
+	storage      LinkStorage
+	clicks       *ClickRecorder
+	blocklist    *Blocklist
+	// targets rechecks stored links, safety config may have changed since they were created
+	targets TargetValidator
 }
 
 func (h ProxyHttpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if h.handlers[r.URL.Path] == nil {
+	if h.handlers[r.URL.Path] != nil {
+		h.handlers[r.URL.Path](w, r)
+		return
+	}
+
+	link, err := h.storage.Get(h.host, r.URL.Path)
+	if err != nil {
+		fmt.Printf("Get link %s%s error: %s\n", h.host, r.URL.Path, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if link != nil {
+		if err = h.targets.ValidateTargetURL(link.URL); err != nil {
+			fmt.Printf("Link %s%s is denied: %s\n", h.host, link.Path, err)
+			http.Error(w, "Link target is denied", http.StatusForbidden)
+			return
+		}
+		h.linkHandler(link.Path, link.Target())(w, r)
+		return
+	}
+
+	key, target := h.routes.Match(r.URL.Path)
+	if target == nil {
 		h.notFoundHandler(w, r)
 		return
 	}
 
-	h.handlers[r.URL.Path](w, r)
+	h.linkHandler(key, *target)(w, r)
+}
+
+// linkHandler redirects to the target, path is the stored path or the key of config route,
+// so all paths of a prefix or pattern route share one click counter and click limit
+func (h ProxyHttpHandler) linkHandler(path string, target LinkTarget) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !target.IsEnabled() {
+			h.notFoundHandler(w, r)
+			return
+		}
+		if target.IsExpired(time.Now()) {
+			http.Error(w, "Link is expired", http.StatusGone)
+			return
+		}
+		redirectURL := target.RedirectURL(r)
+		if u, err := url.Parse(redirectURL); err != nil || h.blocklist.Blocks(u.Hostname()) {
+			BlockedLinkHandler(redirectURL)(w, r)
+			return
+		}
+		if target.MaxClicks > 0 {
+			_, err := h.storage.IncrementClicks(h.host, path, target.MaxClicks)
+			if err == ErrClickLimitReached {
+				http.Error(w, "Link has reached its click limit", http.StatusGone)
+				return
+			}
+			if err != nil {
+				fmt.Printf("Count click of %s%s error: %s\n", h.host, path, err)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		h.clicks.Record(r, h.host, path)
+		RedirectHandler(redirectURL, target.StatusCode())(w, r)
+	}
 }
 
 func (h ProxyHttpHandler) notFoundHandler(w http.ResponseWriter, r *http.Request) {
-	notFoundMessage := fmt.Sprintf(h.notFoundMessage, r.URL.Path)
-	_, err := fmt.Fprintf(w, "%s\n\n%s", notFoundMessage, h.availablePathsMessage)
+	h.notFoundPage.ServeHTTP(w, r)
+}
+
+func PrepareProxyHttpHandler(config Config, host string, storage LinkStorage, clicks *ClickRecorder, blocklist *Blocklist) (*ProxyHttpHandler, error) {
+	routes, err := NewRouteTable(config.DomainConfigs()[host].Paths)
 	if err != nil {
-		fmt.Printf("Write http response error: %s", err)
-		return
+		return nil, err
 	}
-}
 
-func buildAvailablePathsMessage(config Config) string {
-	message := "Available paths:\n"
-	for short, long := range config.Paths {
-		message = fmt.Sprintf("%s * %s -> %s\n", message, short, long)
+	notFoundPage, err := NewNotFoundPage(config, host)
+	if err != nil {
+		return nil, err
 	}
 
-	return message
+	hh := &ProxyHttpHandler{
+		host,
+		notFoundPage,
+		map[string]http.HandlerFunc{},
+		routes,
+		storage,
+		clicks,
+		blocklist,
+		NewTargetValidator(config),
+	}
+	for short, long := range routes.Exact {
+		hh.handlers[short] = hh.linkHandler(short, long)
+	}
+
+	return hh, nil
 }
 
-func PrepareHttpHandler(config Config) http.Handler {
-	hh := ProxyHttpHandler{
-		config.NotFoundMessage,
-		map[string]http.HandlerFunc{},
-		buildAvailablePathsMessage(config),
+func PrepareHttpHandler(config Config, storage LinkStorage, clicks *ClickRecorder) (http.Handler, error) {
+	var blocklist *Blocklist
+	if config.Safety.BlocklistPath != "" {
+		var err error
+		blocklist, err = LoadBlocklist(config.Safety.BlocklistPath)
+		if err != nil {
+			return nil, err
+		}
 	}
-	for short, long := range config.Paths {
-		hh.handlers[short] = RedirectHandler(long)
+
+	hh := HostHttpHandler{map[string]http.Handler{}}
+	api := LinkApiHttpHandler{storage, map[string]apiDomain{}, clicks.Sink(), NewTargetValidator(config)}
+	for host, domain := range config.DomainConfigs() {
+		proxy, err := PrepareProxyHttpHandler(config, host, storage, clicks, blocklist)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Domain %s: %s", domainName(host), err))
+		}
+
+		hh.domains[host] = proxy
+		api.domains[host] = apiDomain{staticPaths: proxy.routes.Exact, baseURL: domain.BaseURL}
 	}
-	return hh
+
+	mux := http.NewServeMux()
+	mux.Handle(ApiLinksPath, RequireAuth(config.Admin, api))
+	mux.Handle(ApiLinksPath+"/", RequireAuth(config.Admin, api))
+	if config.Admin.IsEnabled() {
+		mux.Handle(AdminPath, RequireAuth(config.Admin, AdminHttpHandler()))
+	}
+	mux.Handle("/", hh)
+	return mux, nil
 }
 
-func StartHttpServer(port int, hh http.Handler) error {
+// StartHttpServer serves until a stop signal, requests in flight are finished before it returns
+func StartHttpServer(port int, hh http.Handler, stop <-chan os.Signal) error {
+	server := &http.Server{Addr: fmt.Sprintf(":%d", port), Handler: hh}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ListenAndServe()
+	}()
+
 	fmt.Printf("Starting http server at http://localhost:%d ...\n", port)
-	return http.ListenAndServe(fmt.Sprintf(":%d", port), hh)
+	select {
+	case err := <-errs:
+		return err
+	case s := <-stop:
+		fmt.Printf("Stopping http server on %s ...\n", s)
+		ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+		defer cancel()
+		return server.Shutdown(ctx)
+	}
 }
 
 func main() {
@@ -134,10 +270,59 @@ func main() {
 	}
 
 	EnsureConfigValid(config)
+	err = ValidateConfig(*config)
+	if err != nil {
+		fmt.Printf("Invalid config: %s", err)
+		return
+	}
+
+	storage, err := OpenLinkStorage(config.Storage)
+	if err != nil {
+		fmt.Printf("Open link storage error: %s", err)
+		return
+	}
+	defer func() {
+		err := storage.Close()
+		if err != nil {
+			fmt.Printf("Close link storage error: %s", err)
+		}
+	}()
+
+	clickSink, err := OpenClickSink(config.Analytics)
+	if err != nil {
+		fmt.Printf("Open click sink error: %s", err)
+		return
+	}
+	clicks, err := NewClickRecorder(clickSink, config.Analytics.BufferSize, config.Analytics.VisitorSecret)
+	if err != nil {
+		fmt.Printf("Start click recorder error: %s", err)
+		_ = clickSink.Close()
+		return
+	}
+	defer func() {
+		err := clicks.Close()
+		if err != nil {
+			fmt.Printf("Close click sink error: %s", err)
+		}
+	}()
+
+	handler, err := PrepareHttpHandler(*config, storage, clicks)
+	if err != nil {
+		fmt.Printf("Prepare http handler error: %s", err)
+		return
+	}
+	hh := NewReloadableHttpHandler(handler)
 
-	hh := PrepareHttpHandler(*config)
+	reloader := NewConfigReloader(configPath, *config, storage, clicks, hh)
+	reloader.WatchConfig()
+	reloadSignals := make(chan os.Signal, 1)
+	signal.Notify(reloadSignals, syscall.SIGHUP)
+	reloader.HandleReloadSignals(reloadSignals)
 
-	err = StartHttpServer(config.Port, hh)
+	// buffered clicks and storage are flushed by deferred closes, so stop signals end main normally
+	stopSignals := make(chan os.Signal, 1)
+	signal.Notify(stopSignals, syscall.SIGINT, syscall.SIGTERM)
+	err = StartHttpServer(config.Port, hh, stopSignals)
 	if err != nil {
 		fmt.Printf("Start Http Server error: %s", err)
 		return
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// notFoundPathPlaceholder is replaced by the requested path, NotFoundMessage is never used as a format string
+const notFoundPathPlaceholder = "%s"
+
+const defaultNotFoundHtmlTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Not found</title>
+</head>
+<body>
+<h1>{{.Message}}</h1>
+{{- if .Paths}}
+<p>Available paths:</p>
+<ul>
+{{- range .Paths}}
+<li><a href="{{.Path}}">{{.Path}}</a> &rarr; {{.URL}}</li>
+{{- end}}
+</ul>
+{{- end}}
+</body>
+</html>
+`
+
+const notFoundTextTemplate = `{{.Message}}
+{{- if .Paths}}
+
+Available paths:
+{{range .Paths}} * {{.Path}} -> {{.URL}}
+{{end}}
+{{- end}}
+`
+
+type PathEntry struct {
+	Path string `json:"path"`
+	URL  string `json:"url"`
+}
+
+type notFoundData struct {
+	Path    string
+	Message string
+	Paths   []PathEntry
+}
+
+type notFoundResponse struct {
+	Error string      `json:"error"`
+	Path  string      `json:"path"`
+	Paths []PathEntry `json:"paths,omitempty"`
+}
+
+// NotFoundPage renders 404 responses as html, json or plain text depending on Accept header,
+// only config paths are listed, links created through the API aren't public
+type NotFoundPage struct {
+	message     string
+	hidePaths   bool
+	staticPaths map[string]LinkTarget
+	html        *htmltemplate.Template
+	text        *texttemplate.Template
+}
+
+// NewNotFoundPage reads html template from the file, built-in template is used when file is empty
+func NewNotFoundPage(config Config, host string) (*NotFoundPage, error) {
+	htmlTemplate := defaultNotFoundHtmlTemplate
+	if config.NotFoundTemplate != "" {
+		content, err := ioutil.ReadFile(config.NotFoundTemplate)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Read file %s error: %s", config.NotFoundTemplate, err))
+		}
+		htmlTemplate = string(content)
+	}
+
+	html, err := htmltemplate.New("not_found").Parse(htmlTemplate)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Parse not found template error: %s", err))
+	}
+
+	domain := config.DomainConfigs()[host]
+	return &NotFoundPage{
+		message:     domain.NotFoundMessage,
+		hidePaths:   config.HidePaths,
+		staticPaths: domain.Paths,
+		html:        html,
+		text:        texttemplate.Must(texttemplate.New("not_found").Parse(notFoundTextTemplate)),
+	}, nil
+}
+
+func (p *NotFoundPage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	data := notFoundData{
+		Path:    r.URL.Path,
+		Message: strings.Replace(p.message, notFoundPathPlaceholder, r.URL.Path, 1),
+	}
+	if !p.hidePaths {
+		data.Paths = p.availablePaths()
+	}
+
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	var err error
+	switch negotiateNotFoundFormat(r.Header.Get("Accept")) {
+	case "application/json":
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusNotFound)
+		err = json.NewEncoder(w).Encode(notFoundResponse{Error: data.Message, Path: data.Path, Paths: data.Paths})
+	case "text/html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusNotFound)
+		err = p.html.Execute(w, data)
+	default:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusNotFound)
+		err = p.text.Execute(w, data)
+	}
+	if err != nil {
+		fmt.Printf("Write http response error: %s\n", err)
+	}
+}
+
+func (p *NotFoundPage) availablePaths() []PathEntry {
+	now := time.Now()
+	var paths []PathEntry
+	for short, long := range p.staticPaths {
+		if long.IsEnabled() && !long.IsExpired(now) {
+			paths = append(paths, PathEntry{Path: short, URL: long.URL})
+		}
+	}
+
+	sort.Slice(paths, func(i, j int) bool {
+		return paths[i].Path < paths[j].Path
+	})
+	return paths
+}
+
+// negotiateNotFoundFormat picks json or html when client prefers them, plain text stays the default
+func negotiateNotFoundFormat(accept string) string {
+	best, bestQuality := "text/plain", 0.0
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(fields[0]))
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				_, _ = fmt.Sscanf(param[2:], "%g", &quality)
+			}
+		}
+
+		switch mediaType {
+		case "application/json", "text/html", "text/plain":
+			if quality > bestQuality {
+				best, bestQuality = mediaType, quality
+			}
+		}
+	}
+
+	return best
+}
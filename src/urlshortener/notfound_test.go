@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNotFoundPage(t *testing.T) {
+	config := Config{
+		NotFoundMessage: DefaultNotFoundMessage,
+		Paths:           map[string]LinkTarget{"/z": {URL: "https://z.example"}, "/m": {URL: "https://m.example"}, "/a": {URL: "https://a.example"}},
+	}
+	page, err := NewNotFoundPage(config, DefaultDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]string{
+		"":                                  "text/plain",
+		"text/html,application/xhtml+xml":   "text/html",
+		"application/json":                  "application/json",
+		"text/html;q=0.5, application/json": "application/json",
+	}
+	for accept, contentType := range cases {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/<b>", nil)
+		r.Header.Set("Accept", accept)
+		page.ServeHTTP(w, r)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Status code is wrong for %q. Have: %d, want: %d", accept, w.Code, http.StatusNotFound)
+		}
+		if have := w.Header().Get("Content-Type"); !strings.HasPrefix(have, contentType) {
+			t.Errorf("Content type is wrong for %q. Have: %s, want: %s", accept, have, contentType)
+		}
+		body := w.Body.String()
+		if contentType == "text/html" && strings.Contains(body, "<b>") {
+			t.Errorf("Path is not escaped: %s", body)
+		}
+		if a, m, z := strings.Index(body, "/a"), strings.Index(body, "/m"), strings.Index(body, "/z"); !(a < m && m < z) {
+			t.Errorf("Paths are not sorted: %s", body)
+		}
+	}
+
+	config.HidePaths = true
+	page, _ = NewNotFoundPage(config, DefaultDomain)
+	w := httptest.NewRecorder()
+	page.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/x", nil))
+	if strings.Contains(w.Body.String(), "https://") {
+		t.Errorf("Paths are not hidden: %s", w.Body.String())
+	}
+}
+
+func TestNotFoundPageHidesStoredLinks(t *testing.T) {
+	config := Config{Paths: map[string]LinkTarget{"/static": {URL: "https://static.example"}}, Admin: AdminConfig{Token: testApiToken}}
+	hh := prepareApiHandler(t, config, NewMemoryClickSink(10))
+
+	w := apiRequest(hh, http.MethodPost, ApiLinksPath, `{"url": "https://private.example", "alias": "private"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Status code is wrong. Have: %d, want: %d", w.Code, http.StatusCreated)
+	}
+
+	w = httptest.NewRecorder()
+	hh.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	if body := w.Body.String(); !strings.Contains(body, "/static") || strings.Contains(body, "private") {
+		t.Errorf("Listed paths are wrong: %s", body)
+	}
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	ConfigPollInterval   = 2 * time.Second
+	configReloadDebounce = 200 * time.Millisecond
+)
+
+// ReloadableHttpHandler serves the last prepared handler, requests in flight finish with the old one
+type ReloadableHttpHandler struct {
+	handler atomic.Value
+}
+
+func NewReloadableHttpHandler(hh http.Handler) *ReloadableHttpHandler {
+	h := &ReloadableHttpHandler{}
+	h.handler.Store(hh)
+	return h
+}
+
+func (h *ReloadableHttpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.handler.Load().(http.Handler).ServeHTTP(w, r)
+}
+
+func (h *ReloadableHttpHandler) Swap(hh http.Handler) {
+	h.handler.Store(hh)
+}
+
+// ValidateConfig checks config after EnsureConfigValid, invalid config is never served
+func ValidateConfig(config Config) error {
+	err := validateDomains(config)
+	if err != nil {
+		return err
+	}
+	err = validateSafety(config)
+	if err != nil {
+		return err
+	}
+
+	targets := NewTargetValidator(config)
+	for host, domain := range config.DomainConfigs() {
+		err = validatePaths(domain.Paths, targets)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Domain %s: %s", domainName(host), err))
+		}
+	}
+
+	return nil
+}
+
+func validatePaths(paths map[string]LinkTarget, targets TargetValidator) error {
+	for short, long := range paths {
+		if !strings.HasPrefix(short, "/") {
+			return errors.New(fmt.Sprintf("Path %s must start with /", short))
+		}
+		if err := targets.ValidateTargetURL(long.URL); err != nil {
+			return errors.New(fmt.Sprintf("Path %s: %s", short, err))
+		}
+		if err := ValidateLinkOptions(long.LinkOptions); err != nil {
+			return errors.New(fmt.Sprintf("Path %s has invalid options: %s", short, err))
+		}
+	}
+
+	_, err := NewRouteTable(paths)
+	return err
+}
+
+// ConfigReloader re-reads config file and swaps handler, old config is kept when new one is invalid
+type ConfigReloader struct {
+	mutex   sync.Mutex
+	path    string
+	config  Config
+	storage LinkStorage
+	clicks  *ClickRecorder
+	handler *ReloadableHttpHandler
+}
+
+func NewConfigReloader(path string, config Config, storage LinkStorage, clicks *ClickRecorder, handler *ReloadableHttpHandler) *ConfigReloader {
+	return &ConfigReloader{path: path, config: config, storage: storage, clicks: clicks, handler: handler}
+}
+
+func (cr *ConfigReloader) Reload() error {
+	cr.mutex.Lock()
+	defer cr.mutex.Unlock()
+
+	config, err := ReadConfig(cr.path)
+	if err != nil {
+		return err
+	}
+
+	EnsureConfigValid(config)
+	err = ValidateConfig(*config)
+	if err != nil {
+		return err
+	}
+
+	if config.Port != cr.config.Port {
+		fmt.Printf("Config reload: server_port change to %d requires restart\n", config.Port)
+	}
+	if config.Storage != cr.config.Storage {
+		fmt.Printf("Config reload: storage change requires restart\n")
+	}
+	if config.Analytics != cr.config.Analytics {
+		fmt.Printf("Config reload: analytics change requires restart\n")
+	}
+	// port, storage and analytics are bound at start, keep them to report the change on every reload
+	config.Port = cr.config.Port
+	config.Storage = cr.config.Storage
+	config.Analytics = cr.config.Analytics
+
+	handler, err := PrepareHttpHandler(*config, cr.storage, cr.clicks)
+	if err != nil {
+		return err
+	}
+
+	cr.handler.Swap(handler)
+	logPathsDiff(domainPaths(cr.config), domainPaths(*config))
+	cr.config = *config
+
+	return nil
+}
+
+func (cr *ConfigReloader) reloadAndLog(reason string) {
+	err := cr.Reload()
+	if err != nil {
+		fmt.Printf("Config reload on %s failed, keep previous config: %s\n", reason, err)
+		return
+	}
+	fmt.Printf("Config reloaded on %s\n", reason)
+}
+
+// domainPaths returns paths of all domains prefixed by their host
+func domainPaths(config Config) map[string]LinkTarget {
+	paths := map[string]LinkTarget{}
+	for host, domain := range config.DomainConfigs() {
+		for short, long := range domain.Paths {
+			paths[host+short] = long
+		}
+	}
+
+	return paths
+}
+
+func logPathsDiff(before, after map[string]LinkTarget) {
+	lines := pathsDiff(before, after)
+	if len(lines) == 0 {
+		fmt.Printf("Config reload: paths are not changed\n")
+		return
+	}
+
+	fmt.Printf("Config reload: paths changed:\n%s\n", strings.Join(lines, "\n"))
+}
+
+// pathsDiff describes added, changed and removed paths sorted by path
+func pathsDiff(before, after map[string]LinkTarget) []string {
+	var lines []string
+	for short, long := range after {
+		old, found := before[short]
+		if !found {
+			lines = append(lines, fmt.Sprintf(" + %s -> %s", short, long))
+		} else if !reflect.DeepEqual(old, long) {
+			lines = append(lines, fmt.Sprintf(" ~ %s -> %s (was %s)", short, long, old))
+		}
+	}
+	for short, long := range before {
+		if _, found := after[short]; !found {
+			lines = append(lines, fmt.Sprintf(" - %s -> %s", short, long))
+		}
+	}
+
+	sort.Slice(lines, func(i, j int) bool {
+		return lines[i][3:] < lines[j][3:]
+	})
+	return lines
+}
+
+// watchedPaths are the config file and the blocklist file of the current config
+func (cr *ConfigReloader) watchedPaths() []string {
+	cr.mutex.Lock()
+	defer cr.mutex.Unlock()
+
+	paths := []string{filepath.Clean(cr.path)}
+	if cr.config.Safety.BlocklistPath != "" {
+		paths = append(paths, filepath.Clean(cr.config.Safety.BlocklistPath))
+	}
+
+	return paths
+}
+
+func (cr *ConfigReloader) isWatched(path string) bool {
+	path = filepath.Clean(path)
+	for _, watched := range cr.watchedPaths() {
+		if path == watched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// watchDirs adds directories of watched files, editors replace files by rename,
+// so directories are watched and events are filtered by name
+func (cr *ConfigReloader) watchDirs(watcher *fsnotify.Watcher) error {
+	for _, path := range cr.watchedPaths() {
+		err := watcher.Add(filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WatchConfig reloads config on changes of config and blocklist files,
+// polling is used when file notifications are unavailable
+func (cr *ConfigReloader) WatchConfig() {
+	watcher, err := fsnotify.NewWatcher()
+	if err == nil {
+		err = cr.watchDirs(watcher)
+		if err != nil {
+			_ = watcher.Close()
+		}
+	}
+	if err != nil {
+		fmt.Printf("Watch config %s error, fall back to polling: %s\n", cr.path, err)
+		go cr.pollConfig(ConfigPollInterval)
+		return
+	}
+
+	go cr.watchConfigEvents(watcher)
+}
+
+func (cr *ConfigReloader) watchConfigEvents(watcher *fsnotify.Watcher) {
+	defer watcher.Close()
+
+	var debounce <-chan time.Time
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create) == 0 || !cr.isWatched(event.Name) {
+				continue
+			}
+			// editors write files in several steps, reload once they are done
+			debounce = time.After(configReloadDebounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			fmt.Printf("Watch config %s error: %s\n", cr.path, err)
+		case <-debounce:
+			debounce = nil
+			cr.reloadAndLog("file change")
+			// blocklist path may be changed by the reload
+			if err := cr.watchDirs(watcher); err != nil {
+				fmt.Printf("Watch config %s error: %s\n", cr.path, err)
+			}
+		}
+	}
+}
+
+func (cr *ConfigReloader) pollConfig(interval time.Duration) {
+	modTimes := map[string]time.Time{}
+	for _, path := range cr.watchedPaths() {
+		modTimes[path] = fileModTime(path)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		changed := false
+		for _, path := range cr.watchedPaths() {
+			current := fileModTime(path)
+			previous, known := modTimes[path]
+			if !known {
+				// blocklist added by the last reload is already read
+				modTimes[path] = current
+				continue
+			}
+			if current.IsZero() || current.Equal(previous) {
+				continue
+			}
+
+			modTimes[path] = current
+			changed = true
+		}
+		if changed {
+			cr.reloadAndLog("file change")
+		}
+	}
+}
+
+func fileModTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return info.ModTime()
+}
+
+// HandleReloadSignals reloads config on every signal from the channel, used for SIGHUP
+func (cr *ConfigReloader) HandleReloadSignals(signals <-chan os.Signal) {
+	go func() {
+		for s := range signals {
+			cr.reloadAndLog(s.String())
+		}
+	}()
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeConfig(t *testing.T, path, content string) {
+	err := ioutil.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertLocation(t *testing.T, hh http.Handler, path, location string) {
+	w := httptest.NewRecorder()
+	hh.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	if have := w.Header().Get("Location"); have != location {
+		t.Errorf("Location of %s is wrong. Have: %q, want: %q", path, have, location)
+	}
+}
+
+func TestConfigReload(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+	writeConfig(t, configPath, `{"paths": {"/a": "https://a.example"}}`)
+
+	config, err := ReadConfig(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	EnsureConfigValid(config)
+
+	storage, err := OpenFileLinkStorage(filepath.Join(dir, "links.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+
+	clicks, err := NewClickRecorder(NewMemoryClickSink(10), 10, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clicks.Close()
+	handler, err := PrepareHttpHandler(*config, storage, clicks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hh := NewReloadableHttpHandler(handler)
+	reloader := NewConfigReloader(configPath, *config, storage, clicks, hh)
+	assertLocation(t, hh, "/a", "https://a.example")
+
+	writeConfig(t, configPath, `{"paths": {"/b": "https://b.example"}}`)
+	if err = reloader.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	assertLocation(t, hh, "/a", "")
+	assertLocation(t, hh, "/b", "https://b.example")
+
+	invalidConfigs := map[string]string{
+		"broken json":       `{"paths": `,
+		"path without root": `{"paths": {"c": "https://c.example"}}`,
+		"not http target":   `{"paths": {"/c": "javascript:alert(1)"}}`,
+	}
+	for name, content := range invalidConfigs {
+		writeConfig(t, configPath, content)
+		if err = reloader.Reload(); err == nil {
+			t.Errorf("Config with %s is accepted", name)
+		}
+		assertLocation(t, hh, "/b", "https://b.example")
+	}
+}
+
+func TestReloadBlocklist(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+	blocklistPath := filepath.Join(dir, "blocklist.txt")
+	writeConfig(t, configPath, `{"paths": {"/a": "https://a.example"}, "url_safety": {"blocklist_path": "`+blocklistPath+`"}}`)
+	writeConfig(t, blocklistPath, "")
+
+	config, err := ReadConfig(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	EnsureConfigValid(config)
+
+	storage, err := OpenFileLinkStorage(filepath.Join(dir, "links.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+
+	clicks, err := NewClickRecorder(NewMemoryClickSink(10), 10, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clicks.Close()
+	handler, err := PrepareHttpHandler(*config, storage, clicks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hh := NewReloadableHttpHandler(handler)
+	reloader := NewConfigReloader(configPath, *config, storage, clicks, hh)
+	if !reloader.isWatched(blocklistPath) || reloader.isWatched(filepath.Join(dir, "links.log")) {
+		t.Errorf("Watched files are wrong: %v", reloader.watchedPaths())
+	}
+	assertLocation(t, hh, "/a", "https://a.example")
+
+	writeConfig(t, blocklistPath, "a.example\n")
+	if err = reloader.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	assertLocation(t, hh, "/a", "")
+
+	if err = os.Remove(blocklistPath); err != nil {
+		t.Fatal(err)
+	}
+	if err = reloader.Reload(); err == nil {
+		t.Errorf("Config with missing blocklist is accepted")
+	}
+	assertLocation(t, hh, "/a", "")
+}
+
+func TestPathsDiff(t *testing.T) {
+	before := map[string]LinkTarget{
+		"/same":    {URL: "https://same.example"},
+		"/changed": {URL: "https://old.example"},
+		"/removed": {URL: "https://removed.example"},
+	}
+	after := map[string]LinkTarget{
+		"/same":    {URL: "https://same.example"},
+		"/changed": {URL: "https://new.example"},
+		"/added":   {URL: "https://added.example"},
+	}
+
+	want := []string{
+		" + /added -> https://added.example",
+		" ~ /changed -> https://new.example (was https://old.example)",
+		" - /removed -> https://removed.example",
+	}
+	if have := pathsDiff(before, after); !reflect.DeepEqual(have, want) {
+		t.Errorf("Diff is wrong. Have: %q, want: %q", have, want)
+	}
+	if have := pathsDiff(after, after); len(have) != 0 {
+		t.Errorf("Diff of same paths is not empty: %q", have)
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	prefixRouteSuffix = "/*"
+	// RestParam is the part of the path after prefix of a prefix route
+	RestParam = "rest"
+)
+
+var paramPattern = regexp.MustCompile(`^\{([A-Za-z_][A-Za-z0-9_]*)\}$`)
+var placeholderPattern = regexp.MustCompile(`\{([^{}]*)\}`)
+
+type prefixRoute struct {
+	key    string
+	prefix string
+	target LinkTarget
+}
+
+type patternRoute struct {
+	key      string
+	segments []string
+	literals int
+	target   LinkTarget
+}
+
+// RouteTable resolves Config.Paths, exact paths win over the longest prefix,
+// prefixes win over patterns, patterns with more literal segments win
+type RouteTable struct {
+	Exact    map[string]LinkTarget
+	prefixes []prefixRoute
+	patterns []patternRoute
+}
+
+func NewRouteTable(paths map[string]LinkTarget) (RouteTable, error) {
+	table := RouteTable{Exact: map[string]LinkTarget{}}
+	for key, target := range paths {
+		switch {
+		case strings.HasSuffix(key, prefixRouteSuffix):
+			route, err := newPrefixRoute(key, target)
+			if err != nil {
+				return RouteTable{}, err
+			}
+			table.prefixes = append(table.prefixes, route)
+		case strings.ContainsAny(key, "{*"):
+			route, err := newPatternRoute(key, target)
+			if err != nil {
+				return RouteTable{}, err
+			}
+			table.patterns = append(table.patterns, route)
+		default:
+			table.Exact[key] = target
+		}
+	}
+
+	sort.Slice(table.prefixes, func(i, j int) bool {
+		a, b := table.prefixes[i], table.prefixes[j]
+		if len(a.prefix) != len(b.prefix) {
+			return len(a.prefix) > len(b.prefix)
+		}
+		return a.key < b.key
+	})
+	sort.Slice(table.patterns, func(i, j int) bool {
+		a, b := table.patterns[i], table.patterns[j]
+		if a.literals != b.literals {
+			return a.literals > b.literals
+		}
+		return a.key < b.key
+	})
+
+	return table, nil
+}
+
+func newPrefixRoute(key string, target LinkTarget) (prefixRoute, error) {
+	prefix := strings.TrimSuffix(key, "*")
+	if strings.ContainsAny(prefix, "*{}") {
+		return prefixRoute{}, errors.New(fmt.Sprintf("Prefix route %s may have only trailing /*", key))
+	}
+
+	err := validateTemplate(key, target.URL, map[string]bool{RestParam: true})
+	if err != nil {
+		return prefixRoute{}, err
+	}
+
+	return prefixRoute{key: key, prefix: prefix, target: target}, nil
+}
+
+func newPatternRoute(key string, target LinkTarget) (patternRoute, error) {
+	route := patternRoute{key: key, segments: strings.Split(key, "/"), target: target}
+	params := map[string]bool{}
+	for _, segment := range route.segments {
+		if !strings.ContainsAny(segment, "{}*") {
+			route.literals++
+			continue
+		}
+
+		match := paramPattern.FindStringSubmatch(segment)
+		if match == nil {
+			return patternRoute{}, errors.New(fmt.Sprintf("Pattern route %s has invalid segment %s, use {name}", key, segment))
+		}
+		if params[match[1]] {
+			return patternRoute{}, errors.New(fmt.Sprintf("Pattern route %s has duplicate parameter %s", key, match[1]))
+		}
+		params[match[1]] = true
+	}
+
+	err := validateTemplate(key, target.URL, params)
+	if err != nil {
+		return patternRoute{}, err
+	}
+
+	return route, nil
+}
+
+func validateTemplate(key, template string, params map[string]bool) error {
+	for _, match := range placeholderPattern.FindAllStringSubmatch(template, -1) {
+		if !params[match[1]] {
+			return errors.New(fmt.Sprintf("Route %s target %s uses unknown parameter {%s}", key, template, match[1]))
+		}
+	}
+
+	u, err := url.Parse(placeholderPattern.ReplaceAllString(template, "x"))
+	if err != nil || u.Host == "" {
+		return errors.New(fmt.Sprintf("Route %s target %s must be an absolute url", key, template))
+	}
+	if strings.Contains(templateHost(template), "{") {
+		return errors.New(fmt.Sprintf("Route %s target %s must not have parameters in host", key, template))
+	}
+
+	return nil
+}
+
+func templateHost(template string) string {
+	host := template
+	if i := strings.Index(host, "://"); i >= 0 {
+		host = host[i+len("://"):]
+	}
+	if i := strings.IndexAny(host, "/?#"); i >= 0 {
+		host = host[:i]
+	}
+
+	return host
+}
+
+// Match returns route key and target with expanded url, exact paths are not matched
+func (t RouteTable) Match(path string) (string, *LinkTarget) {
+	for _, route := range t.prefixes {
+		if !strings.HasPrefix(path, route.prefix) {
+			continue
+		}
+
+		rest := strings.TrimPrefix(path, route.prefix)
+		target := route.target
+		target.URL = expandTemplate(target.URL, map[string]string{RestParam: escapePath(rest)})
+		return route.key, &target
+	}
+
+	segments := strings.Split(path, "/")
+	for _, route := range t.patterns {
+		params, ok := route.match(segments)
+		if !ok {
+			continue
+		}
+
+		target := route.target
+		target.URL = expandTemplate(target.URL, params)
+		return route.key, &target
+	}
+
+	return "", nil
+}
+
+func (r patternRoute) match(segments []string) (map[string]string, bool) {
+	if len(segments) != len(r.segments) {
+		return nil, false
+	}
+
+	params := map[string]string{}
+	for i, segment := range r.segments {
+		match := paramPattern.FindStringSubmatch(segment)
+		if match == nil {
+			if segment != segments[i] {
+				return nil, false
+			}
+			continue
+		}
+		if segments[i] == "" {
+			return nil, false
+		}
+		params[match[1]] = url.PathEscape(segments[i])
+	}
+
+	return params, true
+}
+
+func expandTemplate(template string, params map[string]string) string {
+	return placeholderPattern.ReplaceAllStringFunc(template, func(placeholder string) string {
+		return params[placeholder[1:len(placeholder)-1]]
+	})
+}
+
+// escapePath escapes segments of the path keeping slashes between them
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestRouteTablePrecedence(t *testing.T) {
+	routes, err := NewRouteTable(map[string]LinkTarget{
+		"/gh/*":                {URL: "https://github.com/{rest}"},
+		"/gh/go-labs/*":        {URL: "https://github.com/ivan-uskov/go-labs/{rest}"},
+		"/jira/{id}":           {URL: "https://jira.example/browse/{id}"},
+		"/jira/{project}/{id}": {URL: "https://jira.example/{project}/{id}"},
+		"/jira/new/{id}":       {URL: "https://jira.example/new/{id}"},
+		"/jira":                {URL: "https://jira.example"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]struct {
+		key string
+		url string
+	}{
+		"/gh/golang/go":        {"/gh/*", "https://github.com/golang/go"},
+		"/gh/go-labs/issues/5": {"/gh/go-labs/*", "https://github.com/ivan-uskov/go-labs/issues/5"},
+		"/jira/ABC-1":          {"/jira/{id}", "https://jira.example/browse/ABC-1"},
+		"/jira/new/7":          {"/jira/new/{id}", "https://jira.example/new/7"},
+		"/jira/ABC/7":          {"/jira/{project}/{id}", "https://jira.example/ABC/7"},
+		"/jira/a b":            {"/jira/{id}", "https://jira.example/browse/a%20b"},
+		"/jira/":               {"", ""},
+		"/unknown":             {"", ""},
+	}
+
+	for path, want := range cases {
+		key, target := routes.Match(path)
+		url := ""
+		if target != nil {
+			url = target.URL
+		}
+		if key != want.key || url != want.url {
+			t.Errorf("Route of %s is wrong. Have: %s -> %s, want: %s -> %s", path, key, url, want.key, want.url)
+		}
+	}
+
+	if _, found := routes.Exact["/jira"]; !found {
+		t.Errorf("Exact path is not in exact routes")
+	}
+}
+
+func TestRouteTableValidation(t *testing.T) {
+	invalid := map[string]string{
+		"/gh/*":        "https://github.com/{id}",
+		"/a/*/b":       "https://a.example",
+		"/x/{id}":      "https://{id}.example",
+		"/y/{id}/{id}": "https://y.example/{id}",
+		"/z/{id}x":     "https://z.example/{id}",
+		"/r/{id}":      "/relative/{id}",
+	}
+
+	for key, url := range invalid {
+		_, err := NewRouteTable(map[string]LinkTarget{key: {URL: url}})
+		if err == nil {
+			t.Errorf("Route %s -> %s is accepted", key, url)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var defaultAllowedSchemes = []string{"http", "https"}
+
+var loopbackHosts = []string{"localhost", "127.0.0.1", "::1"}
+
+const blockedLinkHtmlTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>Warning</title>
+</head>
+<body>
+<h1>This link may be unsafe</h1>
+<p>The link leads to {{.Host}}, which is on the list of blocked domains.</p>
+<p><a href="{{.URL}}" rel="noreferrer nofollow">Continue to {{.URL}}</a></p>
+</body>
+</html>
+`
+
+var blockedLinkTemplate = template.Must(template.New("blocked").Parse(blockedLinkHtmlTemplate))
+
+// SafetyConfig restricts redirect targets, hosts match themselves and their subdomains
+type SafetyConfig struct {
+	AllowedSchemes []string `json:"allowed_schemes"`
+	// AllowedHosts permits only listed hosts when not empty
+	AllowedHosts []string `json:"allowed_hosts"`
+	DeniedHosts  []string `json:"denied_hosts"`
+	// BlocklistPath is a file of domains, one per line, redirects to them show a warning page
+	BlocklistPath string `json:"blocklist_path"`
+}
+
+func EnsureSafetyValid(config *Config) {
+	if len(config.Safety.AllowedSchemes) == 0 {
+		config.Safety.AllowedSchemes = defaultAllowedSchemes
+	}
+}
+
+// TargetValidator checks redirect targets against safety config and hosts of the shortener itself
+type TargetValidator struct {
+	schemes      []string
+	allowedHosts []string
+	deniedHosts  []string
+	selfHosts    []string
+	port         int
+}
+
+func NewTargetValidator(config Config) TargetValidator {
+	v := TargetValidator{
+		allowedHosts: normalizeHosts(config.Safety.AllowedHosts),
+		deniedHosts:  normalizeHosts(config.Safety.DeniedHosts),
+		port:         config.Port,
+	}
+	for _, scheme := range config.Safety.AllowedSchemes {
+		v.schemes = append(v.schemes, strings.ToLower(scheme))
+	}
+	for host, domain := range config.DomainConfigs() {
+		if host != DefaultDomain {
+			v.selfHosts = append(v.selfHosts, host)
+		}
+		if u, err := url.Parse(domain.BaseURL); err == nil && u.Hostname() != "" {
+			v.selfHosts = append(v.selfHosts, NormalizeHost(u.Hostname()))
+		}
+	}
+
+	return v
+}
+
+// ValidateTargetURL checks target url, selfHosts are additional hosts the shortener is reached by
+func (v TargetValidator) ValidateTargetURL(target string, selfHosts ...string) error {
+	u, err := url.Parse(target)
+	if err != nil {
+		return validationError(fmt.Sprintf("Invalid url %s: %s", target, err))
+	}
+	if !v.allowsScheme(u.Scheme) {
+		return validationError(fmt.Sprintf("Url %s must use one of schemes %s", target, strings.Join(v.schemes, ", ")))
+	}
+	host := NormalizeHost(u.Hostname())
+	if host == "" {
+		return validationError(fmt.Sprintf("Url %s has no host", target))
+	}
+
+	if v.isSelf(u, selfHosts) {
+		return validationError(fmt.Sprintf("Url %s points to the shortener itself", target))
+	}
+	if matchHost(host, v.deniedHosts) {
+		return validationError(fmt.Sprintf("Host %s of url %s is denied", host, target))
+	}
+	if len(v.allowedHosts) > 0 && !matchHost(host, v.allowedHosts) {
+		return validationError(fmt.Sprintf("Host %s of url %s is not allowed", host, target))
+	}
+
+	return nil
+}
+
+func (v TargetValidator) isSelf(u *url.URL, selfHosts []string) bool {
+	host := NormalizeHost(u.Hostname())
+	for _, self := range selfHosts {
+		selfHost, selfPort, err := net.SplitHostPort(self)
+		if err != nil {
+			selfHost, selfPort = self, ""
+		}
+		if host == NormalizeHost(selfHost) && (selfPort == "" || selfPort == strconv.Itoa(targetPort(u))) {
+			return true
+		}
+	}
+	for _, self := range v.selfHosts {
+		if host == self {
+			return true
+		}
+	}
+
+	// loopback addresses point to us only on our port
+	for _, loopback := range loopbackHosts {
+		if host == loopback {
+			return targetPort(u) == v.port
+		}
+	}
+
+	return false
+}
+
+func (v TargetValidator) allowsScheme(scheme string) bool {
+	for _, allowed := range v.schemes {
+		if strings.EqualFold(scheme, allowed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func targetPort(u *url.URL) int {
+	if port, err := strconv.Atoi(u.Port()); err == nil {
+		return port
+	}
+	if strings.ToLower(u.Scheme) == "https" {
+		return 443
+	}
+
+	return 80
+}
+
+func validateSafety(config Config) error {
+	for _, scheme := range config.Safety.AllowedSchemes {
+		if scheme == "" || strings.ContainsAny(scheme, ":/") {
+			return errors.New(fmt.Sprintf("Allowed scheme %q must be a scheme name without ://", scheme))
+		}
+		if strings.EqualFold(scheme, "javascript") || strings.EqualFold(scheme, "data") {
+			return errors.New(fmt.Sprintf("Scheme %s is never allowed", scheme))
+		}
+	}
+	for _, hosts := range [][]string{config.Safety.AllowedHosts, config.Safety.DeniedHosts} {
+		for _, host := range hosts {
+			if host == "" || strings.ContainsAny(host, "/?#@:") {
+				return errors.New(fmt.Sprintf("Host %q must be a host name without scheme and port", host))
+			}
+		}
+	}
+
+	// blocklist file is read by PrepareHttpHandler, a broken file fails it the same way
+	return nil
+}
+
+func normalizeHosts(hosts []string) []string {
+	var normalized []string
+	for _, host := range hosts {
+		normalized = append(normalized, NormalizeHost(host))
+	}
+
+	return normalized
+}
+
+// matchHost reports whether host is one of hosts or their subdomain
+func matchHost(host string, hosts []string) bool {
+	for _, h := range hosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Blocklist is a set of domains read from a local file, nil Blocklist blocks nothing
+type Blocklist struct {
+	hosts map[string]bool
+}
+
+// LoadBlocklist reads one domain per line, empty lines and lines starting with # are skipped
+func LoadBlocklist(path string) (*Blocklist, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Open blocklist %s error: %s", path, err))
+	}
+	defer file.Close()
+
+	blocklist := &Blocklist{hosts: map[string]bool{}}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		blocklist.hosts[NormalizeHost(strings.TrimPrefix(line, "*."))] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.New(fmt.Sprintf("Read blocklist %s error: %s", path, err))
+	}
+
+	return blocklist, nil
+}
+
+// Blocks reports whether host or one of its parent domains is in the blocklist
+func (b *Blocklist) Blocks(host string) bool {
+	if b == nil {
+		return false
+	}
+
+	host = NormalizeHost(host)
+	if net.ParseIP(host) != nil {
+		return b.hosts[host]
+	}
+	for host != "" {
+		if b.hosts[host] {
+			return true
+		}
+		dot := strings.Index(host, ".")
+		if dot < 0 {
+			break
+		}
+		host = host[dot+1:]
+	}
+
+	return false
+}
+
+// BlockedLinkHandler shows a warning page instead of redirect, the user may still follow the link
+func BlockedLinkHandler(target string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		host := target
+		if u, err := url.Parse(target); err == nil {
+			host = u.Hostname()
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("X-Robots-Tag", "noindex")
+		w.Header().Set("Referrer-Policy", "no-referrer")
+		w.WriteHeader(http.StatusOK)
+		err := blockedLinkTemplate.Execute(w, struct {
+			Host string
+			URL  string
+		}{host, target})
+		if err != nil {
+			fmt.Printf("Render warning page of %s error: %s\n", target, err)
+		}
+	}
+}
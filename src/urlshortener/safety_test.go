@@ -0,0 +1,141 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateTargetURL(t *testing.T) {
+	config := Config{
+		Port:    8080,
+		BaseURL: "https://s.example",
+		Domains: map[string]DomainConfig{"b.link": {}},
+		Safety:  SafetyConfig{DeniedHosts: []string{"evil.example"}},
+	}
+	EnsureConfigValid(&config)
+	targets := NewTargetValidator(config)
+
+	cases := map[string]bool{
+		"https://example.com/a":       true,
+		"javascript:alert(1)":         false,
+		"ftp://example.com/a":         false,
+		"https:///a":                  false,
+		"https://s.example/x":         false,
+		"https://B.link/x":            false,
+		"http://localhost:8080/x":     false,
+		"http://localhost:3000/x":     true,
+		"http://api.example:9000/x":   false,
+		"https://evil.example/x":      false,
+		"https://www.evil.example/x":  false,
+		"https://notevil.example/x":   true,
+		"https://example.com/{rest}":  true,
+		"https://127.0.0.1:8080/x?go": false,
+	}
+	for target, valid := range cases {
+		err := targets.ValidateTargetURL(target, "api.example:9000")
+		if (err == nil) != valid {
+			t.Errorf("Validation of %s is wrong. Have: %v, want valid: %v", target, err, valid)
+		}
+	}
+
+	config.Safety.AllowedHosts = []string{"example.com"}
+	targets = NewTargetValidator(config)
+	if err := targets.ValidateTargetURL("https://other.example/a"); err == nil {
+		t.Errorf("Host out of allowed hosts is accepted")
+	}
+	if err := targets.ValidateTargetURL("https://docs.example.com/a"); err != nil {
+		t.Errorf("Subdomain of allowed host is rejected: %s", err)
+	}
+}
+
+func TestBlocklistWarningPage(t *testing.T) {
+	dir := t.TempDir()
+	blocklistPath := filepath.Join(dir, "blocklist.txt")
+	err := ioutil.WriteFile(blocklistPath, []byte("# phishing\nbad.example\n\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storage, err := OpenFileLinkStorage(filepath.Join(dir, "links.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+
+	config := Config{
+		Paths: map[string]LinkTarget{
+			"/bad":  {URL: "https://www.bad.example/<x>"},
+			"/good": {URL: "https://good.example"},
+		},
+		Safety: SafetyConfig{BlocklistPath: blocklistPath},
+	}
+	EnsureConfigValid(&config)
+	if err = ValidateConfig(config); err != nil {
+		t.Fatal(err)
+	}
+
+	clicks, err := NewClickRecorder(NewMemoryClickSink(10), 10, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clicks.Close()
+	hh, err := PrepareHttpHandler(config, storage, clicks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	hh.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/bad", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Location") != "" {
+		t.Errorf("Blocked link is redirected. Have: %d %s", w.Code, w.Header().Get("Location"))
+	}
+	if body := w.Body.String(); !strings.Contains(body, "www.bad.example") || strings.Contains(body, "<x>") {
+		t.Errorf("Warning page is wrong: %s", body)
+	}
+
+	w = httptest.NewRecorder()
+	hh.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/good", nil))
+	if w.Code != http.StatusSeeOther {
+		t.Errorf("Status code is wrong. Have: %d, want: %d", w.Code, http.StatusSeeOther)
+	}
+}
+
+func TestStoredLinkIsRecheckedOnRedirect(t *testing.T) {
+	dir := t.TempDir()
+	storage, err := OpenFileLinkStorage(filepath.Join(dir, "links.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+	for _, link := range []Link{{Path: "/evil", URL: "https://www.evil.example"}, {Path: "/good", URL: "https://good.example"}} {
+		if err = storage.Add(link); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// links were stored before the host was denied
+	config := Config{Safety: SafetyConfig{DeniedHosts: []string{"evil.example"}}}
+	EnsureConfigValid(&config)
+	clicks, err := NewClickRecorder(NewMemoryClickSink(10), 10, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clicks.Close()
+	hh, err := PrepareHttpHandler(config, storage, clicks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]int{"/evil": http.StatusForbidden, "/good": http.StatusSeeOther}
+	for path, status := range cases {
+		w := httptest.NewRecorder()
+		hh.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != status {
+			t.Errorf("Status code of %s is wrong. Have: %d, want: %d", path, w.Code, status)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	StorageTypeFile = "file"
+	StorageTypeSQL  = "sql"
+)
+
+const DefaultStoragePath = "data/links.log"
+
+var ErrLinkExists = errors.New("link already exists")
+var ErrLinkNotFound = errors.New("link not found")
+var ErrClickLimitReached = errors.New("link has reached its click limit")
+
+type Link struct {
+	// Host is the domain of the link, DefaultDomain for links of top level config
+	Host string `json:"host,omitempty"`
+	Path string `json:"path"`
+	URL  string `json:"url"`
+	LinkOptions
+}
+
+func (l Link) Target() LinkTarget {
+	return LinkTarget{URL: l.URL, LinkOptions: l.LinkOptions}
+}
+
+func (l Link) key() linkKey {
+	return linkKey{l.Host, l.Path}
+}
+
+type linkKey struct {
+	host string
+	path string
+}
+
+func (k linkKey) link() Link {
+	return Link{Host: k.host, Path: k.path}
+}
+
+// LinkStorage keeps links created at runtime, links from config paths are never stored,
+// links are unique by host and path
+type LinkStorage interface {
+	// Get returns nil without error when there is no link with such path
+	Get(host, path string) (*Link, error)
+	// List returns links of all domains
+	List() ([]Link, error)
+	// Add returns ErrLinkExists when path is taken
+	Add(link Link) error
+	// Update replaces url and options, returns ErrLinkNotFound when there is no link with such path
+	Update(link Link) error
+	// Delete returns ErrLinkNotFound when there is no link with such path, click counter is reset
+	Delete(host, path string) error
+	// IncrementClicks counts redirects of links with click limit, links from config paths included,
+	// the counter stops at max and ErrClickLimitReached is returned then
+	IncrementClicks(host, path string, max int64) (int64, error)
+	Close() error
+}
+
+type StorageConfig struct {
+	Type string `json:"type"`
+	// Path is the log file of file storage
+	Path string `json:"path"`
+	// Driver and DSN are passed to sql.Open for sql storage
+	Driver string `json:"driver"`
+	DSN    string `json:"dsn"`
+}
+
+func EnsureStorageValid(config *Config) {
+	if config.Storage.Type == "" {
+		config.Storage.Type = StorageTypeFile
+	}
+	if config.Storage.Type == StorageTypeFile && config.Storage.Path == "" {
+		config.Storage.Path = DefaultStoragePath
+	}
+}
+
+func OpenLinkStorage(config StorageConfig) (LinkStorage, error) {
+	switch config.Type {
+	case StorageTypeFile:
+		return OpenFileLinkStorage(config.Path)
+	case StorageTypeSQL:
+		return OpenSQLLinkStorage(config.Driver, config.DSN)
+	default:
+		return nil, errors.New(fmt.Sprintf("Unknown storage type %s", config.Type))
+	}
+}
@@ -0,0 +1,425 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	fileOperationPut    = "put"
+	fileOperationDelete = "delete"
+	fileOperationClicks = "clicks"
+)
+
+const (
+	// clickSyncInterval batches syncs of click records, a crash of the machine may lose clicks of the last interval
+	clickSyncInterval = time.Second
+	// minCompactSize is the log size in bytes below which an open log isn't compacted
+	minCompactSize = 4 << 20
+)
+
+type fileLogRecord struct {
+	Operation string `json:"op"`
+	Link      Link   `json:"link"`
+	// Clicks is the counter value of clicks record
+	Clicks int64 `json:"clicks,omitempty"`
+}
+
+// FileLinkStorage keeps links in memory and appends every change to a log file,
+// the log is replayed and compacted on open and compacted again when it has doubled
+type FileLinkStorage struct {
+	mutex  sync.RWMutex
+	path   string
+	file   *os.File
+	links  map[linkKey]Link
+	clicks map[linkKey]int64
+
+	size          int64
+	compactedSize int64
+	compactSize   int64
+	unsynced      bool
+	syncTimer     *time.Timer
+	closed        bool
+}
+
+func OpenFileLinkStorage(path string) (*FileLinkStorage, error) {
+	links, clicks, err := replayLinkLog(path)
+	if err != nil {
+		return nil, err
+	}
+
+	err = compactLinkLog(path, links, clicks)
+	if err != nil {
+		return nil, err
+	}
+
+	file, size, err := openLinkLog(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileLinkStorage{
+		path:          path,
+		file:          file,
+		links:         links,
+		clicks:        clicks,
+		size:          size,
+		compactedSize: size,
+		compactSize:   minCompactSize,
+	}, nil
+}
+
+func openLinkLog(path string) (*os.File, int64, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, 0, errors.New(fmt.Sprintf("Open file %s error: %s", path, err))
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, 0, errors.New(fmt.Sprintf("Stat file %s error: %s", path, err))
+	}
+
+	return file, info.Size(), nil
+}
+
+func replayLinkLog(path string) (map[linkKey]Link, map[linkKey]int64, error) {
+	links := map[linkKey]Link{}
+	clicks := map[linkKey]int64{}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return links, clicks, nil
+	}
+	if err != nil {
+		return nil, nil, errors.New(fmt.Sprintf("Open file %s error: %s", path, err))
+	}
+	defer file.Close()
+
+	// bufio.Reader has no line length limit unlike bufio.Scanner, links may be long
+	reader := bufio.NewReader(file)
+	line := 0
+	var broken error
+	for {
+		data, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return nil, nil, errors.New(fmt.Sprintf("Read file %s error: %s", path, readErr))
+		}
+		if len(bytes.TrimSpace(data)) > 0 {
+			line++
+			if broken != nil {
+				// only the last record may be torn by a crash during append
+				return nil, nil, errors.New(fmt.Sprintf("Broken record at %s:%d: %s", path, line-1, broken))
+			}
+
+			var record fileLogRecord
+			if err = json.Unmarshal(data, &record); err != nil {
+				broken = err
+			} else {
+				applyLinkLogRecord(record, links, clicks)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+	}
+	if broken != nil {
+		fmt.Printf("Skip torn last record at %s:%d: %s\n", path, line, broken)
+	}
+
+	return links, clicks, nil
+}
+
+func applyLinkLogRecord(record fileLogRecord, links map[linkKey]Link, clicks map[linkKey]int64) {
+	switch record.Operation {
+	case fileOperationPut:
+		links[record.Link.key()] = record.Link
+	case fileOperationDelete:
+		delete(links, record.Link.key())
+		delete(clicks, record.Link.key())
+	case fileOperationClicks:
+		clicks[record.Link.key()] = record.Clicks
+	}
+}
+
+func compactLinkLog(path string, links map[linkKey]Link, clicks map[linkKey]int64) error {
+	if dir := filepath.Dir(path); dir != "" {
+		err := os.MkdirAll(dir, 0755)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Create directory %s error: %s", dir, err))
+		}
+	}
+
+	tmpPath := path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Create file %s error: %s", tmpPath, err))
+	}
+
+	w := bufio.NewWriter(file)
+	encoder := json.NewEncoder(w)
+	for _, link := range sortedLinks(links) {
+		err = encoder.Encode(fileLogRecord{Operation: fileOperationPut, Link: link})
+		if err != nil {
+			break
+		}
+	}
+	for key, count := range clicks {
+		if err != nil {
+			break
+		}
+		err = encoder.Encode(fileLogRecord{Operation: fileOperationClicks, Link: key.link(), Clicks: count})
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return errors.New(fmt.Sprintf("Write file %s error: %s", tmpPath, err))
+	}
+
+	err = os.Rename(tmpPath, path)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Rename file %s error: %s", tmpPath, err))
+	}
+
+	return nil
+}
+
+func sortedLinks(links map[linkKey]Link) []Link {
+	result := make([]Link, 0, len(links))
+	for _, link := range links {
+		result = append(result, link)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Host != result[j].Host {
+			return result[i].Host < result[j].Host
+		}
+		return result[i].Path < result[j].Path
+	})
+
+	return result
+}
+
+func (s *FileLinkStorage) Get(host, path string) (*Link, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	link, found := s.links[linkKey{host, path}]
+	if !found {
+		return nil, nil
+	}
+
+	return &link, nil
+}
+
+func (s *FileLinkStorage) List() ([]Link, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return sortedLinks(s.links), nil
+}
+
+func (s *FileLinkStorage) Add(link Link) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	defer s.compactIfGrown()
+
+	if _, found := s.links[link.key()]; found {
+		return ErrLinkExists
+	}
+
+	err := s.append(fileLogRecord{Operation: fileOperationPut, Link: link})
+	if err != nil {
+		return err
+	}
+
+	s.links[link.key()] = link
+	return nil
+}
+
+func (s *FileLinkStorage) Update(link Link) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	defer s.compactIfGrown()
+
+	if _, found := s.links[link.key()]; !found {
+		return ErrLinkNotFound
+	}
+
+	err := s.append(fileLogRecord{Operation: fileOperationPut, Link: link})
+	if err != nil {
+		return err
+	}
+
+	s.links[link.key()] = link
+	return nil
+}
+
+func (s *FileLinkStorage) Delete(host, path string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	defer s.compactIfGrown()
+
+	key := linkKey{host, path}
+	if _, found := s.links[key]; !found {
+		return ErrLinkNotFound
+	}
+
+	err := s.append(fileLogRecord{Operation: fileOperationDelete, Link: key.link()})
+	if err != nil {
+		return err
+	}
+
+	delete(s.links, key)
+	delete(s.clicks, key)
+	return nil
+}
+
+func (s *FileLinkStorage) IncrementClicks(host, path string, max int64) (int64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	defer s.compactIfGrown()
+
+	key := linkKey{host, path}
+	if s.clicks[key] >= max {
+		return s.clicks[key], ErrClickLimitReached
+	}
+
+	clicks := s.clicks[key] + 1
+	err := s.appendClicks(fileLogRecord{Operation: fileOperationClicks, Link: key.link(), Clicks: clicks})
+	if err != nil {
+		return 0, err
+	}
+
+	s.clicks[key] = clicks
+	return clicks, nil
+}
+
+func (s *FileLinkStorage) write(record fileLogRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	n, err := s.file.Write(append(line, '\n'))
+	s.size += int64(n)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Write file %s error: %s", s.path, err))
+	}
+
+	return nil
+}
+
+// append writes a link record and syncs it together with click records written before it
+func (s *FileLinkStorage) append(record fileLogRecord) error {
+	err := s.write(record)
+	if err != nil {
+		return err
+	}
+
+	return s.sync()
+}
+
+// appendClicks writes a click record, it is synced within clickSyncInterval
+func (s *FileLinkStorage) appendClicks(record fileLogRecord) error {
+	err := s.write(record)
+	if err != nil {
+		return err
+	}
+
+	s.unsynced = true
+	if s.syncTimer == nil {
+		s.syncTimer = time.AfterFunc(clickSyncInterval, s.syncClicks)
+	}
+
+	return nil
+}
+
+func (s *FileLinkStorage) sync() error {
+	err := s.file.Sync()
+	if err != nil {
+		return errors.New(fmt.Sprintf("Sync file %s error: %s", s.path, err))
+	}
+
+	s.unsynced = false
+	return nil
+}
+
+func (s *FileLinkStorage) syncClicks() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.syncTimer = nil
+	if s.closed || !s.unsynced {
+		return
+	}
+
+	if err := s.sync(); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// compactIfGrown rewrites the log from memory once it is twice as large as after the last compaction,
+// the change being made is already applied to memory when it runs
+func (s *FileLinkStorage) compactIfGrown() {
+	if s.closed || s.size < s.compactSize || s.size < 2*s.compactedSize {
+		return
+	}
+
+	err := compactLinkLog(s.path, s.links, s.clicks)
+	if err != nil {
+		// the old log is still complete, it is compacted on the next change
+		fmt.Printf("Compact file %s error: %s\n", s.path, err)
+		return
+	}
+
+	// the old file is replaced, appends to it would be lost
+	_ = s.file.Close()
+	file, size, err := openLinkLog(s.path)
+	if err != nil {
+		// changes fail on the closed file until restart, the compacted log holds everything before them
+		fmt.Println(err)
+		return
+	}
+
+	s.file = file
+	s.size = size
+	s.compactedSize = size
+	s.unsynced = false
+}
+
+func (s *FileLinkStorage) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.syncTimer != nil {
+		s.syncTimer.Stop()
+		s.syncTimer = nil
+	}
+	s.closed = true
+	if s.unsynced {
+		if err := s.sync(); err != nil {
+			_ = s.file.Close()
+			return err
+		}
+	}
+
+	return s.file.Close()
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func openTestFileStorage(t *testing.T, path string) *FileLinkStorage {
+	storage, err := OpenFileLinkStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return storage
+}
+
+func TestFileLinkStorageReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "links.log")
+	storage := openTestFileStorage(t, path)
+
+	longURL := "https://example.com/" + strings.Repeat("a", 100*1024)
+	for _, link := range []Link{{Path: "/a", URL: "https://a.example"}, {Path: "/b", URL: "https://b.example"}, {Path: "/long", URL: longURL}} {
+		if err := storage.Add(link); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := storage.Delete(DefaultDomain, "/b"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := storage.IncrementClicks(DefaultDomain, "/a", 4); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := storage.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	storage = openTestFileStorage(t, path)
+	defer storage.Close()
+
+	links, err := storage.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(links) != 2 || links[0].Path != "/a" || links[1].URL != longURL {
+		t.Errorf("Replayed links are wrong: %d links", len(links))
+	}
+	if clicks, err := storage.IncrementClicks(DefaultDomain, "/a", 4); err != nil || clicks != 4 {
+		t.Errorf("Replayed clicks are wrong. Have: %d, want: %d", clicks-1, 3)
+	}
+	if clicks, err := storage.IncrementClicks(DefaultDomain, "/a", 4); err != ErrClickLimitReached || clicks != 4 {
+		t.Errorf("Clicks are counted past the limit. Have: %d, %v, want: 4, %v", clicks, err, ErrClickLimitReached)
+	}
+}
+
+func TestFileLinkStorageCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "links.log")
+	storage := openTestFileStorage(t, path)
+	for i := 0; i < 5; i++ {
+		if err := storage.Add(Link{Path: "/a", URL: "https://a.example"}); err != nil && err != ErrLinkExists {
+			t.Fatal(err)
+		}
+		if err := storage.Update(Link{Path: "/a", URL: "https://b.example"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := storage.Add(Link{Path: "/deleted", URL: "https://c.example"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.Delete(DefaultDomain, "/deleted"); err != nil {
+		t.Fatal(err)
+	}
+	storage.Close()
+
+	storage = openTestFileStorage(t, path)
+	storage.Close()
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], "https://b.example") {
+		t.Errorf("Log isn't compacted: %s", content)
+	}
+}
+
+func TestFileLinkStorageCompactsOpenLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "links.log")
+	storage := openTestFileStorage(t, path)
+	storage.compactSize = 512
+	if err := storage.Add(Link{Path: "/a", URL: "https://a.example"}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		if _, err := storage.IncrementClicks(DefaultDomain, "/a", 1000); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() >= 2*storage.compactSize {
+		t.Errorf("Open log isn't compacted, size: %d", info.Size())
+	}
+	if err = storage.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	storage = openTestFileStorage(t, path)
+	defer storage.Close()
+	if clicks, err := storage.IncrementClicks(DefaultDomain, "/a", 1000); err != nil || clicks != 101 {
+		t.Errorf("Clicks are lost by compaction. Have: %d, want: %d", clicks-1, 100)
+	}
+}
+
+func TestFileLinkStorageCorruption(t *testing.T) {
+	dir := t.TempDir()
+	record := `{"op":"put","link":{"path":"/a","url":"https://a.example"}}`
+
+	tornPath := filepath.Join(dir, "torn.log")
+	if err := ioutil.WriteFile(tornPath, []byte(record+"\n"+`{"op":"put","li`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	storage := openTestFileStorage(t, tornPath)
+	if link, _ := storage.Get(DefaultDomain, "/a"); link == nil {
+		t.Errorf("Link before torn record is lost")
+	}
+	storage.Close()
+
+	brokenPath := filepath.Join(dir, "broken.log")
+	if err := ioutil.WriteFile(brokenPath, []byte("garbage\n"+record+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenFileLinkStorage(brokenPath); err == nil {
+		t.Errorf("Broken record in the middle of the log is accepted")
+	}
+	if content, _ := ioutil.ReadFile(brokenPath); !strings.HasPrefix(string(content), "garbage") {
+		t.Errorf("Broken log is rewritten: %s", content)
+	}
+	if _, err := os.Stat(brokenPath + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("Temporary file is left: %v", err)
+	}
+}
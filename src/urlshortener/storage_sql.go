@@ -0,0 +1,239 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// SQLLinkStorage keeps links in the link table of MySQL database, options are stored as json
+type SQLLinkStorage struct {
+	db *sql.DB
+}
+
+func OpenSQLLinkStorage(driver, dsn string) (*SQLLinkStorage, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Open %s database error: %s", driver, err))
+	}
+
+	err = createLinkTables(db)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &SQLLinkStorage{db: db}, nil
+}
+
+func createLinkTables(db *sql.DB) error {
+	_, err := db.Exec("" +
+		"CREATE TABLE IF NOT EXISTS link (" +
+		"  host VARCHAR(255) NOT NULL DEFAULT ''," +
+		"  path VARCHAR(255) NOT NULL," +
+		"  url TEXT NOT NULL," +
+		"  options TEXT NULL," +
+		"  PRIMARY KEY (host, path)" +
+		")")
+	if err != nil {
+		return errors.New(fmt.Sprintf("Create link table error: %s", err))
+	}
+
+	// tables created by previous versions miss options and host columns
+	err = ensureColumn(db, "link", "options", "ADD COLUMN options TEXT NULL")
+	if err != nil {
+		return err
+	}
+	err = ensureColumn(db, "link", "host", ""+
+		"ADD COLUMN host VARCHAR(255) NOT NULL DEFAULT '' FIRST, "+
+		"DROP PRIMARY KEY, ADD PRIMARY KEY (host, path)")
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec("" +
+		"CREATE TABLE IF NOT EXISTS link_clicks (" +
+		"  host VARCHAR(255) NOT NULL DEFAULT ''," +
+		"  path VARCHAR(255) NOT NULL," +
+		"  clicks BIGINT NOT NULL," +
+		"  PRIMARY KEY (host, path)" +
+		")")
+	if err != nil {
+		return errors.New(fmt.Sprintf("Create link_clicks table error: %s", err))
+	}
+
+	return ensureColumn(db, "link_clicks", "host", ""+
+		"ADD COLUMN host VARCHAR(255) NOT NULL DEFAULT '' FIRST, "+
+		"DROP PRIMARY KEY, ADD PRIMARY KEY (host, path)")
+}
+
+// ensureColumn alters the table when selecting the column fails
+func ensureColumn(db *sql.DB, table, column, alter string) error {
+	_, err := db.Exec("SELECT " + column + " FROM " + table + " WHERE 1 = 0")
+	if err == nil {
+		return nil
+	}
+
+	_, err = db.Exec("ALTER TABLE " + table + " " + alter)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Add %s column to %s table error: %s", column, table, err))
+	}
+
+	return nil
+}
+
+type linkScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanLink(s linkScanner) (Link, error) {
+	var link Link
+	var options sql.NullString
+	err := s.Scan(&link.Host, &link.Path, &link.URL, &options)
+	if err != nil {
+		return Link{}, err
+	}
+
+	if options.Valid && options.String != "" {
+		err = json.Unmarshal([]byte(options.String), &link.LinkOptions)
+		if err != nil {
+			return Link{}, errors.New(fmt.Sprintf("Parse options of link %s%s error: %s", link.Host, link.Path, err))
+		}
+	}
+
+	return link, nil
+}
+
+func (s *SQLLinkStorage) Get(host, path string) (*Link, error) {
+	link, err := scanLink(s.db.QueryRow("SELECT host, path, url, options FROM link WHERE host = ? AND path = ?", host, path))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &link, nil
+}
+
+func (s *SQLLinkStorage) List() ([]Link, error) {
+	rows, err := s.db.Query("SELECT host, path, url, options FROM link ORDER BY host, path")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []Link{}
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+
+	return links, rows.Err()
+}
+
+func (s *SQLLinkStorage) Add(link Link) error {
+	options, err := json.Marshal(link.LinkOptions)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec("INSERT INTO link (host, path, url, options) VALUES (?, ?, ?, ?)", link.Host, link.Path, link.URL, string(options))
+	if err != nil {
+		// duplicate key errors differ between drivers, so check the path instead of the error code
+		existing, getErr := s.Get(link.Host, link.Path)
+		if getErr == nil && existing != nil {
+			return ErrLinkExists
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *SQLLinkStorage) Update(link Link) error {
+	options, err := json.Marshal(link.LinkOptions)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.db.Exec("UPDATE link SET url = ?, options = ? WHERE host = ? AND path = ?", link.URL, string(options), link.Host, link.Path)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		// MySQL does not count rows updated with the same values
+		existing, err := s.Get(link.Host, link.Path)
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			return ErrLinkNotFound
+		}
+	}
+
+	return nil
+}
+
+func (s *SQLLinkStorage) Delete(host, path string) error {
+	result, err := s.db.Exec("DELETE FROM link WHERE host = ? AND path = ?", host, path)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrLinkNotFound
+	}
+
+	_, err = s.db.Exec("DELETE FROM link_clicks WHERE host = ? AND path = ?", host, path)
+	return err
+}
+
+// IncrementClicks checks the limit in the update itself, locking reads of a missing row take
+// gap locks and concurrent first clicks of a link deadlock on them
+func (s *SQLLinkStorage) IncrementClicks(host, path string, max int64) (int64, error) {
+	_, err := s.db.Exec("INSERT IGNORE INTO link_clicks (host, path, clicks) VALUES (?, ?, 0)", host, path)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := s.db.Exec(""+
+		"UPDATE link_clicks SET clicks = clicks + 1 "+
+		"WHERE host = ? AND path = ? AND clicks < ?", host, path, max)
+	if err != nil {
+		return 0, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	var clicks int64
+	err = s.db.QueryRow("SELECT clicks FROM link_clicks WHERE host = ? AND path = ?", host, path).Scan(&clicks)
+	if err != nil {
+		return 0, err
+	}
+	if affected == 0 {
+		return clicks, ErrClickLimitReached
+	}
+
+	return clicks, nil
+}
+
+func (s *SQLLinkStorage) Close() error {
+	return s.db.Close()
+}